@@ -0,0 +1,211 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ========== 打包下载 ==========
+// 边读边写，归档不在内存或磁盘上缓存。响应头发出后出错已经无法改变状态码，
+// 此时不写归档的结尾（zip 的中央目录、gzip 的校验尾），客户端能发现归档不完整，
+// 而不是得到一个缺少文件的"完整"归档
+
+const (
+	maxArchiveFiles = 1000
+	maxArchiveSize  = 4 << 30
+)
+
+type archiveRequest struct {
+	FileIDs []uint `json:"file_ids" binding:"required,min=1"`
+	// Format zip（默认）或 tar.gz
+	Format string `json:"format"`
+}
+
+// downloadArchive 把多个文件打包成 zip 或 tar.gz 下载，只能包含自己有权访问且扫描通过的文件
+func (s *fileService) downloadArchive(c *gin.Context) {
+	var req archiveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Format == "" {
+		req.Format = "zip"
+	}
+	if req.Format != "zip" && req.Format != "tar.gz" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be zip or tar.gz"})
+		return
+	}
+	if len(req.FileIDs) > maxArchiveFiles {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d files per archive", maxArchiveFiles)})
+		return
+	}
+
+	files, ok := s.archiveFiles(c, req.FileIDs)
+	if !ok {
+		return
+	}
+
+	header := c.Writer.Header()
+	header.Set("Cache-Control", "private")
+	header.Set("Content-Disposition", contentDisposition("files."+req.Format))
+	var err error
+	if req.Format == "zip" {
+		header.Set("Content-Type", "application/zip")
+		c.Status(http.StatusOK)
+		err = s.writeZip(c.Request.Context(), c.Writer, files)
+	} else {
+		header.Set("Content-Type", "application/gzip")
+		c.Status(http.StatusOK)
+		err = s.writeTarGz(c.Request.Context(), c.Writer, files)
+	}
+	if err != nil && c.Request.Context().Err() == nil {
+		log.Printf("write archive: %v", err)
+	}
+}
+
+// archiveFiles 按请求的顺序读取文件并检查权限、状态和总大小，不通过时写入响应并返回 false
+func (s *fileService) archiveFiles(c *gin.Context, ids []uint) ([]File, bool) {
+	var found []File
+	if err := s.db.Where("id IN ?", ids).Find(&found).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	byID := make(map[uint]File, len(found))
+	for _, file := range found {
+		byID[file.ID] = file
+	}
+
+	files := make([]File, 0, len(ids))
+	seen := make(map[uint]bool, len(ids))
+	var total int64
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		file, ok := byID[id]
+		if !ok || !canAccess(c, file.OwnerID) {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("File %d not found", id)})
+			return nil, false
+		}
+		if !downloadable(c, &file) {
+			return nil, false
+		}
+		total += file.Size
+		if total > maxArchiveSize {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Archive exceeds the %d byte limit", maxArchiveSize)})
+			return nil, false
+		}
+		files = append(files, file)
+	}
+	return files, true
+}
+
+func (s *fileService) writeZip(ctx context.Context, w io.Writer, files []File) error {
+	zw := zip.NewWriter(w)
+	names := archiveNames{}
+	for _, file := range files {
+		method := zip.Deflate
+		if compressed(file.MIMEType) {
+			method = zip.Store
+		}
+		dst, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     names.unique(file.OriginalName),
+			Method:   method,
+			Modified: file.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+		if err := s.copyBlob(ctx, dst, file); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func (s *fileService) writeTarGz(ctx context.Context, w io.Writer, files []File) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	names := archiveNames{}
+	for _, file := range files {
+		err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     names.unique(file.OriginalName),
+			Size:     file.Size,
+			Mode:     0644,
+			ModTime:  file.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+		if err := s.copyBlob(ctx, tw, file); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// copyBlob 写入文件内容，客户端断开时 ctx 取消，在下一次读取时停止
+func (s *fileService) copyBlob(ctx context.Context, dst io.Writer, file File) error {
+	f, err := s.blobs.open(file.SHA256)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	n, err := io.Copy(dst, ctxReader{ctx: ctx, r: f})
+	if err == nil && n != file.Size {
+		err = fmt.Errorf("file %d: read %d of %d bytes", file.ID, n, file.Size)
+	}
+	return err
+}
+
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// compressed 已经压缩过的类型直接存储，再压缩只浪费 CPU
+func compressed(mimeType string) bool {
+	return strings.HasPrefix(mimeType, "image/") && mimeType != "image/bmp" ||
+		strings.HasPrefix(mimeType, "video/") || strings.HasPrefix(mimeType, "audio/") ||
+		mimeType == "application/zip" || mimeType == "application/x-gzip" || mimeType == "application/pdf"
+}
+
+// archiveNames 归档中的文件名不能重复，重名时加上序号：a.txt、a (1).txt
+type archiveNames map[string]bool
+
+func (n archiveNames) unique(name string) string {
+	name = filepath.Base(name)
+	if name == "." || name == "/" || name == "" {
+		name = "file"
+	}
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	candidate := name
+	for i := 1; n[candidate]; i++ {
+		candidate = fmt.Sprintf("%s (%d)%s", base, i, ext)
+	}
+	n[candidate] = true
+	return candidate
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newArchiveRouter(s *fileService) *gin.Engine {
+	r := gin.New()
+	r.POST("/api/download/archive", asUser(1, "user"), s.downloadArchive)
+	return r
+}
+
+func postArchive(r *gin.Engine, ids []uint) *http.Response {
+	body, _ := json.Marshal(archiveRequest{FileIDs: ids})
+	return serve(r, http.MethodPost, "/api/download/archive", bytes.NewReader(body), http.Header{"Content-Type": {"application/json"}}).Result()
+}
+
+func TestArchiveZip(t *testing.T) {
+	s := newTestService(t)
+	a := addFile(t, s, 1, "a.txt", []byte("first"))
+	b := addFile(t, s, 1, "a.txt", []byte("second"))
+
+	resp := postArchive(newArchiveRouter(s), []uint{a.ID, b.ID, a.ID})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("read zip: %v", err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	if got := strings.Join(names, ","); got != "a.txt,a (1).txt" {
+		t.Fatalf("unexpected entries %s", got)
+	}
+}
+
+func TestArchiveRejects(t *testing.T) {
+	s := newTestService(t)
+	own := addFile(t, s, 1, "a.txt", []byte("mine"))
+	other := addFile(t, s, 2, "b.txt", []byte("theirs"))
+	// 只有元数据，大小检查在读取内容之前
+	large := []File{
+		{OwnerID: 1, SHA256: own.SHA256, Size: maxArchiveSize / 2, OriginalName: "large1.bin", Status: fileStatusClean},
+		{OwnerID: 1, SHA256: own.SHA256, Size: maxArchiveSize/2 + 1, OriginalName: "large2.bin", Status: fileStatusClean},
+	}
+	if err := s.db.Create(&large).Error; err != nil {
+		t.Fatalf("create files: %v", err)
+	}
+	r := newArchiveRouter(s)
+
+	cases := []struct {
+		name string
+		ids  []uint
+		want int
+	}{
+		{"size cap", []uint{large[0].ID, large[1].ID}, http.StatusRequestEntityTooLarge},
+		{"other owner", []uint{own.ID, other.ID}, http.StatusNotFound},
+		{"missing", []uint{own.ID, 999}, http.StatusNotFound},
+		{"empty", []uint{}, http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		if got := postArchive(r, tc.ids).StatusCode; got != tc.want {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.want, got)
+		}
+	}
+}
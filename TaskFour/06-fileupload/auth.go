@@ -0,0 +1,100 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ========== 认证 ==========
+// 使用 projectdemo 签发的 token，两个服务配置相同的 JWT_SECRET
+
+// Claims 与 projectdemo 的 utils.Claims 保持一致
+type Claims struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+	OrgID    uint   `json:"org_id,omitempty"`
+	jwt.RegisteredClaims
+}
+
+const roleAdmin = "admin"
+
+func parseToken(tokenString string, secret []byte) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return secret, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+		return claims, nil
+	}
+	return nil, errors.New("invalid token")
+}
+
+// authRequired 校验 Bearer token，把用户 ID 和角色写入 Context
+func authRequired(secret []byte) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scheme, tokenString, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !ok || scheme != "Bearer" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+			return
+		}
+		claims, err := parseToken(tokenString, secret)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		c.Set("userID", claims.UserID)
+		c.Set("role", claims.Role)
+		c.Next()
+	}
+}
+
+// canAccess 文件只对所有者和管理员可见
+func canAccess(c *gin.Context, ownerID uint) bool {
+	return c.GetUint("userID") == ownerID || c.GetString("role") == roleAdmin
+}
+
+// ========== 签名下载链接 ==========
+// 链接形如 /api/download/:id?expires=<unix 秒>&sig=<HMAC-SHA256>，任何人持有链接都可以在过期前下载
+
+type urlSigner struct {
+	key []byte
+}
+
+func (s urlSigner) sign(fileID uint, expires time.Time) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(strconv.FormatUint(uint64(fileID), 10) + ":" + strconv.FormatInt(expires.Unix(), 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s urlSigner) url(fileID uint, expires time.Time) string {
+	return "/api/download/" + strconv.FormatUint(uint64(fileID), 10) +
+		"?expires=" + strconv.FormatInt(expires.Unix(), 10) + "&sig=" + s.sign(fileID, expires)
+}
+
+// verify 校验签名并检查是否过期
+func (s urlSigner) verify(fileID uint, expiresParam, sig string, now time.Time) bool {
+	unix, err := strconv.ParseInt(expiresParam, 10, 64)
+	if err != nil {
+		return false
+	}
+	expires := time.Unix(unix, 0)
+	if !now.Before(expires) {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(s.sign(fileID, expires)))
+}
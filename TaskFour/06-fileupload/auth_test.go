@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestURLSignerVerify(t *testing.T) {
+	signer := urlSigner{key: []byte("test-signing-key")}
+	now := time.Unix(1700000000, 0)
+	expires := now.Add(time.Hour)
+	link, err := url.Parse(signer.url(42, expires))
+	if err != nil {
+		t.Fatalf("parse url: %v", err)
+	}
+	q := link.Query()
+	exp, sig := q.Get("expires"), q.Get("sig")
+	if link.Path != "/api/download/42" || exp != strconv.FormatInt(expires.Unix(), 10) {
+		t.Fatalf("unexpected url %s", link)
+	}
+	tampered := []byte(sig)
+	tampered[len(tampered)/2] ^= 1
+
+	cases := []struct {
+		name    string
+		id      uint
+		expires string
+		sig     string
+		now     time.Time
+		want    bool
+	}{
+		{"valid", 42, exp, sig, now, true},
+		{"expired", 42, exp, sig, expires, false},
+		{"long expired", 42, exp, sig, expires.Add(time.Hour), false},
+		{"other file", 43, exp, sig, now, false},
+		{"extended expiry", 42, strconv.FormatInt(expires.Add(time.Hour).Unix(), 10), sig, now, false},
+		{"tampered signature", 42, exp, string(tampered), now, false},
+		{"missing signature", 42, exp, "", now, false},
+		{"invalid expiry", 42, "tomorrow", sig, now, false},
+		{"other key", 42, exp, urlSigner{key: []byte("other-key")}.sign(42, expires), now, false},
+	}
+	for _, tc := range cases {
+		if got := signer.verify(tc.id, tc.expires, tc.sig, tc.now); got != tc.want {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// blobStore 按内容寻址的存储：文件保存在 <dir>/<sha256 前两位>/<sha256>，
+// 路径只由哈希决定，和客户端提供的文件名无关，相同内容只保存一份
+type blobStore struct {
+	dir string
+}
+
+func newBlobStore(dir string) (*blobStore, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "tmp"), 0755); err != nil {
+		return nil, err
+	}
+	return &blobStore{dir: dir}, nil
+}
+
+// blob 已写入临时位置、等待 commit 的内容。MIMEType 根据内容的前 512 字节判断，不信任客户端的 Content-Type
+type blob struct {
+	SHA256   string
+	Size     int64
+	MIMEType string
+	tmp      string
+}
+
+// stage 把 r 写入临时文件，同时计算哈希和 MIME 类型
+func (s *blobStore) stage(r io.Reader) (*blob, error) {
+	tmp, err := os.CreateTemp(filepath.Join(s.dir, "tmp"), "upload-*")
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.New()
+	sniff := &sniffWriter{}
+	size, err := io.Copy(io.MultiWriter(tmp, hash, sniff), r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	return &blob{SHA256: hex.EncodeToString(hash.Sum(nil)), Size: size, MIMEType: http.DetectContentType(sniff.buf), tmp: tmp.Name()}, nil
+}
+
+// stageFile 使用磁盘上已有的文件（如可恢复上传组装好的文件），commit 后 src 不再存在
+func (s *blobStore) stageFile(src string) (*blob, error) {
+	sum, err := hashFile(src)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return &blob{SHA256: sum, Size: info.Size(), MIMEType: http.DetectContentType(head[:n]), tmp: src}, nil
+}
+
+// commit 把临时文件移动到哈希对应的位置，已经存在相同内容时丢弃临时文件
+func (s *blobStore) commit(b *blob) error {
+	dst := s.path(b.SHA256)
+	if _, err := os.Stat(dst); err == nil {
+		return os.Remove(b.tmp)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return os.Rename(b.tmp, dst)
+}
+
+// discard 放弃未提交的内容
+func (s *blobStore) discard(b *blob) {
+	if err := os.Remove(b.tmp); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("discard staged blob %s: %v", b.tmp, err)
+	}
+}
+
+func (s *blobStore) open(sum string) (*os.File, error) {
+	return os.Open(s.path(sum))
+}
+
+func (s *blobStore) remove(sum string) error {
+	err := os.Remove(s.path(sum))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *blobStore) path(sum string) string {
+	return filepath.Join(s.dir, sum[:2], sum)
+}
+
+// hashFile 计算文件的 SHA256 哈希值
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// sniffWriter 只保留写入的前 512 字节，用于判断 MIME 类型
+type sniffWriter struct {
+	buf []byte
+}
+
+func (w *sniffWriter) Write(p []byte) (int, error) {
+	if rest := 512 - len(w.buf); rest > 0 {
+		if len(p) < rest {
+			rest = len(p)
+		}
+		w.buf = append(w.buf, p[:rest]...)
+	}
+	return len(p), nil
+}
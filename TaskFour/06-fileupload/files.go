@@ -0,0 +1,439 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultURLTTL = 15 * time.Minute
+	maxURLTTL     = 7 * 24 * time.Hour
+	scanTimeout   = 5 * time.Minute
+)
+
+// fileService 保存文件内容和元数据，处理上传、查询、删除和签名下载
+type fileService struct {
+	db      *gorm.DB
+	blobs   *blobStore
+	signer  urlSigner
+	policy  uploadPolicy
+	scanner Scanner
+
+	// mu 串行化 blob 引用的增减，避免删除最后一个引用的同时有相同内容的文件写入；
+	// 同时保证同一用户并发上传时配额检查和写入之间不会插入其他写入
+	mu sync.Mutex
+}
+
+func newFileService(db *gorm.DB, blobs *blobStore, signingKey []byte, policy uploadPolicy, scanner Scanner) *fileService {
+	return &fileService{db: db, blobs: blobs, signer: urlSigner{key: signingKey}, policy: policy, scanner: scanner}
+}
+
+// stagedFile 已接收、等待校验和保存的文件
+type stagedFile struct {
+	name string
+	blob *blob
+}
+
+// save 保存单个文件，见 saveAll
+func (s *fileService) save(ownerID uint, name string, b *blob) (*File, error) {
+	files, err := s.saveAll(ownerID, []stagedFile{{name: name, blob: b}})
+	if err != nil {
+		return nil, err
+	}
+	return files[0], nil
+}
+
+// saveAll 校验后提交内容并创建元数据，任何一个文件没有通过校验时全部丢弃并返回 *uploadError。
+// name 只作为原始文件名保存，不参与存储路径。保存的文件在后台扫描，扫描通过前不能下载
+func (s *fileService) saveAll(ownerID uint, staged []stagedFile) ([]*File, error) {
+	var total int64
+	for _, f := range staged {
+		err := s.policy.checkSize(f.blob.Size)
+		if err == nil {
+			err = s.policy.checkType(f.name, f.blob.MIMEType)
+		}
+		if err != nil {
+			s.discard(staged)
+			return nil, err
+		}
+		total += f.blob.Size
+	}
+
+	s.mu.Lock()
+	files, err := s.store(ownerID, staged, total)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		go s.scan(*file)
+	}
+	return files, nil
+}
+
+// store 检查配额后提交内容并创建元数据，调用方必须持有 s.mu
+func (s *fileService) store(ownerID uint, staged []stagedFile, total int64) ([]*File, error) {
+	used, err := s.usage(ownerID)
+	if err == nil {
+		err = s.policy.checkQuota(used, total)
+	}
+	if err != nil {
+		s.discard(staged)
+		return nil, err
+	}
+
+	for i, f := range staged {
+		if err := s.blobs.commit(f.blob); err != nil {
+			s.discard(staged[i:])
+			for _, committed := range staged[:i] {
+				s.removeUnreferenced(committed.blob.SHA256)
+			}
+			return nil, err
+		}
+	}
+	files := make([]*File, 0, len(staged))
+	for _, f := range staged {
+		files = append(files, &File{
+			OwnerID:      ownerID,
+			SHA256:       f.blob.SHA256,
+			Size:         f.blob.Size,
+			MIMEType:     f.blob.MIMEType,
+			OriginalName: filepath.Base(f.name),
+			Status:       fileStatusPending,
+		})
+	}
+	if err := s.db.Create(&files).Error; err != nil {
+		for _, f := range staged {
+			s.removeUnreferenced(f.blob.SHA256)
+		}
+		return nil, err
+	}
+	return files, nil
+}
+
+func (s *fileService) discard(staged []stagedFile) {
+	for _, f := range staged {
+		s.blobs.discard(f.blob)
+	}
+}
+
+// usage 用户所有文件的总大小
+func (s *fileService) usage(ownerID uint) (int64, error) {
+	var used int64
+	err := s.db.Model(&File{}).Where("owner_id = ?", ownerID).Select("COALESCE(SUM(size), 0)").Scan(&used).Error
+	return used, err
+}
+
+// stageMultipart 接收表单中的文件，声明的大小超过限制时不读取内容
+func (s *fileService) stageMultipart(fh *multipart.FileHeader) (*blob, error) {
+	if err := s.policy.checkSize(fh.Size); err != nil {
+		return nil, err
+	}
+	src, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	return s.blobs.stage(src)
+}
+
+// savePath 保存磁盘上已有的文件，成功后 path 被移动到存储中
+func (s *fileService) savePath(ownerID uint, name, path string) (*File, error) {
+	b, err := s.blobs.stageFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return s.save(ownerID, name, b)
+}
+
+// scan 扫描文件内容并更新状态。扫描出错时保持 pending，下次启动时由 scanPending 重试
+func (s *fileService) scan(file File) {
+	f, err := s.blobs.open(file.SHA256)
+	if err != nil {
+		log.Printf("scan file %d: %v", file.ID, err)
+		return
+	}
+	defer f.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), scanTimeout)
+	defer cancel()
+	result, err := s.scanner.Scan(ctx, &file, f)
+	if err != nil {
+		log.Printf("scan file %d: %v", file.ID, err)
+		return
+	}
+
+	updates := map[string]interface{}{"status": fileStatusClean}
+	if result.Threat != "" {
+		log.Printf("file %d quarantined: %s", file.ID, result.Threat)
+		updates = map[string]interface{}{"status": fileStatusQuarantined, "quarantine_reason": result.Threat}
+	}
+	if err := s.db.Model(&File{}).Where("id = ? AND status = ?", file.ID, fileStatusPending).Updates(updates).Error; err != nil {
+		log.Printf("update scan status of file %d: %v", file.ID, err)
+	}
+}
+
+// scanPending 扫描所有 pending 的文件，包括上次运行时没有扫描完的
+func (s *fileService) scanPending() {
+	var files []File
+	if err := s.db.Where("status = ?", fileStatusPending).Find(&files).Error; err != nil {
+		log.Printf("list pending files: %v", err)
+		return
+	}
+	for _, file := range files {
+		s.scan(file)
+	}
+}
+
+// removeUnreferenced 没有文件引用 sum 时删除内容，调用方必须持有 s.mu
+func (s *fileService) removeUnreferenced(sum string) {
+	var refs int64
+	if err := s.db.Model(&File{}).Where("sha256 = ?", sum).Count(&refs).Error; err != nil {
+		log.Printf("count references to blob %s: %v", sum, err)
+		return
+	}
+	if refs > 0 {
+		return
+	}
+	if err := s.blobs.remove(sum); err != nil {
+		log.Printf("remove blob %s: %v", sum, err)
+	}
+}
+
+// find 读取当前用户可以访问的文件，不存在或无权访问时写入 404 并返回 nil
+func (s *fileService) find(c *gin.Context) *File {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return nil
+	}
+	var file File
+	err = s.db.First(&file, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !canAccess(c, file.OwnerID)) {
+		// 无权访问时同样返回 404，不暴露文件是否存在
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return nil
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil
+	}
+	return &file
+}
+
+// ========== 单文件上传 ==========
+func (s *fileService) uploadFile(c *gin.Context) {
+	fh, err := c.FormFile("file")
+	if err != nil {
+		respondError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	b, err := s.stageMultipart(fh)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+	file, err := s.save(c.GetUint("userID"), fh.Filename, b)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "File uploaded successfully",
+		"file":    file,
+	})
+}
+
+// ========== 多文件上传 ==========
+func (s *fileService) uploadFiles(c *gin.Context) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		respondError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	headers := form.File["files"]
+	if len(headers) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No files uploaded"})
+		return
+	}
+
+	// 全部接收后一起校验和保存，任何一个文件不通过时都不保存
+	staged := make([]stagedFile, 0, len(headers))
+	for _, fh := range headers {
+		b, err := s.stageMultipart(fh)
+		if err != nil {
+			s.discard(staged)
+			respondError(c, err, http.StatusInternalServerError)
+			return
+		}
+		staged = append(staged, stagedFile{name: fh.Filename, blob: b})
+	}
+	files, err := s.saveAll(c.GetUint("userID"), staged)
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Files uploaded successfully",
+		"files":   files,
+		"count":   len(files),
+	})
+}
+
+// ========== 文件管理 ==========
+func (s *fileService) listFiles(c *gin.Context) {
+	files := make([]File, 0)
+	if err := s.db.Where("owner_id = ?", c.GetUint("userID")).Order("id DESC").Find(&files).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"files": files})
+}
+
+func (s *fileService) getFile(c *gin.Context) {
+	if file := s.find(c); file != nil {
+		c.JSON(http.StatusOK, file)
+	}
+}
+
+// deleteFile 删除元数据，没有其他文件引用相同内容时一并删除内容
+func (s *fileService) deleteFile(c *gin.Context) {
+	file := s.find(c)
+	if file == nil {
+		return
+	}
+
+	if err := s.remove(file); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// remove 删除文件元数据，没有其他文件引用时同时删除内容
+func (s *fileService) remove(file *File) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.db.Delete(file).Error; err != nil {
+		return err
+	}
+	s.removeUnreferenced(file.SHA256)
+	return nil
+}
+
+// signedURL 生成下载链接，ttl 查询参数指定有效期（如 1h），默认 15 分钟，最长 7 天
+func (s *fileService) signedURL(c *gin.Context) {
+	file := s.find(c)
+	if file == nil || !downloadable(c, file) {
+		return
+	}
+	ttl := defaultURLTTL
+	if v := c.Query("ttl"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 || d > maxURLTTL {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ttl must be a duration between 1s and 168h"})
+			return
+		}
+		ttl = d
+	}
+
+	expires := time.Now().Add(ttl).Truncate(time.Second)
+	c.JSON(http.StatusOK, gin.H{
+		"url":        s.signer.url(file.ID, expires),
+		"expires_at": expires,
+	})
+}
+
+// ========== 文件下载 ==========
+// 不需要 token，凭签名链接下载。支持 Range/If-Range 断点续传，
+// 内容按哈希寻址不会变化，所以用 SHA-256 作为强 ETag
+func (s *fileService) downloadFile(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || !s.signer.verify(uint(id), c.Query("expires"), c.Query("sig"), time.Now()) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid or expired download link"})
+		return
+	}
+
+	var file File
+	if err := s.db.First(&file, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+	if !downloadable(c, &file) {
+		return
+	}
+	f, err := s.blobs.open(file.SHA256)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer f.Close()
+
+	// Content-Type 使用上传时检测的类型，ServeContent 不会再次检测；
+	// 条件请求（If-None-Match/If-Modified-Since/If-Range）和 Range 都由 ServeContent 处理
+	header := c.Writer.Header()
+	header.Set("Content-Type", file.MIMEType)
+	header.Set("ETag", `"`+file.SHA256+`"`)
+	header.Set("Cache-Control", "private")
+	header.Set("Content-Disposition", contentDisposition(file.OriginalName))
+	http.ServeContent(c.Writer, c.Request, "", file.CreatedAt, f)
+}
+
+// downloadable 扫描通过的文件才能下载，否则写入 409 并返回 false
+func downloadable(c *gin.Context, file *File) bool {
+	switch file.Status {
+	case fileStatusClean:
+		return true
+	case fileStatusQuarantined:
+		c.JSON(http.StatusConflict, gin.H{"error": "File has been quarantined", "reason": file.QuarantineReason})
+	default:
+		c.JSON(http.StatusConflict, gin.H{"error": "File is still being scanned"})
+	}
+	return false
+}
+
+// contentDisposition 按 RFC 6266 生成 attachment 头：filename 是只含 ASCII 的备用名，
+// filename* 是 UTF-8 百分号编码的原始文件名（RFC 8187），现代浏览器优先使用后者
+func contentDisposition(name string) string {
+	var fallback, encoded strings.Builder
+	for _, r := range name {
+		if r >= 0x20 && r < 0x7f && r != '"' && r != '\\' {
+			fallback.WriteRune(r)
+		} else {
+			fallback.WriteByte('_')
+		}
+	}
+	for _, b := range []byte(name) {
+		if isAttrChar(b) {
+			encoded.WriteByte(b)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+	return `attachment; filename="` + fallback.String() + `"; filename*=UTF-8''` + encoded.String()
+}
+
+// isAttrChar RFC 8187 中不需要编码的字符
+func isAttrChar(b byte) bool {
+	switch {
+	case 'a' <= b && b <= 'z', 'A' <= b && b <= 'Z', '0' <= b && b <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", b) >= 0
+}
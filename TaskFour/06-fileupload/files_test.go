@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestContentDisposition(t *testing.T) {
+	cases := []struct {
+		name string
+		want string
+	}{
+		{"report.pdf", `attachment; filename="report.pdf"; filename*=UTF-8''report.pdf`},
+		{"年度报告.pdf", `attachment; filename="____.pdf"; filename*=UTF-8''%E5%B9%B4%E5%BA%A6%E6%8A%A5%E5%91%8A.pdf`},
+		{"a b.txt", `attachment; filename="a b.txt"; filename*=UTF-8''a%20b.txt`},
+		// 引号、反斜杠和换行不能出现在 filename 中，否则可以注入其他参数或响应头
+		{"x\";filename=evil.html\r\n.txt", `attachment; filename="x_;filename=evil.html__.txt"; filename*=UTF-8''x%22%3Bfilename%3Devil.html%0D%0A.txt`},
+		{`a\b.txt`, `attachment; filename="a_b.txt"; filename*=UTF-8''a%5Cb.txt`},
+	}
+	for _, tc := range cases {
+		if got := contentDisposition(tc.name); got != tc.want {
+			t.Errorf("%q: expected %s, got %s", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestDownloadRange(t *testing.T) {
+	s := newTestService(t)
+	file := addFile(t, s, 1, "hello.txt", []byte("hello world"))
+	r := gin.New()
+	r.GET("/api/download/:id", s.downloadFile)
+	link := s.signer.url(file.ID, time.Now().Add(time.Hour))
+	etag := `"` + file.SHA256 + `"`
+
+	cases := []struct {
+		name   string
+		header http.Header
+		status int
+		body   string
+	}{
+		{"full", nil, http.StatusOK, "hello world"},
+		{"range", http.Header{"Range": {"bytes=6-"}}, http.StatusPartialContent, "world"},
+		{"if-range matches", http.Header{"Range": {"bytes=0-4"}, "If-Range": {etag}}, http.StatusPartialContent, "hello"},
+		// 内容变化后 If-Range 不匹配，返回完整内容而不是拼接出错误的文件
+		{"if-range stale", http.Header{"Range": {"bytes=0-4"}, "If-Range": {`"stale"`}}, http.StatusOK, "hello world"},
+		{"unsatisfiable", http.Header{"Range": {"bytes=100-"}}, http.StatusRequestedRangeNotSatisfiable, ""},
+		{"not modified", http.Header{"If-None-Match": {etag}}, http.StatusNotModified, ""},
+	}
+	for _, tc := range cases {
+		w := serve(r, http.MethodGet, link, nil, tc.header)
+		if w.Code != tc.status {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.status, w.Code)
+			continue
+		}
+		if tc.body != "" && w.Body.String() != tc.body {
+			t.Errorf("%s: expected body %q, got %q", tc.name, tc.body, w.Body.String())
+		}
+		if got := w.Header().Get("ETag"); w.Code < 400 && got != etag {
+			t.Errorf("%s: expected ETag %s, got %s", tc.name, etag, got)
+		}
+	}
+
+	if w := serve(r, http.MethodGet, link+"x", nil, nil); w.Code != http.StatusForbidden {
+		t.Errorf("expected tampered link to be rejected, got %d", w.Code)
+	}
+}
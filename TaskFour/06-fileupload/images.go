@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ========== 图片处理 ==========
+// GET /api/images/:id?w=&h=&fit=&format= 缩放或裁剪图片后重新编码。
+// 结果按原图内容和参数缓存在磁盘上，缓存随时可以删除，下次请求时重新生成
+
+const (
+	// maxImageDimension 输出的宽高上限
+	maxImageDimension = 4096
+	// maxSourcePixels 原图的像素上限，解码前按图片头检查，避免小文件解码出巨大的图片
+	maxSourcePixels = 40 << 20
+	jpegQuality     = 85
+)
+
+// 缩放方式：contain 等比缩放到框内，cover 等比缩放填满框并居中裁剪，fill 拉伸到框的大小
+const (
+	fitContain = "contain"
+	fitCover   = "cover"
+	fitFill    = "fill"
+)
+
+type imageService struct {
+	files *fileService
+	dir   string
+	// sem 限制同时解码和缩放的图片数量
+	sem chan struct{}
+}
+
+func newImageService(dir string, files *fileService) (*imageService, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &imageService{files: files, dir: dir, sem: make(chan struct{}, runtime.NumCPU())}, nil
+}
+
+type imageParams struct {
+	Width, Height int
+	Fit           string
+	// Format 输出格式，为空时与原图相同
+	Format string
+}
+
+func parseImageParams(c *gin.Context) (imageParams, error) {
+	p := imageParams{Fit: c.DefaultQuery("fit", fitContain), Format: c.Query("format")}
+	for _, q := range []struct {
+		name string
+		dst  *int
+	}{{"w", &p.Width}, {"h", &p.Height}} {
+		v := c.Query(q.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxImageDimension {
+			return p, fmt.Errorf("%s must be between 1 and %d", q.name, maxImageDimension)
+		}
+		*q.dst = n
+	}
+	switch p.Fit {
+	case fitContain, fitCover, fitFill:
+	default:
+		return p, errors.New("fit must be contain, cover or fill")
+	}
+	switch p.Format {
+	case "", "jpeg", "png", "gif":
+	case "jpg":
+		p.Format = "jpeg"
+	default:
+		return p, errors.New("format must be jpeg, png or gif")
+	}
+	return p, nil
+}
+
+// transform 返回处理后的图片，权限和下载相同：所有者或管理员，并且文件已扫描通过
+func (s *imageService) transform(c *gin.Context) {
+	file := s.files.find(c)
+	if file == nil || !downloadable(c, file) {
+		return
+	}
+	p, err := parseImageParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key := p.cacheKey(file.SHA256)
+	path, format, err := s.cached(key)
+	if errors.Is(err, os.ErrNotExist) {
+		path, format, err = s.render(file, p, key)
+	}
+	var uerr *uploadError
+	if errors.As(err, &uerr) {
+		c.JSON(uerr.Status, gin.H{"error": uerr.Message})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer f.Close()
+	header := c.Writer.Header()
+	header.Set("Content-Type", "image/"+format)
+	header.Set("ETag", `"`+key+`"`)
+	header.Set("Cache-Control", "private")
+	http.ServeContent(c.Writer, c.Request, "", file.CreatedAt, f)
+}
+
+// cacheKey 由原图内容和参数决定，相同内容的不同文件共用缓存
+func (p imageParams) cacheKey(sum string) string {
+	h := sha256.Sum256([]byte(fmt.Sprintf("%s:%d:%d:%s:%s", sum, p.Width, p.Height, p.Fit, p.Format)))
+	return hex.EncodeToString(h[:])
+}
+
+// cached 缓存文件名带有输出格式（未指定 format 时格式由原图决定），用 Glob 查找
+func (s *imageService) cached(key string) (string, string, error) {
+	matches, err := filepath.Glob(filepath.Join(s.dir, key[:2], key+".*"))
+	if err != nil {
+		return "", "", err
+	}
+	if len(matches) == 0 {
+		return "", "", os.ErrNotExist
+	}
+	return matches[0], filepath.Ext(matches[0])[1:], nil
+}
+
+// render 解码、缩放、编码并写入缓存，返回缓存文件的路径和格式
+func (s *imageService) render(file *File, p imageParams, key string) (string, string, error) {
+	s.sem <- struct{}{}
+	defer func() { <-s.sem }()
+
+	f, err := s.files.blobs.open(file.SHA256)
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+
+	cfg, format, err := image.DecodeConfig(f)
+	if err != nil {
+		return "", "", &uploadError{Status: http.StatusUnsupportedMediaType, Message: "File is not a JPEG, PNG or GIF image"}
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxSourcePixels {
+		return "", "", &uploadError{Status: http.StatusUnprocessableEntity, Message: "Image is too large to process"}
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", "", err
+	}
+	// GIF 只处理第一帧
+	src, _, err := image.Decode(f)
+	if err != nil {
+		return "", "", &uploadError{Status: http.StatusUnprocessableEntity, Message: "Cannot decode image: " + err.Error()}
+	}
+
+	dst := transformImage(src, p)
+	if p.Format != "" {
+		format = p.Format
+	}
+	var buf bytes.Buffer
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: jpegQuality})
+	case "png":
+		err = png.Encode(&buf, dst)
+	case "gif":
+		err = gif.Encode(&buf, dst, nil)
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	// 先写临时文件再改名，并发生成同一个结果时读到的总是完整的文件
+	path := filepath.Join(s.dir, key[:2], key+"."+format)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", "", err
+	}
+	// 临时文件名不以 key 开头，cached 的 Glob 不会匹配到没写完的文件
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-"+key+"-*")
+	if err != nil {
+		return "", "", err
+	}
+	_, err = tmp.Write(buf.Bytes())
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", "", err
+	}
+	return path, format, nil
+}
+
+// transformImage 按参数计算目标尺寸和原图中要使用的区域，只给出一边时按比例计算另一边，
+// 结果的宽高都不超过 maxImageDimension
+func transformImage(src image.Image, p imageParams) image.Image {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	w, h := p.Width, p.Height
+	switch {
+	case w == 0 && h == 0:
+		w, h = sw, sh
+	case h == 0:
+		h = max(1, int(math.Round(float64(sh)*float64(w)/float64(sw))))
+	case w == 0:
+		w = max(1, int(math.Round(float64(sw)*float64(h)/float64(sh))))
+	case p.Fit == fitContain:
+		scale := math.Min(float64(w)/float64(sw), float64(h)/float64(sh))
+		w = max(1, int(math.Round(float64(sw)*scale)))
+		h = max(1, int(math.Round(float64(sh)*scale)))
+	case p.Fit == fitCover:
+		// 从原图中间裁出与目标相同宽高比的区域
+		cw, ch := sw, sh
+		if sw*h > sh*w {
+			cw = max(1, sh*w/h)
+		} else {
+			ch = max(1, sw*h/w)
+		}
+		x0, y0 := b.Min.X+(sw-cw)/2, b.Min.Y+(sh-ch)/2
+		b = image.Rect(x0, y0, x0+cw, y0+ch)
+	}
+	// 按比例计算出的一边或者原图本身可能超过上限，两边等比缩小
+	if w > maxImageDimension || h > maxImageDimension {
+		scale := math.Min(float64(maxImageDimension)/float64(w), float64(maxImageDimension)/float64(h))
+		w = min(maxImageDimension, max(1, int(math.Round(float64(w)*scale))))
+		h = min(maxImageDimension, max(1, int(math.Round(float64(h)*scale))))
+	}
+
+	// 转换为预乘 alpha 的 RGBA 再插值，透明像素的颜色不会渗到边缘
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, b.Min, draw.Src)
+	if b.Dx() == w && b.Dy() == h {
+		return rgba
+	}
+	return resampleTransposed(resampleTransposed(rgba, w), h)
+}
+
+// resampleTransposed 把每一行缩放到 n 个像素并转置输出：结果的第 x 行是缩放后的第 x 列。
+// 调用两次即完成两个方向的缩放，结果回到原来的方向。
+// 使用三角（双线性）滤波，缩小时滤波半径随比例放大，相当于对覆盖的原像素加权平均
+func resampleTransposed(src *image.RGBA, n int) *image.RGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, sh, n))
+	scale := float64(sw) / float64(n)
+	support := math.Max(scale, 1)
+
+	weights := make([]float64, 0, int(2*support)+2)
+	for x := 0; x < n; x++ {
+		center := (float64(x)+0.5)*scale - 0.5
+		start := max(0, int(math.Ceil(center-support)))
+		end := min(sw-1, int(math.Floor(center+support)))
+		weights = weights[:0]
+		var total float64
+		for i := start; i <= end; i++ {
+			wt := 1 - math.Abs(float64(i)-center)/support
+			if wt < 0 {
+				wt = 0
+			}
+			weights = append(weights, wt)
+			total += wt
+		}
+		if total == 0 {
+			// 放大时中心落在两端像素之外，直接取最近的像素
+			start, weights = min(max(int(math.Round(center)), 0), sw-1), append(weights[:0], 1)
+			total = 1
+		}
+
+		for y := 0; y < sh; y++ {
+			row := src.Pix[y*src.Stride:]
+			var r, g, b, a float64
+			for j, wt := range weights {
+				px := row[(start+j)*4:]
+				r += float64(px[0]) * wt
+				g += float64(px[1]) * wt
+				b += float64(px[2]) * wt
+				a += float64(px[3]) * wt
+			}
+			out := dst.Pix[x*dst.Stride+y*4:]
+			out[0] = clampUint8(r / total)
+			out[1] = clampUint8(g / total)
+			out[2] = clampUint8(b / total)
+			out[3] = clampUint8(a / total)
+		}
+	}
+	return dst
+}
+
+func clampUint8(v float64) uint8 {
+	switch {
+	case v <= 0:
+		return 0
+	case v >= 255:
+		return 255
+	}
+	return uint8(v + 0.5)
+}
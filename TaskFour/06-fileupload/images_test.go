@@ -0,0 +1,60 @@
+package main
+
+import (
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTransformImageDimensions(t *testing.T) {
+	cases := []struct {
+		name   string
+		sw, sh int
+		p      imageParams
+		w, h   int
+	}{
+		{"original", 300, 200, imageParams{Fit: fitContain}, 300, 200},
+		{"width only", 300, 200, imageParams{Width: 150, Fit: fitContain}, 150, 100},
+		{"height only", 300, 200, imageParams{Height: 50, Fit: fitContain}, 75, 50},
+		{"contain", 300, 200, imageParams{Width: 100, Height: 100, Fit: fitContain}, 100, 67},
+		{"cover", 300, 200, imageParams{Width: 100, Height: 100, Fit: fitCover}, 100, 100},
+		{"fill", 300, 200, imageParams{Width: 100, Height: 100, Fit: fitFill}, 100, 100},
+		// 按比例计算的一边超过上限时两边等比缩小
+		{"tall strip by width", 1, 10000, imageParams{Width: maxImageDimension, Fit: fitContain}, 1, maxImageDimension},
+		{"wide strip by height", 10000, 2, imageParams{Height: 100, Fit: fitContain}, maxImageDimension, 1},
+		{"large original", 8000, 10, imageParams{Fit: fitContain}, maxImageDimension, 5},
+	}
+	for _, tc := range cases {
+		src := image.NewRGBA(image.Rect(0, 0, tc.sw, tc.sh))
+		b := transformImage(src, tc.p).Bounds()
+		if b.Dx() != tc.w || b.Dy() != tc.h {
+			t.Errorf("%s: expected %dx%d, got %dx%d", tc.name, tc.w, tc.h, b.Dx(), b.Dy())
+		}
+	}
+}
+
+func TestImageCacheIgnoresTempFiles(t *testing.T) {
+	s := &imageService{dir: t.TempDir()}
+	key := imageParams{Width: 10}.cacheKey("sum")
+	dir := filepath.Join(s.dir, key[:2])
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// 写了一半的临时文件
+	tmp, err := os.CreateTemp(dir, ".tmp-"+key+"-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmp.Close()
+	if _, _, err := s.cached(key); !os.IsNotExist(err) {
+		t.Fatalf("expected temp file to be ignored, got %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, key+".png"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, format, err := s.cached(key); err != nil || format != "png" {
+		t.Fatalf("expected cached png, got %q (%v)", format, err)
+	}
+}
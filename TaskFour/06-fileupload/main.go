@@ -1,112 +1,136 @@
 package main
 
 import (
-	"net/http"
+	"crypto/rand"
+	"log"
 	"os"
-	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
 )
 
 func main() {
 	r := gin.Default()
 
-	// 创建上传目录
-	if err := os.MkdirAll("./uploads", 0755); err != nil {
+	// 创建数据目录，文件内容和元数据都放在这里，不直接对外提供
+	if err := os.MkdirAll("./data", 0755); err != nil {
 		panic(err)
 	}
+	db, err := gorm.Open(sqlite.Open("./data/files.db?_pragma=busy_timeout(5000)"), &gorm.Config{})
+	if err != nil {
+		panic(err)
+	}
+	if err := db.AutoMigrate(&File{}); err != nil {
+		panic(err)
+	}
+	blobs, err := newBlobStore("./data/blobs")
+	if err != nil {
+		panic(err)
+	}
+
+	// token 由 projectdemo 签发，密钥需要与其 jwt.secret 一致
+	jwtSecret := jwtSecretFromEnv()
+	policy := uploadPolicyFromEnv()
+	files := newFileService(db, blobs, signingKey(), policy, eicarScanner{})
+	go files.scanPending()
+	auth := authRequired(jwtSecret)
+	limit := limitRequestBody(policy.MaxRequestSize)
 
 	// ========== 单文件上传 ==========
-	r.POST("/api/upload", uploadFile)
+	r.POST("/api/upload", auth, limit, files.uploadFile)
 
 	// ========== 多文件上传 ==========
-	r.POST("/api/upload-multiple", uploadFiles)
+	r.POST("/api/upload-multiple", auth, limit, files.uploadFiles)
+
+	// ========== 可恢复上传 ==========
+	uploads, err := newUploadStore("./data/uploads", files, uploadTTL)
+	if err != nil {
+		panic(err)
+	}
+	go uploads.cleanupLoop(time.Hour)
+
+	tus := r.Group("/api/uploads", tusResumable())
+	tus.OPTIONS("", uploads.options)
+	tus.POST("", auth, uploads.create)
+	tus.HEAD("/:id", auth, uploads.status)
+	tus.PATCH("/:id", auth, limit, uploads.patch)
+	tus.DELETE("/:id", auth, uploads.terminate)
+
+	// ========== 文件管理 ==========
+	api := r.Group("/api/files", auth)
+	api.GET("", files.listFiles)
+	api.GET("/:id", files.getFile)
+	api.DELETE("/:id", files.deleteFile)
+	api.GET("/:id/url", files.signedURL)
 
 	// ========== 文件下载 ==========
-	r.GET("/api/download/:filename", downloadFile)
+	r.GET("/api/download/:id", files.downloadFile)
+	r.HEAD("/api/download/:id", files.downloadFile)
+	r.POST("/api/download/archive", auth, files.downloadArchive)
+
+	// ========== 图片处理 ==========
+	images, err := newImageService("./data/images", files)
+	if err != nil {
+		panic(err)
+	}
+	r.GET("/api/images/:id", auth, images.transform)
 
 	// ========== 静态文件服务 ==========
 	// 提供静态文件服务
 	r.Static("/static", "./static")
 
-	// 提供文件系统
-	r.StaticFS("/files", http.Dir("./uploads"))
-
 	r.Run(":8080")
 }
 
-// ========== 单文件上传 ==========
-func uploadFile(c *gin.Context) {
-	// 获取上传的文件
-	file, err := c.FormFile("file")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
+// placeholderSecrets 示例配置中常见的密钥，使用它们等于谁都可以伪造 token
+var placeholderSecrets = []string{"your-secret-key-change-in-production", "changeme", "change-me", "secret"}
 
-	// 保存文件
-	filename := filepath.Base(file.Filename)
-	dst := filepath.Join("uploads", filename)
-	if err := c.SaveUploadedFile(file, dst); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+// jwtSecretFromEnv 校验 token 的密钥，未设置或者是示例中的值时拒绝启动
+func jwtSecretFromEnv() []byte {
+	v := os.Getenv("JWT_SECRET")
+	if v == "" {
+		log.Fatal("JWT_SECRET is not set, it must match jwt.secret of the service issuing tokens")
 	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"message":  "File uploaded successfully",
-		"filename": filename,
-		"size":     file.Size,
-	})
-}
-
-// ========== 多文件上传 ==========
-func uploadFiles(c *gin.Context) {
-	form, err := c.MultipartForm()
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+	for _, p := range placeholderSecrets {
+		if strings.EqualFold(v, p) {
+			log.Fatal("JWT_SECRET is a placeholder value, set it to the real signing key")
+		}
 	}
+	return []byte(v)
+}
 
-	files := form.File["files"]
-	if len(files) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No files uploaded"})
-		return
+// signingKey 下载链接的签名密钥。未配置 URL_SIGNING_KEY 时随机生成，重启后已发出的链接失效
+func signingKey() []byte {
+	if v := os.Getenv("URL_SIGNING_KEY"); v != "" {
+		return []byte(v)
 	}
-
-	var filenames []string
-	for _, file := range files {
-		filename := filepath.Join("uploads", filepath.Base(file.Filename))
-		if err := c.SaveUploadedFile(file, filename); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		filenames = append(filenames, filename)
+	log.Println("URL_SIGNING_KEY not set, download links will not survive a restart")
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
 	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Files uploaded successfully",
-		"files":   filenames,
-		"count":   len(filenames),
-	})
+	return key
 }
 
-// ========== 文件下载 ==========
-func downloadFile(c *gin.Context) {
-	filename := c.Param("filename")
-	filepath := filepath.Join("uploads", filename)
-
-	// 检查文件是否存在
-	if _, err := os.Stat(filepath); os.IsNotExist(err) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
-		return
+// uploadPolicyFromEnv 默认上传限制，可以用 MAX_FILE_SIZE、MAX_REQUEST_SIZE、USER_QUOTA（字节数，0 表示不限制）覆盖
+func uploadPolicyFromEnv() uploadPolicy {
+	policy := defaultUploadPolicy()
+	for key, dst := range map[string]*int64{
+		"MAX_FILE_SIZE":    &policy.MaxFileSize,
+		"MAX_REQUEST_SIZE": &policy.MaxRequestSize,
+		"USER_QUOTA":       &policy.UserQuota,
+	} {
+		if v := os.Getenv(key); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n < 0 {
+				log.Fatalf("invalid %s: %q", key, v)
+			}
+			*dst = n
+		}
 	}
-
-	// 设置响应头
-	c.Header("Content-Description", "File Transfer")
-	c.Header("Content-Transfer-Encoding", "binary")
-	c.Header("Content-Disposition", "attachment; filename="+filename)
-	c.Header("Content-Type", "application/octet-stream")
-
-	// 返回文件
-	c.File(filepath)
+	return policy
 }
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newTestService 在测试临时目录中创建数据库和存储，测试结束后自动关闭
+func newTestService(t *testing.T) *fileService {
+	t.Helper()
+
+	dir := t.TempDir()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(dir, "files.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("get generic db: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = sqlDB.Close()
+	})
+	if err := db.AutoMigrate(&File{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	blobs, err := newBlobStore(filepath.Join(dir, "blobs"))
+	if err != nil {
+		t.Fatalf("new blob store: %v", err)
+	}
+	return newFileService(db, blobs, []byte("test-signing-key"), defaultUploadPolicy(), eicarScanner{})
+}
+
+// asUser 代替 authRequired，直接把用户写入 Context
+func asUser(userID uint, role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("userID", userID)
+		c.Set("role", role)
+		c.Next()
+	}
+}
+
+// addFile 保存文件并等待后台扫描通过
+func addFile(t *testing.T, s *fileService, ownerID uint, name string, content []byte) *File {
+	t.Helper()
+
+	b, err := s.blobs.stage(bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("stage: %v", err)
+	}
+	file, err := s.save(ownerID, name, b)
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	waitScanned(t, s, file)
+	return file
+}
+
+func waitScanned(t *testing.T, s *fileService, file *File) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if err := s.db.First(file, file.ID).Error; err != nil {
+			t.Fatalf("reload file: %v", err)
+		}
+		if file.Status != fileStatusPending {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("file %d was not scanned in time", file.ID)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func serve(r http.Handler, method, path string, body io.Reader, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, body)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
@@ -0,0 +1,17 @@
+package main
+
+import "time"
+
+// File 文件的元数据。内容按 SHA-256 存在 blobStore 中，相同内容的多个文件共用一份数据。
+// Status 是扫描状态，只有 clean 的文件可以下载
+type File struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	OwnerID          uint      `json:"owner_id" gorm:"not null;index"`
+	SHA256           string    `json:"sha256" gorm:"size:64;not null;index"`
+	Size             int64     `json:"size"`
+	MIMEType         string    `json:"mime_type" gorm:"size:255"`
+	OriginalName     string    `json:"original_name" gorm:"size:255"`
+	Status           string    `json:"status" gorm:"size:20;not null;default:pending;index"`
+	QuarantineReason string    `json:"quarantine_reason,omitempty" gorm:"size:255"`
+	CreatedAt        time.Time `json:"created_at"`
+}
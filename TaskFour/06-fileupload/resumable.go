@@ -0,0 +1,519 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ========== 可恢复上传（tus 协议） ==========
+// POST 创建上传 -> PATCH 按偏移量追加分块 -> HEAD 查询进度（断线后从这里继续）。
+// 每个分块必须带 SHA-256 校验和，最后一个分块写入后整个文件保存到 fileService。
+// 上传只对创建者可见，超过 uploadTTL 没有新分块的上传会被清理
+
+const (
+	tusVersion     = "1.0.0"
+	tusExtensions  = "creation,expiration,checksum,termination"
+	uploadTTL      = 24 * time.Hour
+	statusChecksum = 460 // tus 约定的 Checksum Mismatch
+)
+
+var (
+	uploadIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+	sha256Pattern   = regexp.MustCompile(`^[0-9a-f]{64}$`)
+)
+
+// uploadInfo 上传的状态，和未完成的数据（.part）一起保存在 uploadStore.dir 中，服务重启后可以继续
+type uploadInfo struct {
+	ID       string `json:"id"`
+	OwnerID  uint   `json:"owner_id"`
+	Length   int64  `json:"length"`
+	Offset   int64  `json:"offset"`
+	Filename string `json:"filename"`
+	// SHA256 创建时客户端声明的整个文件的哈希（可选），完成时校验
+	SHA256    string    `json:"sha256,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Completed bool      `json:"completed"`
+	// FileID 完成后保存的文件
+	FileID uint `json:"file_id,omitempty"`
+}
+
+type uploadStore struct {
+	dir   string
+	files *fileService
+	ttl   time.Duration
+
+	mu sync.Mutex
+	// busy 正在被请求或清理处理的上传，同一个上传同时只允许一个 PATCH
+	busy map[string]bool
+}
+
+func newUploadStore(dir string, files *fileService, ttl time.Duration) (*uploadStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &uploadStore{dir: dir, files: files, ttl: ttl, busy: make(map[string]bool)}, nil
+}
+
+// tusResumable 检查协议版本，所有响应都带上 Tus-Resumable
+func tusResumable() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Tus-Resumable", tusVersion)
+		if c.Request.Method != http.MethodOptions && c.GetHeader("Tus-Resumable") != tusVersion {
+			c.Header("Tus-Version", tusVersion)
+			c.AbortWithStatusJSON(http.StatusPreconditionFailed, gin.H{"error": "Unsupported Tus-Resumable version"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// options 返回服务端支持的协议版本和扩展
+func (s *uploadStore) options(c *gin.Context) {
+	c.Header("Tus-Version", tusVersion)
+	c.Header("Tus-Extension", tusExtensions)
+	if limit := s.files.policy.MaxFileSize; limit > 0 {
+		c.Header("Tus-Max-Size", strconv.FormatInt(limit, 10))
+	}
+	c.Header("Tus-Checksum-Algorithm", "sha256")
+	c.Status(http.StatusNoContent)
+}
+
+// create 创建上传。Upload-Length 是文件总大小，Upload-Metadata 可以带 filename 和 sha256（十六进制）
+func (s *uploadStore) create(c *gin.Context) {
+	length, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Upload-Length"})
+		return
+	}
+	// 类型要等内容传完才能检测，这里先检查大小和配额，避免传完才发现超出
+	if err := s.files.policy.checkSize(length); err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+	used, err := s.files.usage(c.GetUint("userID"))
+	if err == nil {
+		err = s.files.policy.checkQuota(used, length)
+	}
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+	meta, err := parseUploadMetadata(c.GetHeader("Upload-Metadata"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	checksum := strings.ToLower(meta["sha256"])
+	if checksum != "" && !sha256Pattern.MatchString(checksum) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sha256 metadata"})
+		return
+	}
+
+	id, err := newUploadID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	info := &uploadInfo{
+		ID:        id,
+		OwnerID:   c.GetUint("userID"),
+		Length:    length,
+		Filename:  filepath.Base(meta["filename"]),
+		SHA256:    checksum,
+		ExpiresAt: time.Now().Add(s.ttl),
+	}
+	if info.Filename == "." || info.Filename == "/" {
+		info.Filename = id
+	}
+	if err := os.WriteFile(s.partPath(id), nil, 0644); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.save(info); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 空文件不需要 PATCH，创建即完成
+	if length == 0 {
+		if err := s.assemble(info); err != nil {
+			s.remove(id)
+			respondError(c, err, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	c.Header("Location", "/api/uploads/"+id)
+	c.Header("Upload-Expires", info.ExpiresAt.UTC().Format(http.TimeFormat))
+	setFileID(c, info)
+	c.Status(http.StatusCreated)
+}
+
+// status 返回已接收的字节数，客户端从 Upload-Offset 继续上传
+func (s *uploadStore) status(c *gin.Context) {
+	info, ok := s.lookup(c)
+	if !ok {
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Header("Upload-Offset", strconv.FormatInt(info.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(info.Length, 10))
+	c.Header("Upload-Expires", info.ExpiresAt.UTC().Format(http.TimeFormat))
+	setFileID(c, info)
+	c.Status(http.StatusOK)
+}
+
+// patch 从 Upload-Offset 开始追加一个分块。Upload-Checksum 格式为 "sha256 <base64>"，
+// 校验失败时丢弃这个分块，偏移量不变
+func (s *uploadStore) patch(c *gin.Context) {
+	if c.ContentType() != "application/offset+octet-stream" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be application/offset+octet-stream"})
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Upload-Offset"})
+		return
+	}
+	want, err := parseUploadChecksum(c.GetHeader("Upload-Checksum"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	id := c.Param("id")
+	if !s.acquire(id) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload is being modified by another request"})
+		return
+	}
+	defer s.release(id)
+
+	info, ok := s.lookup(c)
+	if !ok {
+		return
+	}
+	if info.Completed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Upload already completed"})
+		return
+	}
+	if offset != info.Offset {
+		c.Header("Upload-Offset", strconv.FormatInt(info.Offset, 10))
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload-Offset does not match the current offset"})
+		return
+	}
+
+	n, sum, err := s.writeChunk(info, c.Request.Body)
+	switch {
+	case errors.Is(err, errChunkTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Chunk exceeds Upload-Length"})
+		return
+	case err != nil:
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	case !bytes.Equal(sum, want):
+		s.truncate(info)
+		c.JSON(statusChecksum, gin.H{"error": "Chunk checksum mismatch"})
+		return
+	}
+
+	info.Offset += n
+	info.ExpiresAt = time.Now().Add(s.ttl)
+	if info.Offset == info.Length {
+		if err := s.assemble(info); err != nil {
+			// 内容已经完整，校验不通过时重传分块也无济于事，直接放弃这个上传
+			var uerr *uploadError
+			switch {
+			case errors.Is(err, errFileChecksum):
+				s.remove(info.ID)
+				c.JSON(statusChecksum, gin.H{"error": err.Error()})
+			case errors.As(err, &uerr):
+				s.remove(info.ID)
+				c.JSON(uerr.Status, gin.H{"error": uerr.Message})
+			default:
+				// 上传的状态没有变化，客户端可以从 Upload-Offset 重传最后一个分块
+				c.Header("Upload-Offset", strconv.FormatInt(info.Offset-n, 10))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			return
+		}
+	} else if err := s.save(info); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Upload-Offset", strconv.FormatInt(info.Offset, 10))
+	c.Header("Upload-Expires", info.ExpiresAt.UTC().Format(http.TimeFormat))
+	setFileID(c, info)
+	c.Status(http.StatusNoContent)
+}
+
+// terminate 放弃上传并删除已接收的数据
+func (s *uploadStore) terminate(c *gin.Context) {
+	id := c.Param("id")
+	if !s.acquire(id) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload is being modified by another request"})
+		return
+	}
+	defer s.release(id)
+
+	if _, ok := s.lookup(c); !ok {
+		return
+	}
+	s.remove(id)
+	c.Status(http.StatusNoContent)
+}
+
+var (
+	errChunkTooLarge = errors.New("chunk exceeds upload length")
+	errFileChecksum  = errors.New("file checksum mismatch")
+)
+
+// writeChunk 把 body 写到 .part 的末尾，同时计算这个分块的 SHA-256。
+// 写入失败（包括客户端断开）时截断回原来的长度，未经校验的数据不会保留
+func (s *uploadStore) writeChunk(info *uploadInfo, body io.Reader) (int64, []byte, error) {
+	f, err := os.OpenFile(s.partPath(info.ID), os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(info.Offset, io.SeekStart); err != nil {
+		return 0, nil, err
+	}
+
+	hash := sha256.New()
+	remaining := info.Length - info.Offset
+	n, err := io.Copy(io.MultiWriter(f, hash), io.LimitReader(body, remaining+1))
+	if err == nil && n > remaining {
+		err = errChunkTooLarge
+	}
+	if err != nil {
+		_ = f.Truncate(info.Offset)
+		return 0, nil, err
+	}
+	return n, hash.Sum(nil), nil
+}
+
+func (s *uploadStore) truncate(info *uploadInfo) {
+	if err := os.Truncate(s.partPath(info.ID), info.Offset); err != nil {
+		log.Printf("truncate upload %s: %v", info.ID, err)
+	}
+}
+
+// assemble 校验整个文件后保存到 fileService。状态保留到过期，期间 HEAD 仍然返回完成的偏移量和文件 ID。
+// 保存的是 .part 的硬链接，查询配额、写数据库等出错时 .part 和磁盘上的状态都不变，客户端可以重传最后一个分块；
+// 文件已保存但完成状态写不进去时删除这个文件，重传时不会再创建一份
+func (s *uploadStore) assemble(info *uploadInfo) error {
+	staged := filepath.Join(s.dir, info.ID+".assemble")
+	_ = os.Remove(staged)
+	if err := os.Link(s.partPath(info.ID), staged); err != nil {
+		return err
+	}
+	b, err := s.files.blobs.stageFile(staged)
+	if err != nil {
+		os.Remove(staged)
+		return err
+	}
+	if info.SHA256 != "" && b.SHA256 != info.SHA256 {
+		s.files.blobs.discard(b)
+		return errFileChecksum
+	}
+	file, err := s.files.save(info.OwnerID, info.Filename, b)
+	if err != nil {
+		s.files.blobs.discard(b)
+		return err
+	}
+	info.Completed = true
+	info.FileID = file.ID
+	if err := s.save(info); err != nil {
+		info.Completed = false
+		info.FileID = 0
+		if rerr := s.files.remove(file); rerr != nil {
+			log.Printf("remove file %d of upload %s: %v", file.ID, info.ID, rerr)
+		}
+		return err
+	}
+	if err := os.Remove(s.partPath(info.ID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("remove upload %s: %v", info.ID, err)
+	}
+	return nil
+}
+
+// setFileID 完成的上传通过 Upload-File-Id 返回保存的文件
+func setFileID(c *gin.Context, info *uploadInfo) {
+	if info.Completed {
+		c.Header("Upload-File-Id", strconv.FormatUint(uint64(info.FileID), 10))
+	}
+}
+
+// lookup 读取 URL 中的上传，不存在或已过期时写入响应并返回 false
+func (s *uploadStore) lookup(c *gin.Context) (*uploadInfo, bool) {
+	id := c.Param("id")
+	if !uploadIDPattern.MatchString(id) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Upload not found"})
+		return nil, false
+	}
+	info, err := s.load(id)
+	if errors.Is(err, os.ErrNotExist) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Upload not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	// 其他用户的上传同样返回 404
+	if info.OwnerID != c.GetUint("userID") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Upload not found"})
+		return nil, false
+	}
+	if !info.Completed && time.Now().After(info.ExpiresAt) {
+		c.JSON(http.StatusGone, gin.H{"error": "Upload expired"})
+		return nil, false
+	}
+	return info, true
+}
+
+func (s *uploadStore) acquire(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.busy[id] {
+		return false
+	}
+	s.busy[id] = true
+	return true
+}
+
+func (s *uploadStore) release(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.busy, id)
+}
+
+func (s *uploadStore) infoPath(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+func (s *uploadStore) partPath(id string) string {
+	return filepath.Join(s.dir, id+".part")
+}
+
+func (s *uploadStore) load(id string) (*uploadInfo, error) {
+	data, err := os.ReadFile(s.infoPath(id))
+	if err != nil {
+		return nil, err
+	}
+	var info uploadInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// save 先写临时文件再重命名，进程中途退出不会留下写了一半的状态
+func (s *uploadStore) save(info *uploadInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	tmp := s.infoPath(info.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.infoPath(info.ID))
+}
+
+func (s *uploadStore) remove(id string) {
+	for _, path := range []string{s.partPath(id), s.infoPath(id), filepath.Join(s.dir, id+".assemble")} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("remove upload %s: %v", id, err)
+		}
+	}
+}
+
+// cleanupLoop 定期删除过期的上传，已完成上传只删除状态，文件保留
+func (s *uploadStore) cleanupLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.removeExpired(time.Now())
+	}
+}
+
+func (s *uploadStore) removeExpired(now time.Time) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		log.Printf("list uploads: %v", err)
+		return
+	}
+	for _, path := range paths {
+		id := strings.TrimSuffix(filepath.Base(path), ".json")
+		if !s.acquire(id) {
+			continue
+		}
+		if info, err := s.load(id); err == nil && now.After(info.ExpiresAt) {
+			s.remove(id)
+		}
+		s.release(id)
+	}
+}
+
+// parseUploadMetadata 解析 "key base64value,key2 base64value2"，值可以省略
+func parseUploadMetadata(header string) (map[string]string, error) {
+	meta := make(map[string]string)
+	if strings.TrimSpace(header) == "" {
+		return meta, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, errors.New("Invalid Upload-Metadata")
+		}
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, errors.New("Invalid Upload-Metadata value for " + key)
+		}
+		meta[key] = string(value)
+	}
+	return meta, nil
+}
+
+// parseUploadChecksum 解析 "sha256 <base64>"，只支持 sha256
+func parseUploadChecksum(header string) ([]byte, error) {
+	algorithm, encoded, ok := strings.Cut(header, " ")
+	if !ok {
+		return nil, errors.New("Upload-Checksum is required")
+	}
+	if algorithm != "sha256" {
+		return nil, errors.New("Unsupported checksum algorithm " + algorithm)
+	}
+	sum, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sum) != sha256.Size {
+		return nil, errors.New("Invalid Upload-Checksum")
+	}
+	return sum, nil
+}
+
+func newUploadID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
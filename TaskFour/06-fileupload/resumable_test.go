@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type tusClient struct {
+	t       *testing.T
+	router  *gin.Engine
+	uploads *uploadStore
+}
+
+func newTusClient(t *testing.T, s *fileService) *tusClient {
+	t.Helper()
+
+	uploads, err := newUploadStore(filepath.Join(t.TempDir(), "uploads"), s, uploadTTL)
+	if err != nil {
+		t.Fatalf("new upload store: %v", err)
+	}
+	r := gin.New()
+	tus := r.Group("/api/uploads", tusResumable(), asUser(1, "user"))
+	tus.POST("", uploads.create)
+	tus.HEAD("/:id", uploads.status)
+	tus.PATCH("/:id", uploads.patch)
+	return &tusClient{t: t, router: r, uploads: uploads}
+}
+
+// create 返回上传的地址
+func (c *tusClient) create(length int, filename string) string {
+	c.t.Helper()
+
+	w := serve(c.router, http.MethodPost, "/api/uploads", nil, http.Header{
+		"Tus-Resumable":   {tusVersion},
+		"Upload-Length":   {strconv.Itoa(length)},
+		"Upload-Metadata": {"filename " + base64.StdEncoding.EncodeToString([]byte(filename))},
+	})
+	if w.Code != http.StatusCreated {
+		c.t.Fatalf("create: expected 201, got %d: %s", w.Code, w.Body)
+	}
+	return w.Header().Get("Location")
+}
+
+// patch checksum 为 nil 时使用 chunk 的 SHA-256
+func (c *tusClient) patch(location string, offset int, chunk, checksum []byte) *http.Response {
+	c.t.Helper()
+
+	if checksum == nil {
+		sum := sha256.Sum256(chunk)
+		checksum = sum[:]
+	}
+	return serve(c.router, http.MethodPatch, location, bytes.NewReader(chunk), http.Header{
+		"Tus-Resumable":   {tusVersion},
+		"Content-Type":    {"application/offset+octet-stream"},
+		"Upload-Offset":   {strconv.Itoa(offset)},
+		"Upload-Checksum": {"sha256 " + base64.StdEncoding.EncodeToString(checksum)},
+	}).Result()
+}
+
+func (c *tusClient) offset(location string) string {
+	c.t.Helper()
+
+	w := serve(c.router, http.MethodHead, location, nil, http.Header{"Tus-Resumable": {tusVersion}})
+	if w.Code != http.StatusOK {
+		c.t.Fatalf("head: expected 200, got %d", w.Code)
+	}
+	return w.Header().Get("Upload-Offset")
+}
+
+func TestTusPatchRejectsBadChunks(t *testing.T) {
+	c := newTusClient(t, newTestService(t))
+	location := c.create(11, "hello.txt")
+
+	if resp := c.patch(location, 0, []byte("hello "), nil); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.StatusCode)
+	}
+
+	cases := []struct {
+		name     string
+		offset   int
+		checksum []byte
+		want     int
+	}{
+		{"offset behind", 0, nil, http.StatusConflict},
+		{"offset ahead", 8, nil, http.StatusConflict},
+		{"checksum mismatch", 6, make([]byte, sha256.Size), statusChecksum},
+	}
+	for _, tc := range cases {
+		resp := c.patch(location, tc.offset, []byte("world"), tc.checksum)
+		if resp.StatusCode != tc.want {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.want, resp.StatusCode)
+		}
+		if got := c.offset(location); got != "6" {
+			t.Errorf("%s: expected offset to stay at 6, got %s", tc.name, got)
+		}
+	}
+	if resp := c.patch(location, 0, []byte("world"), nil); resp.Header.Get("Upload-Offset") != "6" {
+		t.Errorf("expected the conflict response to report offset 6, got %q", resp.Header.Get("Upload-Offset"))
+	}
+
+	resp := c.patch(location, 6, []byte("world"), nil)
+	if resp.StatusCode != http.StatusNoContent || resp.Header.Get("Upload-File-Id") == "" {
+		t.Fatalf("expected the upload to complete, got %d", resp.StatusCode)
+	}
+}
+
+func TestTusRetryLastChunkAfterSaveFailure(t *testing.T) {
+	s := newTestService(t)
+	c := newTusClient(t, s)
+	location := c.create(11, "hello.txt")
+	if resp := c.patch(location, 0, []byte("hello "), nil); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.StatusCode)
+	}
+
+	// 保存文件时数据库出错
+	if err := s.db.Migrator().DropTable(&File{}); err != nil {
+		t.Fatalf("drop table: %v", err)
+	}
+	resp := c.patch(location, 6, []byte("world"), nil)
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", resp.StatusCode)
+	}
+	if got := c.offset(location); got != "6" {
+		t.Fatalf("expected offset to stay at 6, got %s", got)
+	}
+
+	if err := s.db.AutoMigrate(&File{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	resp = c.patch(location, 6, []byte("world"), nil)
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected retry to complete the upload, got %d", resp.StatusCode)
+	}
+	var file File
+	if err := s.db.First(&file, resp.Header.Get("Upload-File-Id")).Error; err != nil {
+		t.Fatalf("load file: %v", err)
+	}
+	sum := sha256.Sum256([]byte("hello world"))
+	if file.Size != 11 || file.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Fatalf("unexpected file %+v", file)
+	}
+	waitScanned(t, s, &file)
+}
+
+// 文件已保存但上传状态写入失败时删除这个文件，重传最后一个分块不会留下重复的文件
+func TestTusRetryAfterStateSaveFailure(t *testing.T) {
+	s := newTestService(t)
+	c := newTusClient(t, s)
+	location := c.create(11, "hello.txt")
+	if resp := c.patch(location, 0, []byte("hello "), nil); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.StatusCode)
+	}
+
+	// 状态先写到 .tmp 再改名，.tmp 是目录时写入失败
+	tmp := c.uploads.infoPath(path.Base(location)) + ".tmp"
+	if err := os.Mkdir(tmp, 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if resp := c.patch(location, 6, []byte("world"), nil); resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", resp.StatusCode)
+	}
+	var count int64
+	if err := s.db.Model(&File{}).Count(&count).Error; err != nil || count != 0 {
+		t.Fatalf("expected the saved file to be removed, got %d (%v)", count, err)
+	}
+	if got := c.offset(location); got != "6" {
+		t.Fatalf("expected offset to stay at 6, got %s", got)
+	}
+
+	if err := os.Remove(tmp); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	resp := c.patch(location, 6, []byte("world"), nil)
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected retry to complete the upload, got %d", resp.StatusCode)
+	}
+	if err := s.db.Model(&File{}).Count(&count).Error; err != nil || count != 1 {
+		t.Fatalf("expected exactly one file, got %d (%v)", count, err)
+	}
+	var file File
+	if err := s.db.First(&file, resp.Header.Get("Upload-File-Id")).Error; err != nil {
+		t.Fatalf("load file: %v", err)
+	}
+	waitScanned(t, s, &file)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ========== 上传校验 ==========
+// 上传依次经过：请求大小 -> 单个文件大小 -> 类型白名单（按内容检测的 MIME 和扩展名）-> 用户配额 -> 扫描。
+// 扫描在文件保存之后异步进行，扫描通过之前文件不能下载，发现威胁的文件被隔离
+
+// uploadPolicy 上传限制，0 表示不限制
+type uploadPolicy struct {
+	// MaxFileSize 单个文件的大小上限，可恢复上传同样适用
+	MaxFileSize int64
+	// MaxRequestSize 单个请求的请求体上限。更大的文件需要用可恢复上传分块发送
+	MaxRequestSize int64
+	// UserQuota 每个用户所有文件的总大小上限，相同内容去重存储也按各自的大小计算
+	UserQuota int64
+	// AllowedTypes 允许的 MIME 类型（http.DetectContentType 的结果，不含参数）及其扩展名
+	AllowedTypes map[string][]string
+}
+
+func defaultUploadPolicy() uploadPolicy {
+	return uploadPolicy{
+		MaxFileSize:    1 << 30,
+		MaxRequestSize: 64 << 20,
+		UserQuota:      5 << 30,
+		AllowedTypes: map[string][]string{
+			"text/plain":         {".txt", ".csv", ".md", ".json", ".log"},
+			"application/pdf":    {".pdf"},
+			"image/png":          {".png"},
+			"image/jpeg":         {".jpg", ".jpeg"},
+			"image/gif":          {".gif"},
+			"image/webp":         {".webp"},
+			"application/zip":    {".zip", ".docx", ".xlsx", ".pptx"},
+			"application/x-gzip": {".gz", ".tgz"},
+		},
+	}
+}
+
+// uploadError 校验失败，Status 是返回给客户端的状态码
+type uploadError struct {
+	Status  int
+	Message string
+}
+
+func (e *uploadError) Error() string {
+	return e.Message
+}
+
+func errFileTooLarge(limit int64) error {
+	return &uploadError{Status: http.StatusRequestEntityTooLarge, Message: fmt.Sprintf("File exceeds the %d byte limit", limit)}
+}
+
+// checkSize 在接收内容之前用声明的大小检查
+func (p uploadPolicy) checkSize(size int64) error {
+	if p.MaxFileSize > 0 && size > p.MaxFileSize {
+		return errFileTooLarge(p.MaxFileSize)
+	}
+	return nil
+}
+
+// checkType 检查内容检测出的类型是否在白名单中，并且扩展名与类型相符，
+// 避免例如把 HTML 改名为 .png 上传
+func (p uploadPolicy) checkType(name, detected string) error {
+	mediaType, _, err := mime.ParseMediaType(detected)
+	if err != nil {
+		mediaType = detected
+	}
+	exts, ok := p.AllowedTypes[mediaType]
+	if !ok {
+		return &uploadError{Status: http.StatusUnsupportedMediaType, Message: "File type " + mediaType + " is not allowed"}
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, allowed := range exts {
+		if ext == allowed {
+			return nil
+		}
+	}
+	return &uploadError{Status: http.StatusUnsupportedMediaType, Message: "Extension " + ext + " does not match file type " + mediaType}
+}
+
+// checkQuota used 是用户已有文件的总大小
+func (p uploadPolicy) checkQuota(used, size int64) error {
+	if p.UserQuota > 0 && used+size > p.UserQuota {
+		return &uploadError{Status: http.StatusRequestEntityTooLarge, Message: fmt.Sprintf("Storage quota of %d bytes exceeded", p.UserQuota)}
+	}
+	return nil
+}
+
+// requestTooLarge 把 http.MaxBytesReader 的错误转换为 413
+func requestTooLarge(err error) error {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return &uploadError{Status: http.StatusRequestEntityTooLarge, Message: fmt.Sprintf("Request body exceeds the %d byte limit", maxErr.Limit)}
+	}
+	return err
+}
+
+// respondError 校验失败（包括请求体超过限制）时返回对应的状态码，其他错误使用 status
+func respondError(c *gin.Context, err error, status int) {
+	var uerr *uploadError
+	if errors.As(requestTooLarge(err), &uerr) {
+		c.JSON(uerr.Status, gin.H{"error": uerr.Message})
+		return
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
+
+// limitRequestBody 限制上传请求的请求体大小
+func limitRequestBody(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limit > 0 {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		}
+		c.Next()
+	}
+}
+
+// ========== 扫描 ==========
+
+// File.Status 的取值
+const (
+	fileStatusPending     = "pending"
+	fileStatusClean       = "clean"
+	fileStatusQuarantined = "quarantined"
+)
+
+// Scanner 检查文件内容，例如接入杀毒引擎。返回的 Threat 不为空时文件被隔离；
+// 返回错误表示这次没有完成扫描，文件保持 pending，下次启动时重新扫描
+type Scanner interface {
+	Scan(ctx context.Context, file *File, content io.Reader) (ScanResult, error)
+}
+
+type ScanResult struct {
+	Threat string
+}
+
+// eicarScanner 占位实现，只识别 EICAR 测试文件，用于在没有接入杀毒引擎时验证隔离流程
+type eicarScanner struct{}
+
+var eicarSignature = []byte(`X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`)
+
+func (eicarScanner) Scan(_ context.Context, _ *File, content io.Reader) (ScanResult, error) {
+	data, err := io.ReadAll(io.LimitReader(content, 1<<20))
+	if err != nil {
+		return ScanResult{}, err
+	}
+	if bytes.Contains(data, eicarSignature) {
+		return ScanResult{Threat: "EICAR-Test-File"}, nil
+	}
+	return ScanResult{}, nil
+}
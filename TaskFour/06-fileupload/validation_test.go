@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestCheckType(t *testing.T) {
+	policy := defaultUploadPolicy()
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	html := []byte("<!DOCTYPE html><html><script>alert(1)</script></html>")
+
+	cases := []struct {
+		name    string
+		content []byte
+		ok      bool
+	}{
+		{"image.png", png, true},
+		{"IMAGE.PNG", png, true},
+		{"notes.txt", []byte("hello"), true},
+		// 按内容检测类型，改扩展名不能绕过白名单
+		{"page.png", html, false},
+		{"page.html", html, false},
+		{"image.jpg", png, false},
+		{"image", png, false},
+		{"program.png", []byte("MZ\x90\x00\x03\x00\x00\x00\x04\x00\x00\x00\xff\xff\x00\x00"), false},
+	}
+	for _, tc := range cases {
+		err := policy.checkType(tc.name, http.DetectContentType(tc.content))
+		if tc.ok {
+			if err != nil {
+				t.Errorf("%s: expected to be allowed, got %v", tc.name, err)
+			}
+			continue
+		}
+		var uerr *uploadError
+		if !errors.As(err, &uerr) || uerr.Status != http.StatusUnsupportedMediaType {
+			t.Errorf("%s: expected 415, got %v", tc.name, err)
+		}
+	}
+}
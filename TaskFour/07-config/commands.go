@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ========== 命令行 ==========
+// 不带参数时启动服务，其他用法：
+//
+//	config validate [file]        检查配置文件（默认按启动时的规则查找），包括未知的键、取值范围和密钥
+//	config defaults               输出带注释的示例配置，内容由 Config 的字段标签生成
+//	config genkey                 生成主密钥，设置为 APP_MASTER_KEY
+//	config encrypt < secret.txt   从标准输入读取明文，输出可以写入配置文件的 enc: 值
+
+func runCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	switch args[0] {
+	case "validate":
+		var path string
+		if len(args) > 1 {
+			path = args[1]
+		}
+		_, used, err := readConfig(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "validate: %v\n", err)
+			return 1
+		}
+		if used == "" {
+			used = "defaults and environment"
+		}
+		fmt.Fprintf(stdout, "%s: OK\n", used)
+		return 0
+
+	case "defaults":
+		writeDefaults(stdout, reflect.TypeOf(Config{}), 0)
+		return 0
+
+	case "genkey":
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			fmt.Fprintf(stderr, "genkey: %v\n", err)
+			return 1
+		}
+		fmt.Fprintln(stdout, base64.StdEncoding.EncodeToString(key))
+		return 0
+
+	case "encrypt":
+		// 明文从标准输入读取，不会留在 shell 历史和进程列表中
+		key, err := masterKey()
+		if err != nil {
+			fmt.Fprintf(stderr, "encrypt: %v\n", err)
+			return 1
+		}
+		plain, err := bufio.NewReader(stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			fmt.Fprintf(stderr, "encrypt: %v\n", err)
+			return 1
+		}
+		plain = strings.TrimRight(plain, "\r\n")
+		if plain == "" {
+			fmt.Fprintln(stderr, "encrypt: no secret on stdin")
+			return 1
+		}
+		encrypted, err := encryptSecret(key, plain)
+		if err != nil {
+			fmt.Fprintf(stderr, "encrypt: %v\n", err)
+			return 1
+		}
+		fmt.Fprintln(stdout, "enc:"+encrypted)
+		return 0
+	}
+
+	fmt.Fprintf(stderr, "unknown command %q\nusage: %s [validate [file] | defaults | genkey | encrypt]\n", args[0], os.Args[0])
+	return 2
+}
+
+// writeDefaults 按字段定义的顺序输出 YAML，doc 和 validate 标签作为注释，没有默认值的项留空
+func writeDefaults(w io.Writer, t reflect.Type, depth int) {
+	indent := strings.Repeat("  ", depth)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if depth == 0 && i > 0 {
+			fmt.Fprintln(w)
+		}
+		if doc := field.Tag.Get("doc"); doc != "" {
+			fmt.Fprintf(w, "%s# %s\n", indent, doc)
+		}
+		key := field.Tag.Get("mapstructure")
+		if isSection(field.Type) {
+			fmt.Fprintf(w, "%s%s:\n", indent, key)
+			writeDefaults(w, field.Type, depth+1)
+			continue
+		}
+		if rules := field.Tag.Get("validate"); rules != "" {
+			fmt.Fprintf(w, "%s# 校验: %s\n", indent, rules)
+		}
+		fmt.Fprintf(w, "%s%s: %s\n", indent, key, yamlValue(field.Type, field.Tag.Get("default")))
+	}
+}
+
+// yamlValue 把 default 标签转换为 YAML 中的值，字符串、密钥和时长加引号
+func yamlValue(t reflect.Type, def string) string {
+	switch {
+	case t.Kind() == reflect.Slice:
+		if def == "" {
+			return "[]"
+		}
+		items := strings.Split(def, ",")
+		for i, item := range items {
+			items[i] = yamlValue(t.Elem(), item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case t.Kind() == reflect.String, t == reflect.TypeOf(Secret{}), t == reflect.TypeOf(time.Duration(0)):
+		return strconv.Quote(def)
+	case def == "":
+		return "0"
+	}
+	return def
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/spf13/viper"
+)
+
+// 字段的标签：
+//
+//	mapstructure  配置中的键名
+//	default       默认值，切片用逗号分隔
+//	validate      校验规则（go-playground/validator），时长可以写 min=1m
+//	doc           说明，config defaults 输出为注释
+type Config struct {
+	Server    ServerConfig    `mapstructure:"server" doc:"HTTP 服务，修改后需要重启"`
+	Database  DatabaseConfig  `mapstructure:"database" doc:"数据库连接"`
+	JWT       JWTConfig       `mapstructure:"jwt" doc:"JWT 签发"`
+	Log       LogConfig       `mapstructure:"log" doc:"日志，修改后立即生效"`
+	RateLimit RateLimitConfig `mapstructure:"rate_limit" doc:"按客户端 IP 限流，修改后立即生效"`
+	CORS      CORSConfig      `mapstructure:"cors" doc:"跨域策略，修改后立即生效"`
+}
+
+type ServerConfig struct {
+	Port int    `mapstructure:"port" default:"8088" validate:"min=1,max=65535" doc:"监听端口"`
+	Host string `mapstructure:"host" default:"0.0.0.0" validate:"required" doc:"监听地址"`
+	Mode string `mapstructure:"mode" default:"debug" validate:"oneof=debug release test" doc:"Gin 模式：debug、release 或 test"`
+}
+
+type DatabaseConfig struct {
+	Host     string `mapstructure:"host" default:"localhost" validate:"required" doc:"数据库主机"`
+	Port     int    `mapstructure:"port" default:"3306" validate:"min=1,max=65535" doc:"数据库端口"`
+	Username string `mapstructure:"username" validate:"required" doc:"用户名"`
+	Password Secret `mapstructure:"password" validate:"required,notplaceholder" doc:"密码，可以写 file:<路径>、env:<变量名> 或 enc:<密文>（config encrypt 生成）"`
+	DBName   string `mapstructure:"dbname" validate:"required" doc:"数据库名"`
+}
+
+type JWTConfig struct {
+	Secret Secret        `mapstructure:"secret" validate:"required,min=32,notplaceholder" doc:"签名密钥，至少 32 个字符，写法同 database.password"`
+	Expire time.Duration `mapstructure:"expire" default:"24h" validate:"min=1m,max=720h" doc:"token 有效期，如 30m、24h"`
+}
+
+type LogConfig struct {
+	Level string `mapstructure:"level" default:"info" validate:"oneof=debug info warn error" doc:"日志级别：debug、info、warn 或 error"`
+}
+
+type RateLimitConfig struct {
+	RPS   float64 `mapstructure:"rps" default:"0" validate:"min=0" doc:"每个客户端 IP 每秒的请求数，0 表示不限制"`
+	Burst int     `mapstructure:"burst" default:"20" validate:"min=1" doc:"允许的突发请求数"`
+}
+
+type CORSConfig struct {
+	AllowOrigins []string `mapstructure:"allow_origins" validate:"dive,required" doc:"允许的来源，为空时不允许跨域，* 表示任意来源"`
+	AllowMethods []string `mapstructure:"allow_methods" default:"GET,POST,PUT,PATCH,DELETE,OPTIONS" validate:"dive,oneof=GET HEAD POST PUT PATCH DELETE OPTIONS" doc:"允许的方法"`
+	AllowHeaders []string `mapstructure:"allow_headers" default:"Content-Type,Authorization" doc:"允许的请求头"`
+}
+
+func newViper() *viper.Viper {
+	v := viper.New()
+	// 设置配置文件名称（不含扩展名）
+	v.SetConfigName("config")
+	// 设置配置文件类型
+	v.SetConfigType("yaml")
+	// 添加配置文件搜索路径
+	v.AddConfigPath(".")
+	v.AddConfigPath("$HOME/.app")
+
+	// 读取环境变量
+	v.AutomaticEnv()
+	v.SetEnvPrefix("APP")
+
+	// 设置默认值，来自字段的 default 标签
+	walkConfig(reflect.TypeOf(Config{}), "", func(key string, field reflect.StructField) {
+		if def, ok := field.Tag.Lookup("default"); ok {
+			if field.Type.Kind() == reflect.Slice {
+				v.SetDefault(key, strings.Split(def, ","))
+			} else {
+				v.SetDefault(key, def)
+			}
+		}
+	})
+	return v
+}
+
+// walkConfig 按定义顺序访问 t 中的配置项，key 是以点分隔的完整键名。
+// 嵌套的配置段（Secret 以外的结构体）先访问本身再访问其中的字段
+func walkConfig(t reflect.Type, prefix string, visit func(key string, field reflect.StructField)) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := prefix + field.Tag.Get("mapstructure")
+		visit(key, field)
+		if isSection(field.Type) {
+			walkConfig(field.Type, key+".", visit)
+		}
+	}
+}
+
+func isSection(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != reflect.TypeOf(Secret{})
+}
+
+// readConfig 读取并校验配置。path 为空时在默认路径中查找配置文件，找不到时只使用默认值和环境变量；
+// 同时返回实际使用的配置文件
+func readConfig(path string) (*Config, string, error) {
+	v := newViper()
+	if path != "" {
+		v.SetConfigFile(path)
+	}
+	if err := v.ReadInConfig(); err != nil {
+		var notFound viper.ConfigFileNotFoundError
+		if path != "" || !errors.As(err, &notFound) {
+			return nil, "", err
+		}
+		log.Printf("Warning: %v", err)
+		log.Println("Using default values and environment variables")
+	}
+
+	config, err := LoadConfig(v)
+	if err != nil {
+		return nil, "", err
+	}
+	return config, v.ConfigFileUsed(), nil
+}
+
+// LoadConfig 将 Viper 中的配置数据映射到 Config 结构体并校验，
+// 配置数据来源包括：配置文件、环境变量和默认值（在 newViper 中设置）。
+// 配置中有 Config 没有定义的键（通常是拼写错误）时返回错误
+func LoadConfig(v *viper.Viper) (*Config, error) {
+	var config Config
+	var md mapstructure.Metadata
+
+	// 使用 Unmarshal 将配置数据解析到 config 结构体中，同时解析 Secret 字段的引用
+	err := v.Unmarshal(&config, withSecrets, func(c *mapstructure.DecoderConfig) { c.Metadata = &md })
+	if err != nil {
+		return nil, err
+	}
+	if len(md.Unused) > 0 {
+		slices.Sort(md.Unused)
+		return nil, fmt.Errorf("unknown config keys: %s", strings.Join(md.Unused, ", "))
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	// 返回解析成功的配置对象
+	return &config, nil
+}
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	// 错误中使用配置的键名
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		return field.Tag.Get("mapstructure")
+	})
+	// Secret 按明文校验，错误信息中不包含取值
+	v.RegisterCustomTypeFunc(func(field reflect.Value) any {
+		return field.Interface().(Secret).Reveal()
+	}, Secret{})
+	err := v.RegisterValidation("notplaceholder", func(fl validator.FieldLevel) bool {
+		return !slices.ContainsFunc(placeholderSecrets, func(p string) bool {
+			return strings.EqualFold(fl.Field().String(), p)
+		})
+	})
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Validate 按 validate 标签检查配置，返回所有不通过的配置项。热加载时校验不通过的配置不会生效
+func (c *Config) Validate() error {
+	err := validate.Struct(c)
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return err
+	}
+	errs := make([]error, 0, len(fieldErrs))
+	for _, fe := range fieldErrs {
+		// Namespace 形如 Config.server.port
+		_, key, _ := strings.Cut(fe.Namespace(), ".")
+		errs = append(errs, fmt.Errorf("%s: %s", key, describeRule(fe)))
+	}
+	return errors.Join(errs...)
+}
+
+func describeRule(fe validator.FieldError) string {
+	param := fe.Param()
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "notplaceholder":
+		return "placeholder value must be replaced"
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(param, " ", ", ")
+	case "min":
+		if fe.Kind() == reflect.String {
+			return "must be at least " + param + " characters"
+		}
+		return "must be at least " + param
+	case "max":
+		return "must be at most " + param
+	}
+	return "failed " + fe.Tag() + " validation"
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 以下组件都可以在运行中通过 update 替换配置，配合 Subscribe 实现热加载
+
+// ========== 日志级别 ==========
+
+var logLevel = new(slog.LevelVar)
+
+// setupLogging 让 slog 和标准库 log 的输出都经过可以调整级别的 handler
+func setupLogging(cfg LogConfig) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel})))
+	setLogLevel(cfg)
+}
+
+func setLogLevel(cfg LogConfig) {
+	level, err := parseLogLevel(cfg.Level)
+	if err != nil {
+		// 配置经过校验，不会走到这里
+		return
+	}
+	logLevel.Set(level)
+	slog.Info("log level set", "level", level)
+}
+
+func parseLogLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("unknown level %q", s)
+	}
+	return level, nil
+}
+
+// ========== 限流 ==========
+
+// maxBuckets 记录的客户端数量上限，超过时淘汰最久没有请求的客户端
+const maxBuckets = 10000
+
+// rateLimiter 按客户端 IP 的令牌桶限流
+type rateLimiter struct {
+	mu      sync.Mutex
+	cfg     RateLimitConfig
+	buckets map[string]*list.Element
+	// lru 按最近请求的时间排列的 *bucket，最近的在前
+	lru *list.List
+}
+
+type bucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(cfg RateLimitConfig) *rateLimiter {
+	return &rateLimiter{cfg: cfg, buckets: make(map[string]*list.Element), lru: list.New()}
+}
+
+// update 替换限流参数，所有客户端按新参数重新开始计算
+func (l *rateLimiter) update(cfg RateLimitConfig) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.cfg = cfg
+	l.buckets = make(map[string]*list.Element)
+	l.lru.Init()
+	slog.Info("rate limit updated", "rps", cfg.RPS, "burst", cfg.Burst)
+}
+
+func (l *rateLimiter) allow(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.cfg.RPS <= 0 {
+		return true
+	}
+	burst := float64(l.cfg.Burst)
+	var b *bucket
+	if e, ok := l.buckets[key]; ok {
+		l.lru.MoveToFront(e)
+		b = e.Value.(*bucket)
+	} else {
+		if l.lru.Len() >= maxBuckets {
+			oldest := l.lru.Back()
+			l.lru.Remove(oldest)
+			delete(l.buckets, oldest.Value.(*bucket).key)
+		}
+		b = &bucket{key: key, tokens: burst, last: now}
+		l.buckets[key] = l.lru.PushFront(b)
+	}
+	b.tokens = min(burst, b.tokens+now.Sub(b.last).Seconds()*l.cfg.RPS)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func (l *rateLimiter) middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !l.allow(c.ClientIP(), time.Now()) {
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// ========== CORS ==========
+
+type corsPolicy struct {
+	cfg atomic.Pointer[CORSConfig]
+}
+
+func newCORSPolicy(cfg CORSConfig) *corsPolicy {
+	p := &corsPolicy{}
+	p.cfg.Store(&cfg)
+	return p
+}
+
+func (p *corsPolicy) update(cfg CORSConfig) {
+	p.cfg.Store(&cfg)
+	slog.Info("CORS policy updated", "allow_origins", cfg.AllowOrigins)
+}
+
+func (p *corsPolicy) middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := p.cfg.Load()
+		origin := c.GetHeader("Origin")
+		allowed := origin != "" && (slices.Contains(cfg.AllowOrigins, "*") || slices.Contains(cfg.AllowOrigins, origin))
+		if origin != "" {
+			c.Writer.Header().Add("Vary", "Origin")
+		}
+		if allowed {
+			c.Header("Access-Control-Allow-Origin", origin)
+		}
+
+		// 预检请求
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+			if allowed {
+				c.Header("Access-Control-Allow-Methods", strings.Join(cfg.AllowMethods, ", "))
+				c.Header("Access-Control-Allow-Headers", strings.Join(cfg.AllowHeaders, ", "))
+				c.Header("Access-Control-Max-Age", "600")
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(RateLimitConfig{RPS: 1, Burst: 2})
+	now := time.Unix(1700000000, 0)
+
+	for i, want := range []bool{true, true, false} {
+		if got := l.allow("a", now); got != want {
+			t.Fatalf("request %d: expected %v, got %v", i, want, got)
+		}
+	}
+	if !l.allow("b", now) {
+		t.Fatal("expected clients to be limited separately")
+	}
+	if !l.allow("a", now.Add(time.Second)) {
+		t.Fatal("expected a token to be refilled after one second")
+	}
+}
+
+func TestRateLimiterEvictsOldestClients(t *testing.T) {
+	l := newRateLimiter(RateLimitConfig{RPS: 1, Burst: 1})
+	now := time.Unix(1700000000, 0)
+
+	l.allow("first", now)
+	for i := 0; i < maxBuckets; i++ {
+		l.allow(strconv.Itoa(i), now)
+		// 保持 first 最近有请求
+		if i == maxBuckets/2 {
+			l.allow("first", now)
+		}
+	}
+	if len(l.buckets) != maxBuckets || l.lru.Len() != maxBuckets {
+		t.Fatalf("expected %d buckets, got %d", maxBuckets, len(l.buckets))
+	}
+	if _, ok := l.buckets["first"]; !ok {
+		t.Fatal("expected a recently used client to be kept")
+	}
+	if _, ok := l.buckets["0"]; ok {
+		t.Fatal("expected the least recently used client to be evicted")
+	}
+}
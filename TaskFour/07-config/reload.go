@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"log"
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ========== 配置热加载 ==========
+// 定期检查配置文件的内容，变化后重新读取并校验，通过后原子地替换当前配置并通知订阅者，
+// 读取或校验失败时保留原来的配置。
+// 使用轮询而不是 inotify：编辑器保存时常常写新文件再改名，Kubernetes 的 ConfigMap 通过替换符号链接更新，
+// 比较文件内容的方式对这些情况都有效。环境变量在进程内不会变化，只在启动时读取一次
+
+const reloadInterval = 2 * time.Second
+
+// ConfigStore 持有当前生效的配置
+type ConfigStore struct {
+	path    string
+	current atomic.Pointer[Config]
+
+	// mu 串行化重新加载和订阅，订阅者按配置变化的顺序收到通知
+	mu          sync.Mutex
+	digest      []byte
+	subscribers []func(old, cur *Config)
+}
+
+// NewConfigStore path 是 cfg 读取自的配置文件，为空时不会重新加载
+func NewConfigStore(path string, cfg *Config) *ConfigStore {
+	s := &ConfigStore{path: path}
+	s.current.Store(cfg)
+	if path != "" {
+		if data, err := os.ReadFile(path); err == nil {
+			sum := sha256.Sum256(data)
+			s.digest = sum[:]
+		}
+	}
+	return s
+}
+
+// Current 返回当前配置，调用方不能修改返回的配置
+func (s *ConfigStore) Current() *Config {
+	return s.current.Load()
+}
+
+func (s *ConfigStore) Path() string {
+	return s.path
+}
+
+// Subscribe 配置中 section 选出的部分变化时（reflect.DeepEqual 比较）用新的值调用 fn。
+// fn 在重新加载的 goroutine 中同步执行，不能阻塞；订阅时不会用当前值调用
+func Subscribe[T any](s *ConfigStore, section func(*Config) T, fn func(T)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscribers = append(s.subscribers, func(old, cur *Config) {
+		if v := section(cur); !reflect.DeepEqual(section(old), v) {
+			fn(v)
+		}
+	})
+}
+
+// Watch 每隔 interval 检查一次配置文件，直到 ctx 取消
+func (s *ConfigStore) Watch(ctx context.Context, interval time.Duration) {
+	if s.path == "" {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if changed, err := s.Reload(); err != nil {
+			log.Printf("Config reload rejected, keeping the previous config: %v", err)
+		} else if changed {
+			log.Printf("Config reloaded from %s", s.path)
+		}
+	}
+}
+
+// Reload 配置文件的内容变化时重新加载，返回是否替换了配置。
+// 同样的内容只尝试一次，有错误的文件不会在每次检查时重复报错
+func (s *ConfigStore) Reload() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return false, err
+	}
+	sum := sha256.Sum256(data)
+	if bytes.Equal(sum[:], s.digest) {
+		return false, nil
+	}
+	s.digest = sum[:]
+
+	cfg, _, err := readConfig(s.path)
+	if err != nil {
+		return false, err
+	}
+	old := s.current.Swap(cfg)
+	for _, notify := range s.subscribers {
+		callSubscriber(notify, old, cfg)
+	}
+	return true, nil
+}
+
+// callSubscriber 一个订阅者 panic 不影响其他订阅者和之后的重新加载
+func callSubscriber(notify func(old, cur *Config), old, cur *Config) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Config subscriber panicked: %v", r)
+		}
+	}()
+	notify(old, cur)
+}
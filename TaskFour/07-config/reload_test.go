@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testConfig = `
+database:
+  username: app
+  password: s3cr3t-for-tests
+  dbname: app
+jwt:
+  secret: 0123456789abcdef0123456789abcdef
+log:
+  level: info
+rate_limit:
+  rps: 10
+`
+
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+}
+
+func newTestStore(t *testing.T) *ConfigStore {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, testConfig)
+	cfg, _, err := readConfig(path)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	return NewConfigStore(path, cfg)
+}
+
+func TestReload(t *testing.T) {
+	s := newTestStore(t)
+	var levels []string
+	var rateLimits int
+	Subscribe(s, func(c *Config) LogConfig { return c.Log }, func(cfg LogConfig) { levels = append(levels, cfg.Level) })
+	Subscribe(s, func(c *Config) RateLimitConfig { return c.RateLimit }, func(RateLimitConfig) { rateLimits++ })
+
+	// 内容没有变化
+	if changed, err := s.Reload(); changed || err != nil {
+		t.Fatalf("expected unchanged file to be skipped, got %v (%v)", changed, err)
+	}
+
+	// 校验不通过时保留原来的配置，同样的内容不再重复报错
+	writeConfig(t, s.Path(), strings.Replace(testConfig, "level: info", "level: verbose", 1))
+	if changed, err := s.Reload(); changed || err == nil || !strings.Contains(err.Error(), "log.level") {
+		t.Fatalf("expected invalid config to be rejected, got %v (%v)", changed, err)
+	}
+	if changed, err := s.Reload(); changed || err != nil {
+		t.Fatalf("expected the rejected file to be skipped, got %v (%v)", changed, err)
+	}
+	if got := s.Current().Log.Level; got != "info" {
+		t.Fatalf("expected the previous config to be kept, got level %s", got)
+	}
+
+	// 只通知变化的部分
+	writeConfig(t, s.Path(), strings.Replace(testConfig, "level: info", "level: debug", 1))
+	if changed, err := s.Reload(); !changed || err != nil {
+		t.Fatalf("expected config to be reloaded, got %v (%v)", changed, err)
+	}
+	if got := s.Current().Log.Level; got != "debug" {
+		t.Fatalf("expected level debug, got %s", got)
+	}
+	if len(levels) != 1 || levels[0] != "debug" || rateLimits != 0 {
+		t.Fatalf("unexpected notifications: levels %v, rate limits %d", levels, rateLimits)
+	}
+}
+
+func TestReloadSurvivesPanickingSubscriber(t *testing.T) {
+	s := newTestStore(t)
+	var notified bool
+	Subscribe(s, func(c *Config) LogConfig { return c.Log }, func(LogConfig) { panic("boom") })
+	Subscribe(s, func(c *Config) LogConfig { return c.Log }, func(LogConfig) { notified = true })
+
+	writeConfig(t, s.Path(), strings.Replace(testConfig, "level: info", "level: warn", 1))
+	if changed, err := s.Reload(); !changed || err != nil {
+		t.Fatalf("expected config to be reloaded, got %v (%v)", changed, err)
+	}
+	if !notified {
+		t.Fatal("expected the second subscriber to be notified")
+	}
+}
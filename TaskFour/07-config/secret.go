@@ -0,0 +1,192 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+)
+
+// ========== 密钥 ==========
+// 配置中的密码和密钥使用 Secret 类型，打印、记录日志和序列化为 JSON 时都只显示 ******。
+// 配置值可以是：
+//
+//	file:/run/secrets/db_password   读取文件内容（去掉末尾的换行）
+//	env:DB_PASS                     读取环境变量
+//	enc:<base64>                    用 APP_MASTER_KEY 解密的 AES-256-GCM 密文，由 config encrypt 生成
+//
+// 其他值按明文使用，只建议在本地开发时这样做
+
+const redacted = "******"
+
+// masterKeyEnv 主密钥的环境变量，值为 base64 编码的 32 字节
+const masterKeyEnv = "APP_MASTER_KEY"
+
+// Secret 零值表示未设置
+type Secret struct {
+	value string
+}
+
+// Reveal 返回明文，只在真正使用密钥的地方调用
+func (s Secret) Reveal() string {
+	return s.value
+}
+
+func (s Secret) IsZero() bool {
+	return s.value == ""
+}
+
+func (s Secret) String() string {
+	if s.value == "" {
+		return ""
+	}
+	return redacted
+}
+
+func (s Secret) GoString() string {
+	return `Secret("` + s.String() + `")`
+}
+
+// Format 让 %v、%+v、%#v、%s、%q 等所有格式都不输出明文
+func (s Secret) Format(f fmt.State, verb rune) {
+	if verb == 'v' && f.Flag('#') {
+		fmt.Fprint(f, s.GoString())
+		return
+	}
+	if verb == 'q' {
+		fmt.Fprintf(f, "%q", s.String())
+		return
+	}
+	fmt.Fprint(f, s.String())
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + s.String() + `"`), nil
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(s.String())
+}
+
+// resolveSecret 按前缀取得密钥的明文
+func resolveSecret(ref string) (Secret, error) {
+	scheme, rest, _ := strings.Cut(ref, ":")
+	switch scheme {
+	case "file":
+		data, err := os.ReadFile(rest)
+		if err != nil {
+			return Secret{}, err
+		}
+		return Secret{value: strings.TrimRight(string(data), "\r\n")}, nil
+	case "env":
+		v, ok := os.LookupEnv(rest)
+		if !ok {
+			return Secret{}, fmt.Errorf("environment variable %s is not set", rest)
+		}
+		return Secret{value: v}, nil
+	case "enc":
+		key, err := masterKey()
+		if err != nil {
+			return Secret{}, err
+		}
+		plain, err := decryptSecret(key, rest)
+		if err != nil {
+			return Secret{}, err
+		}
+		return Secret{value: plain}, nil
+	}
+	return Secret{value: ref}, nil
+}
+
+func masterKey() ([]byte, error) {
+	encoded := os.Getenv(masterKeyEnv)
+	if encoded == "" {
+		return nil, errors.New(masterKeyEnv + " is not set, cannot decrypt enc: values")
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != 32 {
+		return nil, errors.New(masterKeyEnv + " must be 32 bytes encoded in base64")
+	}
+	return key, nil
+}
+
+// encryptSecret 返回 base64(nonce || 密文)，不带 enc: 前缀
+func encryptSecret(key []byte, plain string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(plain), nil)), nil
+}
+
+func decryptSecret(key []byte, encoded string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(data) < gcm.NonceSize() {
+		return "", errors.New("malformed enc: value")
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		// 密钥不对或密文被修改
+		return "", errors.New("cannot decrypt enc: value with " + masterKeyEnv)
+	}
+	return string(plain), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// withSecrets 在 viper 默认的 decode hook 之前解析 Secret 字段
+func withSecrets(c *mapstructure.DecoderConfig) {
+	secretType := reflect.TypeOf(Secret{})
+	resolve := func(from, to reflect.Type, data any) (any, error) {
+		if to != secretType {
+			return data, nil
+		}
+		switch from.Kind() {
+		case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+			return nil, fmt.Errorf("secret must be a string, got %T", data)
+		}
+		// YAML 中没有加引号的数字密码解析出来是数字
+		return resolveSecret(fmt.Sprint(data))
+	}
+	c.DecodeHook = mapstructure.ComposeDecodeHookFunc(resolve, c.DecodeHook)
+}
+
+// placeholderSecrets 示例配置和教程中常见的值，出现在配置中说明忘记了替换
+var placeholderSecrets = []string{
+	"your-secret-key-change-in-production",
+	"changeme",
+	"change-me",
+	"secret",
+	"password",
+	"password123",
+	"mypass",
+	"123456",
+	"admin",
+	"root",
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSecretEncryptRoundTrip(t *testing.T) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	for _, plain := range []string{"", "p@ssw0rd", "密码"} {
+		enc, err := encryptSecret(key, plain)
+		if err != nil {
+			t.Fatalf("encrypt %q: %v", plain, err)
+		}
+		if got, err := decryptSecret(key, enc); err != nil || got != plain {
+			t.Fatalf("expected %q, got %q (%v)", plain, got, err)
+		}
+	}
+
+	enc, _ := encryptSecret(key, "p@ssw0rd")
+	data, _ := base64.StdEncoding.DecodeString(enc)
+	otherKey := bytes.Clone(key)
+	otherKey[0] ^= 1
+	cases := []struct {
+		name string
+		key  []byte
+		enc  string
+	}{
+		{"tampered ciphertext", key, tamper(data, len(data)-1)},
+		{"tampered nonce", key, tamper(data, 0)},
+		{"truncated", key, base64.StdEncoding.EncodeToString(data[:8])},
+		{"not base64", key, "!!!"},
+		{"wrong key", otherKey, enc},
+	}
+	for _, tc := range cases {
+		if got, err := decryptSecret(tc.key, tc.enc); err == nil {
+			t.Errorf("%s: expected an error, got %q", tc.name, got)
+		}
+	}
+}
+
+func tamper(data []byte, i int) string {
+	data = bytes.Clone(data)
+	data[i] ^= 1
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+func TestResolveSecret(t *testing.T) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(masterKeyEnv, base64.StdEncoding.EncodeToString(key))
+	t.Setenv("TEST_DB_PASS", "from-env")
+	path := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(path, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	enc, err := encryptSecret(key, "from-enc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		ref  string
+		want string
+		err  bool
+	}{
+		{"plain", "plain", false},
+		{"file:" + path, "from-file", false},
+		{"file:" + path + ".missing", "", true},
+		{"env:TEST_DB_PASS", "from-env", false},
+		{"env:TEST_MISSING_VAR", "", true},
+		{"enc:" + enc, "from-enc", false},
+		{"enc:" + enc[:len(enc)-4], "", true},
+	}
+	for _, tc := range cases {
+		s, err := resolveSecret(tc.ref)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%s: expected an error", tc.ref)
+			}
+			continue
+		}
+		if err != nil || s.Reveal() != tc.want {
+			t.Errorf("%s: expected %q, got %q (%v)", tc.ref, tc.want, s.Reveal(), err)
+		}
+	}
+
+	t.Setenv(masterKeyEnv, "")
+	if _, err := resolveSecret("enc:" + enc); err == nil {
+		t.Error("expected enc: to fail without a master key")
+	}
+}
+
+func TestSecretRedaction(t *testing.T) {
+	const plain = "hunter2-plaintext"
+	s := Secret{value: plain}
+	cfg := DatabaseConfig{Username: "app", Password: s}
+
+	var logged bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logged, nil))
+	logger.Info("connect", "password", s, "database", cfg)
+	jsonOut, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputs := map[string]string{
+		"%v":   fmt.Sprintf("%v", s),
+		"%+v":  fmt.Sprintf("%+v", cfg),
+		"%#v":  fmt.Sprintf("%#v", cfg),
+		"%s":   fmt.Sprintf("%s", s),
+		"%q":   fmt.Sprintf("%q", s),
+		"%x":   fmt.Sprintf("%x", s),
+		"json": string(jsonOut),
+		"slog": logged.String(),
+	}
+	for name, out := range outputs {
+		if strings.Contains(out, plain) || !strings.Contains(out, redacted) {
+			t.Errorf("%s: expected redacted output, got %s", name, out)
+		}
+	}
+	if got := fmt.Sprint(Secret{}); got != "" {
+		t.Errorf("expected an empty secret to print as empty, got %q", got)
+	}
+}
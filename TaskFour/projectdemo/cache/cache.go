@@ -0,0 +1,15 @@
+// Package cache 提供键值缓存。Store 只存取字节，可以由进程内的 LRU 或 Redis 等外部存储实现；
+// Loader 在 Store 之上做类型化的读穿透缓存
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Store 缓存存储。Get 未命中时返回 false；ttl 为 0 表示不过期
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"hash/maphash"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Stats 命中和未命中次数。Store 出错按未命中计
+type Stats struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+}
+
+// generations 代数计数器的个数，key 按哈希分到其中一个
+const generations = 256
+
+// Loader 类型化的读穿透缓存。值用 gob 编码，json:"-" 的字段同样会被缓存，
+// 不应缓存的字段（如密码哈希）要在 load 中清除；
+// 同一个 key 并发未命中时只调用一次 load，其余调用方共享结果
+type Loader[T any] struct {
+	store  Store
+	prefix string
+	ttl    time.Duration
+
+	group  singleflight.Group
+	hits   atomic.Int64
+	misses atomic.Int64
+
+	// gens 每次 Delete 递增 key 所在的计数器，加载期间计数变化说明读到的可能是删除之前的数据，
+	// 不再写入缓存。不同 key 偶尔共用计数器，只会多一次未命中
+	seed maphash.Seed
+	gens [generations]atomic.Uint64
+}
+
+// NewLoader 创建 Loader，所有 key 加上 prefix 后写入 store，多个 Loader 可以共用一个 Store
+func NewLoader[T any](store Store, prefix string, ttl time.Duration) *Loader[T] {
+	return &Loader[T]{store: store, prefix: prefix, ttl: ttl, seed: maphash.MakeSeed()}
+}
+
+// Get 返回 key 的缓存值，未命中时调用 load 并写入缓存。load 返回错误时不缓存
+func (l *Loader[T]) Get(ctx context.Context, key string, load func(ctx context.Context) (T, error)) (T, error) {
+	key = l.prefix + key
+	if v, ok := l.get(ctx, key); ok {
+		l.hits.Add(1)
+		return v, nil
+	}
+	l.misses.Add(1)
+
+	// 共享的加载不随第一个调用方取消而失败
+	ch := l.group.DoChan(key, func() (interface{}, error) {
+		loadCtx := context.WithoutCancel(ctx)
+		gen := l.generation(key)
+		before := gen.Load()
+		v, err := load(loadCtx)
+		if err != nil {
+			return v, err
+		}
+		if gen.Load() != before {
+			return v, nil
+		}
+		l.set(loadCtx, key, v)
+		// 写入和 Delete 同时发生时，Delete 可能先删除再被这里的写入覆盖，写入后再检查一次
+		if gen.Load() != before {
+			if err := l.store.Delete(loadCtx, key); err != nil {
+				log.Printf("cache: delete %s: %v", key, err)
+			}
+		}
+		return v, nil
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			var zero T
+			return zero, res.Err
+		}
+		return res.Val.(T), nil
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// Delete 删除缓存，写入方应在提交之后调用。进行中的加载可能读到旧数据，结果不会写入缓存
+func (l *Loader[T]) Delete(ctx context.Context, keys ...string) error {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = l.prefix + key
+		// 删除之前开始的加载不再写入缓存，之后的未命中重新加载，不再等待它
+		l.generation(prefixed[i]).Add(1)
+		l.group.Forget(prefixed[i])
+	}
+	return l.store.Delete(ctx, prefixed...)
+}
+
+func (l *Loader[T]) Stats() Stats {
+	return Stats{Hits: l.hits.Load(), Misses: l.misses.Load()}
+}
+
+func (l *Loader[T]) generation(key string) *atomic.Uint64 {
+	return &l.gens[maphash.String(l.seed, key)%generations]
+}
+
+// get 缓存不可用时按未命中处理，不影响调用方
+func (l *Loader[T]) get(ctx context.Context, key string) (T, bool) {
+	var v T
+	data, ok, err := l.store.Get(ctx, key)
+	if err != nil {
+		log.Printf("cache: get %s: %v", key, err)
+		return v, false
+	}
+	if !ok {
+		return v, false
+	}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&v); err != nil {
+		log.Printf("cache: decode %s: %v", key, err)
+		return v, false
+	}
+	return v, true
+}
+
+func (l *Loader[T]) set(ctx context.Context, key string, v T) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		log.Printf("cache: encode %s: %v", key, err)
+		return
+	}
+	if err := l.store.Set(ctx, key, buf.Bytes(), l.ttl); err != nil {
+		log.Printf("cache: set %s: %v", key, err)
+	}
+}
@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type profile struct {
+	Name     string
+	Password string `json:"-"`
+	Seen     *time.Time
+}
+
+func TestLoaderReadThrough(t *testing.T) {
+	store := NewLRU(10)
+	l := NewLoader[profile](store, "p:", time.Minute)
+	ctx := context.Background()
+	seen := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	var loads int
+	load := func(context.Context) (profile, error) {
+		loads++
+		return profile{Name: "alice", Password: "hash", Seen: &seen}, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		p, err := l.Get(ctx, "1", load)
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		if p.Name != "alice" || p.Password != "hash" || p.Seen == nil || !p.Seen.Equal(seen) {
+			t.Fatalf("unexpected value %+v", p)
+		}
+	}
+	if loads != 1 {
+		t.Fatalf("expected one load, got %d", loads)
+	}
+	if _, ok, _ := store.Get(ctx, "p:1"); !ok {
+		t.Fatal("expected the key to be prefixed in the store")
+	}
+
+	if err := l.Delete(ctx, "1"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := l.Get(ctx, "1", load); err != nil || loads != 2 {
+		t.Fatalf("expected reload after delete, got %d loads (%v)", loads, err)
+	}
+	if stats := l.Stats(); stats.Hits != 2 || stats.Misses != 2 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestLoaderDoesNotCacheErrors(t *testing.T) {
+	l := NewLoader[string](NewLRU(10), "", time.Minute)
+	ctx := context.Background()
+	notFound := errors.New("not found")
+	for i := 0; i < 2; i++ {
+		if _, err := l.Get(ctx, "k", func(context.Context) (string, error) { return "", notFound }); !errors.Is(err, notFound) {
+			t.Fatalf("expected load error, got %v", err)
+		}
+	}
+	if stats := l.Stats(); stats.Misses != 2 {
+		t.Fatalf("expected errors to be retried, got %+v", stats)
+	}
+}
+
+func TestLoaderCollapsesConcurrentMisses(t *testing.T) {
+	l := NewLoader[int](NewLRU(10), "", time.Minute)
+	var loads atomic.Int32
+	release := make(chan struct{})
+	load := func(context.Context) (int, error) {
+		loads.Add(1)
+		<-release
+		return 42, nil
+	}
+
+	const callers = 20
+	var wg sync.WaitGroup
+	results := make(chan int, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := l.Get(context.Background(), "answer", load)
+			if err != nil {
+				t.Errorf("get: %v", err)
+			}
+			results <- v
+		}()
+	}
+	// 等所有调用方都未命中后再放行加载
+	for l.Stats().Misses < callers {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	close(results)
+
+	if n := loads.Load(); n != 1 {
+		t.Fatalf("expected one load for concurrent misses, got %d", n)
+	}
+	for v := range results {
+		if v != 42 {
+			t.Fatalf("unexpected value %d", v)
+		}
+	}
+}
+
+func TestLoaderCallerCancellation(t *testing.T) {
+	l := NewLoader[string](NewLRU(10), "", time.Minute)
+	release := make(chan struct{})
+	load := func(ctx context.Context) (string, error) {
+		<-release
+		return "value", ctx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := l.Get(ctx, "k", load); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled caller to return, got %v", err)
+	}
+
+	// 取消的调用方不影响进行中的加载：之后的调用要么加入这次加载，要么命中它写入的缓存
+	close(release)
+	v, err := l.Get(context.Background(), "k", func(context.Context) (string, error) { return "", errors.New("unexpected load") })
+	if err != nil || v != "value" {
+		t.Fatalf("expected the shared load result, got %q (%v)", v, err)
+	}
+}
+
+func TestLoaderDropsLoadsStartedBeforeDelete(t *testing.T) {
+	l := NewLoader[string](NewLRU(10), "", time.Minute)
+	ctx := context.Background()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan string)
+	go func() {
+		v, _ := l.Get(ctx, "k", func(context.Context) (string, error) {
+			close(started)
+			<-release
+			return "old", nil
+		})
+		done <- v
+	}()
+
+	// 加载读到旧数据之后，写入方提交并删除缓存
+	<-started
+	if err := l.Delete(ctx, "k"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	close(release)
+	if v := <-done; v != "old" {
+		t.Fatalf("expected the caller to get its own load result, got %q", v)
+	}
+
+	v, err := l.Get(ctx, "k", func(context.Context) (string, error) { return "new", nil })
+	if err != nil || v != "new" {
+		t.Fatalf("expected the stale load to be dropped, got %q (%v)", v, err)
+	}
+	if v, _ := l.Get(ctx, "k", func(context.Context) (string, error) { return "", errors.New("unexpected load") }); v != "new" {
+		t.Fatalf("expected later loads to be cached, got %q", v)
+	}
+}
@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRU(2)
+	ctx := context.Background()
+	_ = c.Set(ctx, "a", []byte("1"), 0)
+	_ = c.Set(ctx, "b", []byte("2"), 0)
+	// 读取 a 之后 b 成为最久未使用的条目
+	if _, ok, _ := c.Get(ctx, "a"); !ok {
+		t.Fatal("expected a to be cached")
+	}
+	_ = c.Set(ctx, "c", []byte("3"), 0)
+
+	if _, ok, _ := c.Get(ctx, "b"); ok {
+		t.Fatal("expected b to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok, _ := c.Get(ctx, key); !ok {
+			t.Fatalf("expected %s to be cached", key)
+		}
+	}
+	if c.Len() != 2 {
+		t.Fatalf("expected 2 entries, got %d", c.Len())
+	}
+}
+
+func TestLRUExpiresAndDeletes(t *testing.T) {
+	c := NewLRU(10)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+	_ = c.Set(ctx, "short", []byte("1"), time.Minute)
+	_ = c.Set(ctx, "forever", []byte("2"), 0)
+	_ = c.Set(ctx, "deleted", []byte("3"), 0)
+	_ = c.Delete(ctx, "deleted", "missing")
+
+	now = now.Add(time.Minute)
+	if _, ok, _ := c.Get(ctx, "short"); ok {
+		t.Fatal("expected short to expire")
+	}
+	if v, ok, _ := c.Get(ctx, "forever"); !ok || string(v) != "2" {
+		t.Fatalf("expected forever to be cached, got %q %v", v, ok)
+	}
+	if _, ok, _ := c.Get(ctx, "deleted"); ok {
+		t.Fatal("expected deleted to be gone")
+	}
+	if c.Len() != 1 {
+		t.Fatalf("expected expired entries to be removed on read, got %d", c.Len())
+	}
+
+	// 覆盖写入会刷新过期时间
+	_ = c.Set(ctx, "forever", []byte("4"), time.Second)
+	now = now.Add(time.Second)
+	if _, ok, _ := c.Get(ctx, "forever"); ok {
+		t.Fatal("expected overwritten entry to use the new ttl")
+	}
+}
@@ -0,0 +1,203 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"projectdemo/config"
+	"projectdemo/models"
+	"projectdemo/utils"
+	"strings"
+	"testing"
+)
+
+type result struct {
+	code   int
+	stdout string
+	stderr string
+}
+
+// runCLI 在临时数据库上执行一条命令，stdin 为空字符串时不提供输入
+func runCLI(t *testing.T, db, stdin string, args ...string) result {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := Run(context.Background(), append([]string{"-db", db}, args...), strings.NewReader(stdin), &stdout, &stderr)
+	return result{code: code, stdout: stdout.String(), stderr: stderr.String()}
+}
+
+func mustRun(t *testing.T, db, stdin string, args ...string) string {
+	t.Helper()
+	res := runCLI(t, db, stdin, args...)
+	if res.code != 0 {
+		t.Fatalf("%v: exit %d, stderr: %s", args, res.code, res.stderr)
+	}
+	return res.stdout
+}
+
+func newTestDB(t *testing.T) string {
+	t.Helper()
+	db := filepath.Join(t.TempDir(), "cli.db")
+	mustRun(t, db, "", "migrate")
+	mustRun(t, db, "", "user", "create", "-username", "alice", "-email", "alice@example.com", "-password", "secret123")
+	return db
+}
+
+func TestUserCreate(t *testing.T) {
+	tests := []struct {
+		name     string
+		stdin    string
+		args     []string
+		wantCode int
+		wantErr  string
+	}{
+		{
+			name: "password flag",
+			args: []string{"-username", "bob", "-email", "bob@example.com", "-password", "secret123"},
+		},
+		{
+			name:  "password from stdin",
+			stdin: "secret123\n",
+			args:  []string{"-username", "bob", "-email", "bob@example.com", "-role", "admin"},
+		},
+		{
+			name:     "duplicate username",
+			args:     []string{"-username", "alice", "-email", "other@example.com", "-password", "secret123"},
+			wantCode: 1,
+			wantErr:  "username",
+		},
+		{
+			name:     "invalid email",
+			args:     []string{"-username", "bob", "-email", "not-an-email", "-password", "secret123"},
+			wantCode: 1,
+			wantErr:  "validation failed",
+		},
+		{
+			name:     "missing password",
+			args:     []string{"-username", "bob", "-email", "bob@example.com"},
+			wantCode: 2,
+			wantErr:  "password required",
+		},
+		{
+			name:     "unknown role",
+			args:     []string{"-username", "bob", "-email", "bob@example.com", "-password", "secret123", "-role", "root"},
+			wantCode: 1,
+			wantErr:  "Unknown role",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			res := runCLI(t, db, tt.stdin, append([]string{"user", "create"}, tt.args...)...)
+			if res.code != tt.wantCode {
+				t.Fatalf("expected exit %d, got %d (stderr: %s)", tt.wantCode, res.code, res.stderr)
+			}
+			if tt.wantErr != "" && !strings.Contains(res.stderr, tt.wantErr) {
+				t.Fatalf("expected stderr to contain %q, got %q", tt.wantErr, res.stderr)
+			}
+		})
+	}
+}
+
+func TestUserAdministration(t *testing.T) {
+	db := newTestDB(t)
+	mustRun(t, db, "", "user", "create", "-username", "bob", "-email", "bob@example.com", "-password", "secret123")
+
+	mustRun(t, db, "", "user", "disable", "bob")
+	mustRun(t, db, "newsecret\n", "user", "reset-password", "1")
+
+	var listed struct {
+		Items []models.User `json:"items"`
+		Total int64         `json:"total"`
+	}
+	if err := json.Unmarshal([]byte(mustRun(t, db, "", "user", "list", "-json")), &listed); err != nil {
+		t.Fatalf("decode user list: %v", err)
+	}
+	if listed.Total != 2 || len(listed.Items) != 2 {
+		t.Fatalf("expected 2 users, got %d", listed.Total)
+	}
+	if listed.Items[0].IsDisabled() || !listed.Items[1].IsDisabled() {
+		t.Fatalf("expected only bob to be disabled")
+	}
+
+	table := mustRun(t, db, "", "user", "list")
+	if !strings.Contains(table, "disabled") || !strings.Contains(table, "2 of 2 users") {
+		t.Fatalf("unexpected table output:\n%s", table)
+	}
+
+	a := &app{ctx: context.Background(), cfg: config.Load(), stdout: &bytes.Buffer{}, stderr: &bytes.Buffer{}}
+	a.cfg.Database.Path = db
+	defer a.close()
+	svc, err := a.userService()
+	if err != nil {
+		t.Fatalf("open service: %v", err)
+	}
+	if _, err := svc.Authenticate(context.Background(), "alice", "newsecret"); err != nil {
+		t.Fatalf("login with reset password: %v", err)
+	}
+	_, err = svc.Authenticate(context.Background(), "bob", "secret123")
+	var appErr *utils.AppError
+	if !errors.As(err, &appErr) || appErr.Code != 403 {
+		t.Fatalf("expected disabled user to get 403, got %v", err)
+	}
+
+	res := runCLI(t, db, "", "user", "disable", "nobody")
+	if res.code != 1 || !strings.Contains(res.stderr, "User not found") {
+		t.Fatalf("expected not found, got exit %d: %s", res.code, res.stderr)
+	}
+}
+
+func TestTokenIssue(t *testing.T) {
+	db := newTestDB(t)
+	token := strings.TrimSpace(mustRun(t, db, "", "token", "issue", "alice"))
+
+	claims, err := utils.ParseToken(token, []byte(config.Load().JWT.Secret))
+	if err != nil {
+		t.Fatalf("parse issued token: %v", err)
+	}
+	if claims.UserID != 1 || claims.Username != "alice" || claims.Role != models.RoleUser {
+		t.Fatalf("unexpected claims: %+v", claims)
+	}
+}
+
+func TestConfigPrintRedactsSecrets(t *testing.T) {
+	out := mustRun(t, filepath.Join(t.TempDir(), "unused.db"), "", "config", "print")
+	cfg := config.Load()
+	if strings.Contains(out, cfg.JWT.Secret) || strings.Contains(out, "= "+cfg.Database.Password+"\n") {
+		t.Fatalf("config print leaked a secret:\n%s", out)
+	}
+	for _, want := range []string{"jwt.secret", "******", "server.port", "storage.local_dir"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in output:\n%s", want, out)
+		}
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	db := filepath.Join(t.TempDir(), "unused.db")
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+	}{
+		{"unknown command", []string{"frobnicate"}, 2},
+		{"unknown subcommand", []string{"user", "frobnicate"}, 2},
+		{"unknown flag", []string{"user", "list", "-verbose"}, 2},
+		{"missing argument", []string{"user", "disable"}, 2},
+		{"invalid job payload", []string{"job", "enqueue", "-payload", "{", "cleanup"}, 2},
+		{"help", []string{"user", "list", "-h"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := runCLI(t, db, "", tt.args...)
+			if res.code != tt.wantCode {
+				t.Fatalf("expected exit %d, got %d (stderr: %s)", tt.wantCode, res.code, res.stderr)
+			}
+			if !strings.Contains(res.stderr, "usage:") {
+				t.Fatalf("expected usage on stderr, got %q", res.stderr)
+			}
+		})
+	}
+}
@@ -0,0 +1,381 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"projectdemo/events"
+	"projectdemo/jobs"
+	"projectdemo/models"
+	"projectdemo/scheduler"
+	"projectdemo/server"
+	"projectdemo/services"
+	"projectdemo/utils"
+	"reflect"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+func runServe(a *app, args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := fs.String("addr", a.cfg.Server.Host+":"+a.cfg.Server.Port, "listen `address`")
+	if err := a.parse(fs, args); err != nil {
+		return err
+	}
+
+	db, err := a.openDB()
+	if err != nil {
+		return err
+	}
+	if err := server.Migrate(db); err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	// 进程内事件总线，退出时先停止 HTTP 服务，再等待异步订阅者处理完剩余事件
+	bus := events.NewBus()
+	r, err := server.NewServer(a.cfg, db, server.WithEventBus(bus))
+	if err != nil {
+		return err
+	}
+
+	// webhook 投递、后台任务和定时任务随服务一起运行，退出时等待进行中的投递和任务完成
+	queue := jobs.NewQueue(db, a.cfg.Jobs)
+	services.RegisterJobs(queue, services.NewOrgService(db))
+	sched := scheduler.New(db, a.cfg.Scheduler)
+	if err := services.RegisterMaintenance(sched, db); err != nil {
+		return err
+	}
+	stopBackground := background(a.ctx,
+		services.NewWebhookDispatcher(db, a.cfg.Webhook).Run,
+		queue.Run,
+		sched.Run,
+	)
+	defer stopBackground()
+
+	srv := &http.Server{Addr: *addr, Handler: r}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+	fmt.Fprintf(a.stderr, "Server starting on %s\n", *addr)
+
+	// 收到退出信号后等待进行中的请求完成
+	select {
+	case err := <-errCh:
+		return err
+	case <-a.ctx.Done():
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	fmt.Fprintln(a.stderr, "Shutting down")
+	return errors.Join(srv.Shutdown(ctx), bus.Shutdown(ctx))
+}
+
+// background 在各自的 goroutine 中运行 fns，返回的函数取消它们并等待全部返回
+func background(ctx context.Context, fns ...func(context.Context)) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	for _, fn := range fns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn(ctx)
+		}()
+	}
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+func runMigrate(a *app, args []string) error {
+	if err := a.parse(flag.NewFlagSet("migrate", flag.ContinueOnError), args); err != nil {
+		return err
+	}
+	db, err := a.openDB()
+	if err != nil {
+		return err
+	}
+	if err := server.Migrate(db); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "Migrated %s\n", a.cfg.Database.Path)
+	return nil
+}
+
+func runUserCreate(a *app, args []string) error {
+	fs := flag.NewFlagSet("user create", flag.ContinueOnError)
+	var req models.CreateUserRequest
+	fs.StringVar(&req.Username, "username", "", "user name")
+	fs.StringVar(&req.Email, "email", "", "email address")
+	fs.StringVar(&req.Password, "password", "", "password, read from stdin when empty")
+	role := fs.String("role", models.RoleUser, "role: user or admin")
+	if err := a.parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return usagef("unexpected argument %q", fs.Arg(0))
+	}
+
+	if req.Password == "" {
+		var err error
+		if req.Password, err = a.readPassword(); err != nil {
+			return err
+		}
+	}
+	// 与 HTTP 接口使用同一个校验器和同一组 binding 规则
+	if err := binding.Validator.ValidateStruct(&req); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
+	svc, err := a.userService()
+	if err != nil {
+		return err
+	}
+	ctx := a.auditContext()
+	user, err := svc.CreateUser(ctx, req)
+	if err != nil {
+		return err
+	}
+	if *role != models.RoleUser {
+		if err := svc.SetRole(ctx, user.ID, *role); err != nil {
+			return fmt.Errorf("user %d created but setting role failed: %s", user.ID, errorMessage(err))
+		}
+	}
+
+	fmt.Fprintf(a.stdout, "Created user %d (%s, role %s)\n", user.ID, user.Username, *role)
+	return nil
+}
+
+func runUserList(a *app, args []string) error {
+	fs := flag.NewFlagSet("user list", flag.ContinueOnError)
+	offset := fs.Int("offset", 0, "number of users to skip")
+	limit := fs.Int("limit", 50, "maximum number of users to print")
+	asJSON := fs.Bool("json", false, "print JSON instead of a table")
+	if err := a.parse(fs, args); err != nil {
+		return err
+	}
+	if *offset < 0 || *limit < 1 {
+		return usagef("offset must be >= 0 and limit >= 1")
+	}
+
+	svc, err := a.userService()
+	if err != nil {
+		return err
+	}
+	users, total, err := svc.ListUsers(a.ctx, *offset, *limit)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(a.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(map[string]interface{}{"items": users, "total": total})
+	}
+
+	tw := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tUSERNAME\tEMAIL\tROLE\tSTATUS\tCREATED")
+	for _, u := range users {
+		status := "active"
+		if u.IsDisabled() {
+			status = "disabled"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", u.ID, u.Username, u.Email, u.Role, status, u.CreatedAt.UTC().Format(time.RFC3339))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "%d of %d users\n", len(users), total)
+	return nil
+}
+
+func runUserDisable(a *app, args []string) error {
+	ref, err := a.singleArg(flag.NewFlagSet("user disable", flag.ContinueOnError), args, "user")
+	if err != nil {
+		return err
+	}
+	svc, err := a.userService()
+	if err != nil {
+		return err
+	}
+	id, err := a.findUser(svc, ref)
+	if err != nil {
+		return err
+	}
+	if err := svc.DisableUser(a.auditContext(), id); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "Disabled user %d; tokens already issued are rejected once the API's user cache expires\n", id)
+	return nil
+}
+
+// runUserVerify 标记邮箱已验证，会触发 user.verified webhook
+func runUserVerify(a *app, args []string) error {
+	ref, err := a.singleArg(flag.NewFlagSet("user verify", flag.ContinueOnError), args, "user")
+	if err != nil {
+		return err
+	}
+	svc, err := a.userService()
+	if err != nil {
+		return err
+	}
+	id, err := a.findUser(svc, ref)
+	if err != nil {
+		return err
+	}
+	if err := svc.VerifyEmail(a.auditContext(), id); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "Verified email of user %d\n", id)
+	return nil
+}
+
+func runUserResetPassword(a *app, args []string) error {
+	fs := flag.NewFlagSet("user reset-password", flag.ContinueOnError)
+	password := fs.String("password", "", "new password, read from stdin when empty")
+	ref, err := a.singleArg(fs, args, "user")
+	if err != nil {
+		return err
+	}
+
+	req := models.ResetPasswordRequest{Password: *password}
+	if req.Password == "" {
+		if req.Password, err = a.readPassword(); err != nil {
+			return err
+		}
+	}
+	if err := binding.Validator.ValidateStruct(&req); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
+	svc, err := a.userService()
+	if err != nil {
+		return err
+	}
+	id, err := a.findUser(svc, ref)
+	if err != nil {
+		return err
+	}
+	if err := svc.ResetPassword(a.auditContext(), id, req.Password); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "Password reset for user %d\n", id)
+	return nil
+}
+
+func runTokenIssue(a *app, args []string) error {
+	fs := flag.NewFlagSet("token issue", flag.ContinueOnError)
+	orgID := fs.Uint("org", 0, "active organization `id`, 0 for the personal workspace")
+	ref, err := a.singleArg(fs, args, "user")
+	if err != nil {
+		return err
+	}
+	svc, err := a.userService()
+	if err != nil {
+		return err
+	}
+	id, err := a.findUser(svc, ref)
+	if err != nil {
+		return err
+	}
+	user, err := svc.GetUserByID(a.ctx, id)
+	if err != nil {
+		return err
+	}
+	if user.IsDisabled() {
+		fmt.Fprintf(a.stderr, "warning: user %d is disabled, the API would refuse to issue this token\n", user.ID)
+	}
+
+	if *orgID != 0 {
+		if _, err := services.NewOrgService(a.db).MemberRole(a.ctx, uint(*orgID), user.ID); err != nil {
+			return err
+		}
+	}
+
+	token, err := utils.GenerateToken([]byte(a.cfg.JWT.Secret), user.ID, user.Username, user.Role, uint(*orgID))
+	if err != nil {
+		return err
+	}
+	// 只把 token 写到 stdout，便于 TOKEN=$(projectdemo token issue alice)
+	fmt.Fprintln(a.stdout, token)
+	return nil
+}
+
+func runJobEnqueue(a *app, args []string) error {
+	fs := flag.NewFlagSet("job enqueue", flag.ContinueOnError)
+	payload := fs.String("payload", "{}", "JSON `payload` passed to the handler")
+	delay := fs.Duration("delay", 0, "run after this `duration`")
+	maxAttempts := fs.Int("max-attempts", jobs.DefaultMaxAttempts, "maximum number of attempts")
+	jobType, err := a.singleArg(fs, args, "job type")
+	if err != nil {
+		return err
+	}
+	if !json.Valid([]byte(*payload)) {
+		return usagef("payload must be valid JSON")
+	}
+	if *delay < 0 || *maxAttempts < 1 {
+		return usagef("delay must be >= 0 and max-attempts >= 1")
+	}
+
+	db, err := a.openDB()
+	if err != nil {
+		return err
+	}
+	job, err := jobs.Enqueue(a.ctx, db, jobType, json.RawMessage(*payload), jobs.Delay(*delay), jobs.MaxAttempts(*maxAttempts))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "Enqueued job %d (%s), runs at %s\n", job.ID, job.Type, job.RunAt.UTC().Format(time.RFC3339))
+	return nil
+}
+
+func runConfigPrint(a *app, args []string) error {
+	if err := a.parse(flag.NewFlagSet("config print", flag.ContinueOnError), args); err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(a.stdout, 0, 4, 1, ' ', 0)
+	for _, kv := range flatten(reflect.ValueOf(*a.cfg.Redacted()), "") {
+		fmt.Fprintf(tw, "%s\t= %s\n", kv[0], kv[1])
+	}
+	return tw.Flush()
+}
+
+// flatten 按 mapstructure 标签把配置展开为 server.port = 8080 形式，与配置文件中的键一致
+func flatten(v reflect.Value, prefix string) [][2]string {
+	var out [][2]string
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key := t.Field(i).Tag.Get("mapstructure")
+		if key == "" {
+			key = strings.ToLower(t.Field(i).Name)
+		}
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			out = append(out, flatten(field, key)...)
+			continue
+		}
+		out = append(out, [2]string{key, fmt.Sprint(field.Interface())})
+	}
+	return out
+}
+
+// readPassword 从 stdin 读取一行作为密码，便于 echo "$PASSWORD" | projectdemo user create ...
+func (a *app) readPassword() (string, error) {
+	line, _ := bufio.NewReader(a.stdin).ReadString('\n')
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return "", usagef("password required: pass -password or write it to stdin")
+	}
+	return line, nil
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"projectdemo/models"
+	"strconv"
+	"time"
+)
+
+// 以下接口要求当前用户具有 admin 角色，否则返回 ErrForbidden
+
+func (c *Client) DeleteUser(ctx context.Context, id uint) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/api/v1/admin/users/" + strconv.FormatUint(uint64(id), 10), auth: true}, nil)
+}
+
+// ListAuditEvents 查询审计事件，q.Format 会被忽略，始终以 JSON 返回
+func (c *Client) ListAuditEvents(ctx context.Context, q models.AuditQuery) ([]models.AuditEvent, error) {
+	query := url.Values{}
+	if !q.From.IsZero() {
+		query.Set("from", q.From.Format(time.RFC3339))
+	}
+	if !q.To.IsZero() {
+		query.Set("to", q.To.Format(time.RFC3339))
+	}
+	if q.Action != "" {
+		query.Set("action", q.Action)
+	}
+	if q.ActorID != 0 {
+		query.Set("actor_id", strconv.FormatUint(uint64(q.ActorID), 10))
+	}
+	if q.TargetID != "" {
+		query.Set("target_id", q.TargetID)
+	}
+	if q.Limit != 0 {
+		query.Set("limit", strconv.Itoa(q.Limit))
+	}
+
+	var events []models.AuditEvent
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/admin/audit-events", query: query, auth: true}, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (c *Client) ShipOrder(ctx context.Context, id uint) (*models.Order, error) {
+	var order models.Order
+	path := "/api/v1/admin/orders/" + strconv.FormatUint(uint64(id), 10) + "/ship"
+	if err := c.do(ctx, request{method: http.MethodPost, path: path, auth: true}, &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
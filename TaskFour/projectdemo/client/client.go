@@ -0,0 +1,384 @@
+// Package client 是 projectdemo HTTP API 的 Go 客户端。
+//
+// 客户端负责保存和自动刷新访问令牌、按策略重试临时性错误，
+// 并把服务端的 utils.Response 错误响应解码为 *APIError：
+//
+//	c := client.New("http://localhost:8080", client.WithCredentials("alice", "secret123"))
+//	me, err := c.Me(ctx)
+//	if errors.Is(err, client.ErrNotFound) { ... }
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxResponseSize 单个响应体的读取上限
+const maxResponseSize = 10 << 20
+
+// RetryPolicy 重试策略。429 和 503 表示请求未被处理，任何方法都会重试；
+// 其他 5xx 和网络错误只对幂等方法（GET、HEAD、PUT、DELETE）重试，避免重复创建
+type RetryPolicy struct {
+	// MaxAttempts 包含第一次请求在内的最大尝试次数，1 表示不重试
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   200 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+}
+
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	tokens     TokenStore
+	retry      RetryPolicy
+	// refreshBefore token 剩余有效期小于该值时在请求前自动刷新
+	refreshBefore time.Duration
+	userAgent     string
+
+	// mu 串行化刷新和重新登录，避免并发请求同时刷新
+	mu       sync.Mutex
+	username string
+	password string
+}
+
+type Option func(*Client)
+
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+func WithTokenStore(store TokenStore) Option {
+	return func(c *Client) {
+		c.tokens = store
+	}
+}
+
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		if policy.MaxAttempts < 1 {
+			policy.MaxAttempts = 1
+		}
+		c.retry = policy
+	}
+}
+
+func WithRefreshBefore(d time.Duration) Option {
+	return func(c *Client) {
+		c.refreshBefore = d
+	}
+}
+
+// WithCredentials 配置后，没有可用 token、token 已过期或服务端返回 401 时自动重新登录
+func WithCredentials(username, password string) Option {
+	return func(c *Client) {
+		c.username = username
+		c.password = password
+	}
+}
+
+func WithUserAgent(ua string) Option {
+	return func(c *Client) {
+		c.userAgent = ua
+	}
+}
+
+// New 创建客户端，baseURL 形如 http://localhost:8080，不包含 /api/v1
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:       strings.TrimRight(baseURL, "/"),
+		httpClient:    &http.Client{Timeout: 30 * time.Second},
+		tokens:        NewMemoryTokenStore(),
+		retry:         DefaultRetryPolicy,
+		refreshBefore: 5 * time.Minute,
+		userAgent:     "projectdemo-go-client/1.0",
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Token 返回当前保存的访问令牌
+func (c *Client) Token() (Token, error) {
+	return c.tokens.Load()
+}
+
+// SetToken 使用已有的访问令牌，例如从其他进程传入
+func (c *Client) SetToken(accessToken string) error {
+	return c.tokens.Save(newToken(accessToken))
+}
+
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	body   interface{}
+	// auth 为 true 时携带 token，必要时先刷新或重新登录
+	auth bool
+}
+
+type envelope struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+	Error   json.RawMessage `json:"error"`
+	Field   string          `json:"field"`
+}
+
+// do 发送请求并把 data 解码到 out，out 为 nil 时忽略 data
+func (c *Client) do(ctx context.Context, req request, out interface{}) error {
+	var payload []byte
+	if req.body != nil {
+		var err error
+		if payload, err = json.Marshal(req.body); err != nil {
+			return fmt.Errorf("projectdemo: encode request: %w", err)
+		}
+	}
+
+	if !req.auth {
+		return c.send(ctx, req, payload, "", out)
+	}
+
+	token, err := c.ensureToken(ctx)
+	if err != nil {
+		return err
+	}
+	err = c.send(ctx, req, payload, token.AccessToken, out)
+	if !errors.Is(err, ErrUnauthorized) || !c.hasCredentials() {
+		return err
+	}
+
+	// token 被服务端拒绝（例如签名密钥轮换），重新登录后只重试一次
+	if token, err = c.relogin(ctx, token); err != nil {
+		return err
+	}
+	return c.send(ctx, req, payload, token.AccessToken, out)
+}
+
+// send 发送请求，按重试策略重试临时性错误
+func (c *Client) send(ctx context.Context, req request, payload []byte, token string, out interface{}) error {
+	for attempt := 1; ; attempt++ {
+		err := c.roundTrip(ctx, req, payload, token, out)
+		if err == nil || attempt >= c.retry.MaxAttempts || !retryable(ctx, req.method, err) {
+			return err
+		}
+
+		timer := time.NewTimer(c.backoff(attempt, err))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) roundTrip(ctx context.Context, req request, payload []byte, token string, out interface{}) error {
+	u := c.baseURL + req.path
+	if len(req.query) > 0 {
+		u += "?" + req.query.Encode()
+	}
+
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, u, body)
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.Header.Set("User-Agent", c.userAgent)
+	if payload != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return decodeResponse(resp, out)
+}
+
+func decodeResponse(resp *http.Response, out interface{}) error {
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return fmt.Errorf("projectdemo: read response: %w", err)
+	}
+
+	var env envelope
+	decodeErr := json.Unmarshal(raw, &env)
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		apiErr := &APIError{
+			StatusCode: resp.StatusCode,
+			Code:       resp.StatusCode,
+			Message:    http.StatusText(resp.StatusCode),
+			RequestID:  resp.Header.Get("X-Request-ID"),
+			retryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+		if decodeErr != nil {
+			// 非 JSON 的错误响应（例如反向代理返回的页面）
+			apiErr.Detail = strings.TrimSpace(string(raw))
+			return apiErr
+		}
+		if env.Code != 0 {
+			apiErr.Code = env.Code
+		}
+		if env.Message != "" {
+			apiErr.Message = env.Message
+		}
+		apiErr.Field = env.Field
+		if err := json.Unmarshal(env.Error, &apiErr.Detail); err != nil {
+			_ = json.Unmarshal(env.Error, &apiErr.Fields)
+		}
+		return apiErr
+	}
+
+	if decodeErr != nil {
+		return fmt.Errorf("projectdemo: decode response: %w", decodeErr)
+	}
+	if out == nil || len(env.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(env.Data, out); err != nil {
+		return fmt.Errorf("projectdemo: decode response data: %w", err)
+	}
+	return nil
+}
+
+func retryable(ctx context.Context, method string, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.StatusCode == http.StatusTooManyRequests, apiErr.StatusCode == http.StatusServiceUnavailable:
+			return true
+		case apiErr.StatusCode >= http.StatusInternalServerError:
+			return idempotent(method)
+		}
+		return false
+	}
+
+	// 只重试网络错误，响应解码失败等错误重试也不会成功
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) && idempotent(method)
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// backoff 指数退避加随机抖动，服务端给出 Retry-After 时以其为准，均不超过 MaxDelay
+func (c *Client) backoff(attempt int, err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.retryAfter > 0 {
+		return min(apiErr.retryAfter, c.retry.MaxDelay)
+	}
+
+	delay := c.retry.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > c.retry.MaxDelay {
+		delay = c.retry.MaxDelay
+	}
+	if half := int64(delay / 2); half > 0 {
+		delay = time.Duration(half + rand.Int64N(half))
+	}
+	return delay
+}
+
+// parseRetryAfter 支持秒数和 HTTP 日期两种格式
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(v); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
+
+func (c *Client) hasCredentials() bool {
+	return c.username != ""
+}
+
+// ensureToken 返回可用的 token：即将过期时先刷新，已过期或不存在时用配置的凭据重新登录
+func (c *Client) ensureToken(ctx context.Context) (Token, error) {
+	token, err := c.tokens.Load()
+	if err != nil || !token.expiresWithin(c.refreshBefore) {
+		return token, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// 加锁后重新读取，其他请求可能已经完成刷新
+	if token, err = c.tokens.Load(); err != nil || !token.expiresWithin(c.refreshBefore) {
+		return token, err
+	}
+
+	if !token.expired() {
+		refreshed, err := c.refreshLocked(ctx, token)
+		if err == nil {
+			return refreshed, nil
+		}
+		if !c.hasCredentials() {
+			// 刷新失败时旧 token 仍在有效期内，继续使用
+			return token, nil
+		}
+	}
+	if c.hasCredentials() {
+		return c.loginLocked(ctx, c.username, c.password)
+	}
+	if token.AccessToken == "" {
+		return Token{}, ErrNotLoggedIn
+	}
+	// token 已过期且无法重新登录，交由服务端返回 401
+	return token, nil
+}
+
+// relogin 在 rejected 被服务端拒绝后重新登录；如果其他请求已经换了新 token，直接使用新 token
+func (c *Client) relogin(ctx context.Context, rejected Token) (Token, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	current, err := c.tokens.Load()
+	if err != nil {
+		return Token{}, err
+	}
+	if current.AccessToken != rejected.AccessToken && !current.expired() {
+		return current, nil
+	}
+	return c.loginLocked(ctx, c.username, c.password)
+}
@@ -0,0 +1,404 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"projectdemo/config"
+	"projectdemo/models"
+	"projectdemo/server"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testServer 启动完整的 projectdemo 服务，refreshes 统计刷新接口被调用的次数
+type testServer struct {
+	url       string
+	db        *gorm.DB
+	refreshes atomic.Int32
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("get generic db: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = sqlDB.Close()
+	})
+	if err := server.Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	cfg := config.Load()
+	cfg.Server.Mode = gin.TestMode
+	cfg.Storage.LocalDir = filepath.Join(t.TempDir(), "blobs")
+	// 测试直接修改数据库中的用户，关闭用户缓存使修改立即生效
+	cfg.Cache.UserTTL = 0
+	engine, err := server.NewServer(cfg, db)
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+
+	ts := &testServer{db: db}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/users/refresh" {
+			ts.refreshes.Add(1)
+		}
+		engine.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	ts.url = srv.URL
+	return ts
+}
+
+func (ts *testServer) register(t *testing.T, username string) {
+	t.Helper()
+	_, err := New(ts.url).Register(context.Background(), models.CreateUserRequest{
+		Username: username,
+		Email:    username + "@example.com",
+		Password: "secret123",
+	})
+	if err != nil {
+		t.Fatalf("register %s: %v", username, err)
+	}
+}
+
+func TestUserEndpoints(t *testing.T) {
+	ts := newTestServer(t)
+	ctx := context.Background()
+	c := New(ts.url)
+
+	user, err := c.Register(ctx, models.CreateUserRequest{Username: "alice", Email: "alice@example.com", Password: "secret123"})
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if user.ID == 0 || user.Username != "alice" {
+		t.Fatalf("unexpected user: %+v", user)
+	}
+
+	// 未登录时调用需要认证的接口
+	if _, err := c.Me(ctx); !errors.Is(err, ErrNotLoggedIn) {
+		t.Fatalf("expected ErrNotLoggedIn, got %v", err)
+	}
+
+	login, err := c.Login(ctx, "alice", "secret123")
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if token, _ := c.Token(); token.AccessToken != login.Token || token.ExpiresAt.IsZero() {
+		t.Fatalf("token not stored: %+v", token)
+	}
+
+	displayName := "Alice"
+	updated, err := c.UpdateMe(ctx, models.UpdateUserRequest{DisplayName: &displayName})
+	if err != nil {
+		t.Fatalf("update me: %v", err)
+	}
+	if updated.DisplayName != "Alice" {
+		t.Fatalf("display name not updated: %+v", updated)
+	}
+
+	me, err := c.Me(ctx)
+	if err != nil {
+		t.Fatalf("me: %v", err)
+	}
+	if me.ID != user.ID || me.DisplayName != "Alice" {
+		t.Fatalf("unexpected profile: %+v", me)
+	}
+	if n := ts.refreshes.Load(); n != 0 {
+		t.Fatalf("fresh token should not be refreshed, got %d refreshes", n)
+	}
+}
+
+func TestTypedErrors(t *testing.T) {
+	ts := newTestServer(t)
+	ts.register(t, "alice")
+	ctx := context.Background()
+	c := New(ts.url)
+
+	_, err := c.Register(ctx, models.CreateUserRequest{Username: "alice", Email: "other@example.com", Password: "secret123"})
+	var apiErr *APIError
+	if !errors.Is(err, ErrConflict) || !errors.As(err, &apiErr) || apiErr.Field != "username" {
+		t.Fatalf("expected username conflict, got %v", err)
+	}
+
+	_, err = c.Register(ctx, models.CreateUserRequest{Username: "b", Email: "not-an-email", Password: "secret123"})
+	if !errors.Is(err, ErrValidation) || !errors.As(err, &apiErr) || len(apiErr.Fields) == 0 {
+		t.Fatalf("expected validation error details, got %v (%+v)", err, apiErr)
+	}
+
+	_, err = c.Login(ctx, "alice", "wrong-password")
+	if !errors.Is(err, ErrUnauthorized) || !errors.As(err, &apiErr) || apiErr.RequestID == "" {
+		t.Fatalf("expected 401 with request id, got %v", err)
+	}
+}
+
+func TestAdminEndpoints(t *testing.T) {
+	ts := newTestServer(t)
+	ts.register(t, "root")
+	ts.register(t, "bob")
+	ctx := context.Background()
+
+	c := New(ts.url, WithCredentials("root", "secret123"))
+	if _, err := c.ListAuditEvents(ctx, models.AuditQuery{}); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected ErrForbidden before promotion, got %v", err)
+	}
+
+	// 提升为管理员后刷新 token，新 token 使用数据库中的角色
+	if err := ts.db.Model(&models.User{}).Where("username = ?", "root").Update("role", models.RoleAdmin).Error; err != nil {
+		t.Fatalf("promote user: %v", err)
+	}
+	if _, err := c.Refresh(ctx); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+
+	events, err := c.ListAuditEvents(ctx, models.AuditQuery{Action: models.AuditUserRegister, Limit: 10})
+	if err != nil {
+		t.Fatalf("list audit events: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 register events, got %d", len(events))
+	}
+
+	var bob models.User
+	ts.db.Where("username = ?", "bob").First(&bob)
+	if err := c.DeleteUser(ctx, bob.ID); err != nil {
+		t.Fatalf("delete user: %v", err)
+	}
+	if err := c.DeleteUser(ctx, bob.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for deleted user, got %v", err)
+	}
+	if _, err := c.ShipOrder(ctx, 999); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for unknown order, got %v", err)
+	}
+}
+
+func TestOrganizationEndpoints(t *testing.T) {
+	ts := newTestServer(t)
+	ts.register(t, "alice")
+	ts.register(t, "bob")
+	ctx := context.Background()
+
+	alice := New(ts.url, WithCredentials("alice", "secret123"))
+	bob := New(ts.url, WithCredentials("bob", "secret123"))
+
+	org, err := alice.CreateOrganization(ctx, "Acme")
+	if err != nil {
+		t.Fatalf("create organization: %v", err)
+	}
+	inv, err := alice.CreateInvitation(ctx, org.ID, models.CreateInvitationRequest{Email: "bob@example.com"})
+	if err != nil {
+		t.Fatalf("create invitation: %v", err)
+	}
+	if _, err := bob.SwitchOrganization(ctx, org.ID); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected ErrForbidden before joining, got %v", err)
+	}
+	if _, err := bob.AcceptInvitation(ctx, inv.Token); err != nil {
+		t.Fatalf("accept invitation: %v", err)
+	}
+
+	resp, err := bob.SwitchOrganization(ctx, org.ID)
+	if err != nil || resp.OrgID != org.ID {
+		t.Fatalf("switch organization: %+v, %v", resp, err)
+	}
+	members, err := bob.ListMembers(ctx, org.ID)
+	if err != nil || len(members) != 2 {
+		t.Fatalf("expected 2 members, got %v (%v)", members, err)
+	}
+	orgs, err := bob.ListOrganizations(ctx)
+	if err != nil || len(orgs) != 1 || orgs[0].Role != models.OrgRoleMember {
+		t.Fatalf("unexpected organizations: %v (%v)", orgs, err)
+	}
+
+	if err := bob.RemoveMember(ctx, org.ID, members[0].UserID); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected member removal by a member to be forbidden, got %v", err)
+	}
+}
+
+func TestTokenRefresh(t *testing.T) {
+	ts := newTestServer(t)
+	ts.register(t, "alice")
+	ctx := context.Background()
+
+	// 服务端签发的 token 有效期为 24 小时，刷新窗口大于有效期时每次请求前都会刷新
+	c := New(ts.url, WithRefreshBefore(25*time.Hour))
+	if _, err := c.Login(ctx, "alice", "secret123"); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := c.Me(ctx); err != nil {
+			t.Fatalf("me: %v", err)
+		}
+	}
+	if n := ts.refreshes.Load(); n != 2 {
+		t.Fatalf("expected 2 refreshes, got %d", n)
+	}
+}
+
+func TestReloginWithCredentials(t *testing.T) {
+	ts := newTestServer(t)
+	ts.register(t, "alice")
+	ctx := context.Background()
+	c := New(ts.url, WithCredentials("alice", "secret123"))
+
+	// 没有 token 时自动登录
+	if _, err := c.Me(ctx); err != nil {
+		t.Fatalf("me without explicit login: %v", err)
+	}
+
+	// 服务端拒绝 token 时重新登录并重试
+	if err := c.SetToken("not-a-valid-token"); err != nil {
+		t.Fatalf("set token: %v", err)
+	}
+	if _, err := c.Me(ctx); err != nil {
+		t.Fatalf("me after token rejected: %v", err)
+	}
+	if token, _ := c.Token(); token.AccessToken == "not-a-valid-token" {
+		t.Fatalf("rejected token was not replaced")
+	}
+}
+
+// flakyServer 前 failures 次请求返回 status，之后返回成功
+func flakyServer(t *testing.T, failures int32, status int, header http.Header) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if calls.Add(1) <= failures {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(`{"code":` + strconv.Itoa(status) + `,"message":"try again","error":"try again"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"code":200,"message":"success","data":{"id":7,"username":"alice"}}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func fastRetry(attempts int) Option {
+	return WithRetryPolicy(RetryPolicy{MaxAttempts: attempts, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond})
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int32
+		status    int
+		header    http.Header
+		call      func(c *Client) error
+		wantCalls int32
+		wantErr   *APIError
+	}{
+		{
+			name: "GET retried on 500", failures: 2, status: http.StatusInternalServerError,
+			call:      func(c *Client) error { _, err := c.Me(context.Background()); return err },
+			wantCalls: 3,
+		},
+		{
+			name: "POST retried on 503", failures: 2, status: http.StatusServiceUnavailable,
+			call: func(c *Client) error {
+				_, err := c.Register(context.Background(), models.CreateUserRequest{Username: "alice"})
+				return err
+			},
+			wantCalls: 3,
+		},
+		{
+			name: "POST not retried on 500", failures: 1, status: http.StatusInternalServerError,
+			call: func(c *Client) error {
+				_, err := c.Register(context.Background(), models.CreateUserRequest{Username: "alice"})
+				return err
+			},
+			wantCalls: 1, wantErr: ErrInternal,
+		},
+		{
+			name: "429 honours Retry-After", failures: 1, status: http.StatusTooManyRequests,
+			header:    http.Header{"Retry-After": {"1"}},
+			call:      func(c *Client) error { _, err := c.Me(context.Background()); return err },
+			wantCalls: 2,
+		},
+		{
+			name: "gives up after max attempts", failures: 5, status: http.StatusBadGateway,
+			call:      func(c *Client) error { _, err := c.Me(context.Background()); return err },
+			wantCalls: 3, wantErr: &APIError{Code: http.StatusBadGateway},
+		},
+		{
+			name: "4xx not retried", failures: 1, status: http.StatusNotFound,
+			call:      func(c *Client) error { _, err := c.Me(context.Background()); return err },
+			wantCalls: 1, wantErr: ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, calls := flakyServer(t, tt.failures, tt.status, tt.header)
+			c := New(srv.URL, fastRetry(3))
+			if err := c.SetToken("token"); err != nil {
+				t.Fatalf("set token: %v", err)
+			}
+
+			err := tt.call(c)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Fatalf("expected %d calls, got %d", tt.wantCalls, got)
+			}
+		})
+	}
+}
+
+func TestContextCancelledDuringBackoff(t *testing.T) {
+	srv, _ := flakyServer(t, 100, http.StatusServiceUnavailable, nil)
+	c := New(srv.URL, WithRetryPolicy(RetryPolicy{MaxAttempts: 10, BaseDelay: time.Second, MaxDelay: time.Second}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.Register(ctx, models.CreateUserRequest{Username: "alice"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("cancellation took too long: %v", elapsed)
+	}
+}
+
+func TestNonJSONErrorResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = w.Write([]byte("<html>bad gateway</html>"))
+	}))
+	defer srv.Close()
+
+	_, err := New(srv.URL, fastRetry(1)).Register(context.Background(), models.CreateUserRequest{})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusBadGateway || apiErr.Detail != "<html>bad gateway</html>" {
+		t.Fatalf("unexpected error: %v (%+v)", err, apiErr)
+	}
+}
@@ -0,0 +1,60 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// APIError 服务端返回的错误响应，Code 与服务端 utils.AppError 的 Code 一致
+type APIError struct {
+	// StatusCode HTTP 状态码，Code 为响应体中的 code，两者通常相同
+	StatusCode int
+	Code       int
+	Message    string
+	// Detail 响应体中 error 字段为字符串时的内容
+	Detail string
+	// Field 出错的请求字段，例如唯一约束冲突的 username
+	Field string
+	// Fields 参数校验失败时每个字段的错误信息
+	Fields    map[string]string
+	RequestID string
+
+	retryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("projectdemo: %d %s", e.Code, e.Message)
+	if e.Field != "" {
+		msg += " (field " + e.Field + ")"
+	}
+	if e.RequestID != "" {
+		msg += " [request " + e.RequestID + "]"
+	}
+	return msg
+}
+
+// Is 按 Code 匹配，使 errors.Is(err, client.ErrConflict) 可用
+func (e *APIError) Is(target error) bool {
+	var t *APIError
+	if !errors.As(target, &t) {
+		return false
+	}
+	return t.Code == e.Code
+}
+
+var (
+	ErrBadRequest         = &APIError{Code: http.StatusBadRequest, Message: "bad request"}
+	ErrUnauthorized       = &APIError{Code: http.StatusUnauthorized, Message: "unauthorized"}
+	ErrForbidden          = &APIError{Code: http.StatusForbidden, Message: "forbidden"}
+	ErrNotFound           = &APIError{Code: http.StatusNotFound, Message: "not found"}
+	ErrConflict           = &APIError{Code: http.StatusConflict, Message: "conflict"}
+	ErrValidation         = &APIError{Code: http.StatusUnprocessableEntity, Message: "validation failed"}
+	ErrTooManyRequests    = &APIError{Code: http.StatusTooManyRequests, Message: "too many requests"}
+	ErrInternal           = &APIError{Code: http.StatusInternalServerError, Message: "internal server error"}
+	ErrServiceUnavailable = &APIError{Code: http.StatusServiceUnavailable, Message: "service unavailable"}
+)
+
+// ErrNotLoggedIn 调用需要认证的接口时既没有 token 也没有配置登录凭据
+var ErrNotLoggedIn = errors.New("projectdemo: not logged in")
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"projectdemo/models"
+	"strconv"
+)
+
+func (c *Client) CreateOrganization(ctx context.Context, name string) (*models.Organization, error) {
+	var org models.Organization
+	req := request{method: http.MethodPost, path: "/api/v1/orgs", body: models.CreateOrganizationRequest{Name: name}, auth: true}
+	if err := c.do(ctx, req, &org); err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+func (c *Client) ListOrganizations(ctx context.Context) ([]models.OrganizationResponse, error) {
+	var orgs []models.OrganizationResponse
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/orgs", auth: true}, &orgs); err != nil {
+		return nil, err
+	}
+	return orgs, nil
+}
+
+// SwitchOrganization 切换活动组织并保存新 token，orgID 为 0 时切换回个人空间。
+// 通过 WithCredentials 自动重新登录后会回到个人空间
+func (c *Client) SwitchOrganization(ctx context.Context, orgID uint) (*models.LoginResponse, error) {
+	var resp models.LoginResponse
+	req := request{method: http.MethodPut, path: "/api/v1/users/me/organization", body: models.SwitchOrganizationRequest{OrgID: orgID}, auth: true}
+	if err := c.do(ctx, req, &resp); err != nil {
+		return nil, err
+	}
+	if err := c.tokens.Save(newToken(resp.Token)); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) ListMembers(ctx context.Context, orgID uint) ([]models.MemberResponse, error) {
+	var members []models.MemberResponse
+	if err := c.do(ctx, request{method: http.MethodGet, path: orgPath(orgID) + "/members", auth: true}, &members); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (c *Client) RemoveMember(ctx context.Context, orgID, userID uint) error {
+	path := orgPath(orgID) + "/members/" + strconv.FormatUint(uint64(userID), 10)
+	return c.do(ctx, request{method: http.MethodDelete, path: path, auth: true}, nil)
+}
+
+// CreateInvitation 返回的 Token 只在此时可见
+func (c *Client) CreateInvitation(ctx context.Context, orgID uint, req models.CreateInvitationRequest) (*models.Invitation, error) {
+	var inv models.Invitation
+	if err := c.do(ctx, request{method: http.MethodPost, path: orgPath(orgID) + "/invitations", body: req, auth: true}, &inv); err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+func (c *Client) AcceptInvitation(ctx context.Context, token string) (*models.Membership, error) {
+	var membership models.Membership
+	req := request{method: http.MethodPost, path: "/api/v1/invitations/accept", body: models.AcceptInvitationRequest{Token: token}, auth: true}
+	if err := c.do(ctx, req, &membership); err != nil {
+		return nil, err
+	}
+	return &membership, nil
+}
+
+func orgPath(id uint) string {
+	return "/api/v1/orgs/" + strconv.FormatUint(uint64(id), 10)
+}
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Token 访问令牌，ExpiresAt 从 JWT 的 exp 声明解析，解析失败时为零值
+type Token struct {
+	AccessToken string
+	ExpiresAt   time.Time
+}
+
+// expiresWithin token 为空或将在 d 内过期时返回 true，过期时间未知时视为不会过期
+func (t Token) expiresWithin(d time.Duration) bool {
+	if t.AccessToken == "" {
+		return true
+	}
+	return !t.ExpiresAt.IsZero() && time.Until(t.ExpiresAt) < d
+}
+
+func (t Token) expired() bool {
+	return t.expiresWithin(0)
+}
+
+// TokenStore 保存当前的访问令牌。多个 Client 共享同一个 TokenStore 时可以共享登录状态，
+// 也可以实现为写入文件或密钥管理服务
+type TokenStore interface {
+	Load() (Token, error)
+	Save(token Token) error
+}
+
+// MemoryTokenStore 默认的内存实现，并发安全
+type MemoryTokenStore struct {
+	mu    sync.RWMutex
+	token Token
+}
+
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{}
+}
+
+func (s *MemoryTokenStore) Load() (Token, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.token, nil
+}
+
+func (s *MemoryTokenStore) Save(token Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = token
+	return nil
+}
+
+// newToken 客户端不持有签名密钥，这里只读取 exp 用于判断何时刷新，不校验签名
+func newToken(accessToken string) Token {
+	token := Token{AccessToken: accessToken}
+
+	parts := strings.Split(accessToken, ".")
+	if len(parts) != 3 {
+		return token
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return token
+	}
+	var claims struct {
+		ExpiresAt int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err == nil && claims.ExpiresAt > 0 {
+		token.ExpiresAt = time.Unix(claims.ExpiresAt, 0)
+	}
+	return token
+}
@@ -0,0 +1,106 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"projectdemo/models"
+)
+
+func (c *Client) Register(ctx context.Context, req models.CreateUserRequest) (*models.UserResponse, error) {
+	var user models.UserResponse
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/users/register", body: req}, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Login 登录并保存返回的 token。密码不会被保存，需要自动重新登录时使用 WithCredentials
+func (c *Client) Login(ctx context.Context, username, password string) (*models.LoginResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var resp models.LoginResponse
+	if err := c.loginRequest(ctx, username, password, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Refresh 立即用当前 token 换取新 token。通常不需要手动调用，请求前会自动刷新即将过期的 token
+func (c *Client) Refresh(ctx context.Context) (*models.LoginResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	token, err := c.tokens.Load()
+	if err != nil {
+		return nil, err
+	}
+	if token.AccessToken == "" {
+		return nil, ErrNotLoggedIn
+	}
+
+	var resp models.LoginResponse
+	if err := c.refreshRequest(ctx, token, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Logout 清除保存的 token，服务端的 token 是无状态的，不需要通知服务端
+func (c *Client) Logout() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tokens.Save(Token{})
+}
+
+func (c *Client) Me(ctx context.Context) (*models.UserResponse, error) {
+	var user models.UserResponse
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/users/me", auth: true}, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// UpdateMe 更新邮箱和个人资料，指针字段为 nil 的资料不修改
+func (c *Client) UpdateMe(ctx context.Context, req models.UpdateUserRequest) (*models.UserResponse, error) {
+	var user models.UserResponse
+	if err := c.do(ctx, request{method: http.MethodPut, path: "/api/v1/users/me", body: req, auth: true}, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// 以下方法要求调用方持有 c.mu
+
+func (c *Client) loginLocked(ctx context.Context, username, password string) (Token, error) {
+	var resp models.LoginResponse
+	if err := c.loginRequest(ctx, username, password, &resp); err != nil {
+		return Token{}, err
+	}
+	return c.tokens.Load()
+}
+
+func (c *Client) refreshLocked(ctx context.Context, token Token) (Token, error) {
+	var resp models.LoginResponse
+	if err := c.refreshRequest(ctx, token, &resp); err != nil {
+		return Token{}, err
+	}
+	return c.tokens.Load()
+}
+
+func (c *Client) loginRequest(ctx context.Context, username, password string, resp *models.LoginResponse) error {
+	req := request{method: http.MethodPost, path: "/api/v1/users/login", body: models.LoginRequest{Username: username, Password: password}}
+	if err := c.do(ctx, req, resp); err != nil {
+		return err
+	}
+	return c.tokens.Save(newToken(resp.Token))
+}
+
+// refreshRequest 直接使用传入的 token，不经过 ensureToken，避免递归刷新
+func (c *Client) refreshRequest(ctx context.Context, token Token, resp *models.LoginResponse) error {
+	req := request{method: http.MethodPost, path: "/api/v1/users/refresh"}
+	if err := c.send(ctx, req, nil, token.AccessToken, resp); err != nil {
+		return err
+	}
+	return c.tokens.Save(newToken(resp.Token))
+}
@@ -1,9 +1,17 @@
 package config
 
+import "time"
+
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
+	Server    ServerConfig    `mapstructure:"server"`
+	Database  DatabaseConfig  `mapstructure:"database"`
+	JWT       JWTConfig       `mapstructure:"jwt"`
+	Storage   StorageConfig   `mapstructure:"storage"`
+	Avatar    AvatarConfig    `mapstructure:"avatar"`
+	Webhook   WebhookConfig   `mapstructure:"webhook"`
+	Jobs      JobsConfig      `mapstructure:"jobs"`
+	Scheduler SchedulerConfig `mapstructure:"scheduler"`
+	Cache     CacheConfig     `mapstructure:"cache"`
 }
 
 type ServerConfig struct {
@@ -13,6 +21,9 @@ type ServerConfig struct {
 }
 
 type DatabaseConfig struct {
+	// Driver 目前只支持 sqlite，Path 为数据库文件路径
+	Driver   string `mapstructure:"driver"`
+	Path     string `mapstructure:"path"`
 	Host     string `mapstructure:"host"`
 	Port     int    `mapstructure:"port"`
 	Username string `mapstructure:"username"`
@@ -25,6 +36,50 @@ type JWTConfig struct {
 	Expire string `mapstructure:"expire"`
 }
 
+type StorageConfig struct {
+	Driver   string `mapstructure:"driver"`
+	LocalDir string `mapstructure:"local_dir"`
+	BaseURL  string `mapstructure:"base_url"`
+}
+
+type AvatarConfig struct {
+	MaxSize      int64 `mapstructure:"max_size"`
+	MaxDimension int   `mapstructure:"max_dimension"`
+	Sizes        []int `mapstructure:"sizes"`
+}
+
+// WebhookConfig webhook 投递。第 n 次失败后等待 BaseDelay*2^(n-1)（不超过 MaxDelay）再重试，
+// 共尝试 MaxAttempts 次
+type WebhookConfig struct {
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	Timeout      time.Duration `mapstructure:"timeout"`
+	MaxAttempts  int           `mapstructure:"max_attempts"`
+	BaseDelay    time.Duration `mapstructure:"base_delay"`
+	MaxDelay     time.Duration `mapstructure:"max_delay"`
+}
+
+// JobsConfig 后台任务队列。Timeout 是单个任务的执行时间上限，也决定 worker 租约的长度；
+// 失败后按 BaseDelay*2^(n-1)（不超过 MaxDelay）重试
+type JobsConfig struct {
+	Workers      int           `mapstructure:"workers"`
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	Timeout      time.Duration `mapstructure:"timeout"`
+	BaseDelay    time.Duration `mapstructure:"base_delay"`
+	MaxDelay     time.Duration `mapstructure:"max_delay"`
+}
+
+// SchedulerConfig 定时任务。每隔 PollInterval 检查一次到期的任务，所以实际执行时间最多晚这么久
+type SchedulerConfig struct {
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+}
+
+// CacheConfig 进程内缓存。Size 是最多缓存的条目数；UserTTL 为 0 时不缓存用户，
+// 多个副本各自缓存时，其他副本修改的用户最多要过 UserTTL 才能读到
+type CacheConfig struct {
+	Size    int           `mapstructure:"size"`
+	UserTTL time.Duration `mapstructure:"user_ttl"`
+}
+
 func Load() *Config {
 	// 简化配置加载，实际应该使用 Viper
 	return &Config{
@@ -34,6 +89,8 @@ func Load() *Config {
 			Mode: "debug",
 		},
 		Database: DatabaseConfig{
+			Driver:   "sqlite",
+			Path:     "users.db",
 			Host:     "localhost",
 			Port:     3306,
 			Username: "root",
@@ -44,6 +101,51 @@ func Load() *Config {
 			Secret: "your-secret-key-change-in-production",
 			Expire: "24h",
 		},
+		Storage: StorageConfig{
+			Driver:   "local",
+			LocalDir: "./data/blobs",
+			BaseURL:  "/media",
+		},
+		Avatar: AvatarConfig{
+			MaxSize:      5 << 20,
+			MaxDimension: 4096,
+			Sizes:        []int{64, 128, 256},
+		},
+		Webhook: WebhookConfig{
+			PollInterval: time.Second,
+			Timeout:      10 * time.Second,
+			MaxAttempts:  8,
+			BaseDelay:    30 * time.Second,
+			MaxDelay:     time.Hour,
+		},
+		Jobs: JobsConfig{
+			Workers:      4,
+			PollInterval: time.Second,
+			Timeout:      time.Minute,
+			BaseDelay:    10 * time.Second,
+			MaxDelay:     time.Hour,
+		},
+		Scheduler: SchedulerConfig{
+			PollInterval: 10 * time.Second,
+		},
+		Cache: CacheConfig{
+			Size:    10000,
+			UserTTL: time.Minute,
+		},
 	}
 
 }
+
+const redacted = "******"
+
+// Redacted 返回隐藏了密钥和密码的副本，用于打印或记录日志
+func (c *Config) Redacted() *Config {
+	cp := *c
+	if cp.JWT.Secret != "" {
+		cp.JWT.Secret = redacted
+	}
+	if cp.Database.Password != "" {
+		cp.Database.Password = redacted
+	}
+	return &cp
+}
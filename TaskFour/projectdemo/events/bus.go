@@ -0,0 +1,265 @@
+// Package events 进程内的类型化发布/订阅。事件按 Go 类型路由，订阅者可以同步执行
+// （在 Publish 中依次调用，错误返回给发布方），也可以异步执行（有界队列 + 固定数量的 worker）。
+// 单个订阅者 panic 不会影响发布方和其他订阅者
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"projectdemo/internal/background"
+	"reflect"
+	"sync"
+)
+
+var ErrClosed = errors.New("events: bus is closed")
+
+const (
+	defaultQueueSize = 64
+	defaultWorkers   = 1
+)
+
+// Bus 零值不可用，使用 NewBus 创建
+type Bus struct {
+	mu     sync.RWMutex
+	subs   map[reflect.Type][]*subscriber
+	nextID int
+	closed bool
+	// wg 跟踪所有异步 worker，Shutdown 等待它们处理完队列
+	wg sync.WaitGroup
+}
+
+func NewBus() *Bus {
+	return &Bus{subs: make(map[reflect.Type][]*subscriber)}
+}
+
+type envelope struct {
+	ctx   context.Context
+	event interface{}
+}
+
+type subscriber struct {
+	id      int
+	name    string
+	handle  func(ctx context.Context, event interface{}) error
+	async   bool
+	size    int
+	workers int
+	queue   chan envelope
+
+	// closed 在取消订阅或 Shutdown 后置位，之后不再调用或入队；
+	// done 唤醒阻塞在满队列上的 Publish，sending 记录正在入队的 Publish，全部退出后才关闭 queue
+	mu      sync.Mutex
+	closed  bool
+	done    chan struct{}
+	sending sync.WaitGroup
+}
+
+// SubscribeOption 调整订阅者的执行方式
+type SubscribeOption func(*subscriber)
+
+// Async 在 workers 个 goroutine 中处理事件，队列最多缓存 queueSize 个事件。
+// 队列满时 Publish 阻塞，直到有空位或发布方的 ctx 结束
+func Async(queueSize, workers int) SubscribeOption {
+	return func(s *subscriber) {
+		s.async = true
+		if queueSize > 0 {
+			s.size = queueSize
+		}
+		if workers > 0 {
+			s.workers = workers
+		}
+	}
+}
+
+// Named 设置订阅者名称，用于错误信息和日志
+func Named(name string) SubscribeOption {
+	return func(s *subscriber) {
+		s.name = name
+	}
+}
+
+// Subscribe 订阅类型为 T 的事件，返回的函数用于取消订阅（异步订阅者会先处理完已入队的事件）
+func Subscribe[T any](b *Bus, handler func(ctx context.Context, event T) error, opts ...SubscribeOption) (func(), error) {
+	s := &subscriber{
+		handle: func(ctx context.Context, event interface{}) error {
+			return handler(ctx, event.(T))
+		},
+		size:    defaultQueueSize,
+		workers: defaultWorkers,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	typ := reflect.TypeFor[T]()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrClosed
+	}
+	b.nextID++
+	s.id = b.nextID
+	if s.name == "" {
+		s.name = fmt.Sprintf("%s#%d", typ, s.id)
+	}
+	s.done = make(chan struct{})
+	if s.async {
+		s.queue = make(chan envelope, s.size)
+		for i := 0; i < s.workers; i++ {
+			b.wg.Add(1)
+			go func() {
+				defer b.wg.Done()
+				for env := range s.queue {
+					if err := s.call(env.ctx, env.event); err != nil {
+						log.Printf("events: %v", err)
+					}
+				}
+			}()
+		}
+	}
+	b.subs[typ] = append(b.subs[typ], s)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { b.unsubscribe(typ, s) })
+	}, nil
+}
+
+func (b *Bus) unsubscribe(typ reflect.Type, s *subscriber) {
+	b.mu.Lock()
+	subs := b.subs[typ]
+	for i, other := range subs {
+		if other == s {
+			// 复制一份，避免影响正在遍历旧切片的 Publish
+			b.subs[typ] = append(subs[:i:i], subs[i+1:]...)
+			break
+		}
+	}
+	b.mu.Unlock()
+	// 在锁外关闭：处理函数内取消订阅时，等待入队的 Publish 不会和它互相等待
+	s.close()
+}
+
+// Publish 把事件交给所有订阅了类型 T 的订阅者。同步订阅者的错误（包括 panic）合并后返回；
+// 异步订阅者只负责入队，处理错误记录到日志。
+// 异步处理使用的 context 保留 ctx 中的值，但不随 ctx 取消，请求结束后事件仍会被处理
+func Publish[T any](ctx context.Context, b *Bus, event T) error {
+	if b == nil {
+		return nil
+	}
+	// 只在读取订阅者列表时持有锁，处理函数可以订阅或取消订阅，满队列也不会阻塞 Subscribe 和 Shutdown
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return ErrClosed
+	}
+	subs := b.subs[reflect.TypeFor[T]()]
+	b.mu.RUnlock()
+
+	var errs []error
+	var detached context.Context
+	for _, s := range subs {
+		if !s.async {
+			if s.isClosed() {
+				continue
+			}
+			if err := s.call(ctx, event); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if detached == nil {
+			detached = context.WithoutCancel(ctx)
+		}
+		if err := s.enqueue(ctx, envelope{ctx: detached, event: event}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *subscriber) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// enqueue 把事件放入异步队列，订阅者已关闭时丢弃
+func (s *subscriber) enqueue(ctx context.Context, env envelope) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.sending.Add(1)
+	s.mu.Unlock()
+	defer s.sending.Done()
+
+	select {
+	case s.queue <- env:
+		return nil
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("events: enqueue to %s: %w", s.name, ctx.Err())
+	}
+}
+
+// close 停止接收事件，异步订阅者的 worker 处理完已入队的事件后退出
+func (s *subscriber) close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	close(s.done)
+	if s.async {
+		s.sending.Wait()
+		close(s.queue)
+	}
+}
+
+func (s *subscriber) call(ctx context.Context, event interface{}) error {
+	err := background.Call(ctx, func(ctx context.Context) error {
+		return s.handle(ctx, event)
+	})
+	if err != nil {
+		return fmt.Errorf("subscriber %s: %w", s.name, err)
+	}
+	return nil
+}
+
+// Shutdown 停止接收新事件，并等待异步订阅者处理完队列中剩余的事件。
+// ctx 结束时直接返回，尚未处理的事件由后台 worker 继续处理
+func (b *Bus) Shutdown(ctx context.Context) error {
+	b.mu.Lock()
+	var closing []*subscriber
+	if !b.closed {
+		b.closed = true
+		for _, subs := range b.subs {
+			closing = append(closing, subs...)
+		}
+		b.subs = nil
+	}
+	b.mu.Unlock()
+	for _, s := range closing {
+		s.close()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,322 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type userCreated struct {
+	ID int
+}
+
+type orderPlaced struct {
+	ID int
+}
+
+func mustSubscribe[T any](t *testing.T, b *Bus, handler func(context.Context, T) error, opts ...SubscribeOption) func() {
+	t.Helper()
+	unsubscribe, err := Subscribe(b, handler, opts...)
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	return unsubscribe
+}
+
+func TestSyncSubscribersByType(t *testing.T) {
+	b := NewBus()
+	var users, orders []int
+	mustSubscribe(t, b, func(_ context.Context, e userCreated) error {
+		users = append(users, e.ID)
+		return nil
+	})
+	mustSubscribe(t, b, func(_ context.Context, e orderPlaced) error {
+		orders = append(orders, e.ID)
+		return nil
+	})
+
+	ctx := context.Background()
+	for _, err := range []error{
+		Publish(ctx, b, userCreated{ID: 1}),
+		Publish(ctx, b, orderPlaced{ID: 2}),
+		Publish(ctx, b, userCreated{ID: 3}),
+		// 没有订阅者的类型
+		Publish(ctx, b, "ignored"),
+	} {
+		if err != nil {
+			t.Fatalf("publish: %v", err)
+		}
+	}
+	if len(users) != 2 || users[1] != 3 || len(orders) != 1 {
+		t.Fatalf("unexpected deliveries: users %v, orders %v", users, orders)
+	}
+}
+
+func TestSubscriberErrorsAndPanicsAreIsolated(t *testing.T) {
+	b := NewBus()
+	var calls int
+	mustSubscribe(t, b, func(context.Context, userCreated) error { panic("boom") }, Named("panicky"))
+	mustSubscribe(t, b, func(context.Context, userCreated) error { return errors.New("mail down") }, Named("mailer"))
+	mustSubscribe(t, b, func(context.Context, userCreated) error {
+		calls++
+		return nil
+	})
+
+	err := Publish(context.Background(), b, userCreated{ID: 1})
+	if err == nil || !strings.Contains(err.Error(), "panicky: panic: boom") || !strings.Contains(err.Error(), "mailer: mail down") {
+		t.Fatalf("expected both failures to be reported, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected healthy subscriber to run once, got %d", calls)
+	}
+
+	// 异步订阅者 panic 后 worker 继续处理后续事件
+	var handled atomic.Int32
+	mustSubscribe(t, b, func(_ context.Context, e orderPlaced) error {
+		if e.ID == 1 {
+			panic("boom")
+		}
+		handled.Add(1)
+		return nil
+	}, Async(4, 1))
+	for i := 1; i <= 3; i++ {
+		if err := Publish(context.Background(), b, orderPlaced{ID: i}); err != nil {
+			t.Fatalf("publish: %v", err)
+		}
+	}
+	if err := b.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if handled.Load() != 2 {
+		t.Fatalf("expected 2 events after the panic, got %d", handled.Load())
+	}
+}
+
+func TestAsyncBoundedQueue(t *testing.T) {
+	b := NewBus()
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	mustSubscribe(t, b, func(context.Context, userCreated) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	}, Async(1, 1))
+
+	ctx := context.Background()
+	// 第一个事件被 worker 取走，第二个占满队列
+	if err := Publish(ctx, b, userCreated{ID: 1}); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	<-started
+	if err := Publish(ctx, b, userCreated{ID: 2}); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := Publish(timeout, b, userCreated{ID: 3}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected full queue to block until the deadline, got %v", err)
+	}
+	close(release)
+}
+
+func TestAsyncContextOutlivesPublisher(t *testing.T) {
+	type key struct{}
+	b := NewBus()
+	got := make(chan error, 1)
+	mustSubscribe(t, b, func(ctx context.Context, _ userCreated) error {
+		if ctx.Value(key{}) != "meta" {
+			got <- errors.New("context value lost")
+			return nil
+		}
+		got <- ctx.Err()
+		return nil
+	}, Async(1, 1))
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), key{}, "meta"))
+	if err := Publish(ctx, b, userCreated{ID: 1}); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	cancel()
+	if err := <-got; err != nil {
+		t.Fatalf("expected handler context to keep values and not be canceled, got %v", err)
+	}
+}
+
+func TestShutdownDrainsQueues(t *testing.T) {
+	b := NewBus()
+	var mu sync.Mutex
+	var seen []int
+	mustSubscribe(t, b, func(_ context.Context, e userCreated) error {
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		seen = append(seen, e.ID)
+		mu.Unlock()
+		return nil
+	}, Async(16, 2))
+
+	for i := 0; i < 10; i++ {
+		if err := Publish(context.Background(), b, userCreated{ID: i}); err != nil {
+			t.Fatalf("publish: %v", err)
+		}
+	}
+	if err := b.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if len(seen) != 10 {
+		t.Fatalf("expected all 10 queued events to be handled, got %d", len(seen))
+	}
+
+	if err := Publish(context.Background(), b, userCreated{ID: 11}); !errors.Is(err, ErrClosed) {
+		t.Fatalf("expected ErrClosed after shutdown, got %v", err)
+	}
+	if _, err := Subscribe(b, func(context.Context, userCreated) error { return nil }); !errors.Is(err, ErrClosed) {
+		t.Fatalf("expected subscribe to fail after shutdown, got %v", err)
+	}
+	if err := b.Shutdown(context.Background()); err != nil {
+		t.Fatalf("second shutdown: %v", err)
+	}
+}
+
+func TestShutdownHonorsContext(t *testing.T) {
+	b := NewBus()
+	release := make(chan struct{})
+	defer close(release)
+	mustSubscribe(t, b, func(context.Context, userCreated) error {
+		<-release
+		return nil
+	}, Async(1, 1))
+	if err := Publish(context.Background(), b, userCreated{ID: 1}); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := b.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected shutdown to give up at the deadline, got %v", err)
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	b := NewBus()
+	var calls int
+	unsubscribe := mustSubscribe(t, b, func(context.Context, userCreated) error {
+		calls++
+		return nil
+	})
+	ctx := context.Background()
+	_ = Publish(ctx, b, userCreated{ID: 1})
+	unsubscribe()
+	unsubscribe()
+	_ = Publish(ctx, b, userCreated{ID: 2})
+	if calls != 1 {
+		t.Fatalf("expected 1 call before unsubscribe, got %d", calls)
+	}
+
+	// 未设置总线时发布为空操作
+	if err := Publish(ctx, (*Bus)(nil), userCreated{ID: 3}); err != nil {
+		t.Fatalf("publish to nil bus: %v", err)
+	}
+}
+
+func TestUnsubscribeFromHandler(t *testing.T) {
+	b := NewBus()
+	ctx := context.Background()
+	var calls, others int
+	var unsubscribe func()
+	unsubscribe = mustSubscribe(t, b, func(context.Context, userCreated) error {
+		calls++
+		// Publish 不再持有总线的锁，处理函数内取消订阅不会死锁
+		unsubscribe()
+		return nil
+	})
+	mustSubscribe(t, b, func(context.Context, userCreated) error {
+		others++
+		return nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = Publish(ctx, b, userCreated{ID: 1})
+		_ = Publish(ctx, b, userCreated{ID: 2})
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("publish deadlocked when a handler unsubscribed")
+	}
+	if calls != 1 || others != 2 {
+		t.Fatalf("expected 1 call before unsubscribe and 2 for the other subscriber, got %d and %d", calls, others)
+	}
+}
+
+func TestUnsubscribeReleasesBlockedPublish(t *testing.T) {
+	b := NewBus()
+	ctx := context.Background()
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	unsubscribe := mustSubscribe(t, b, func(context.Context, userCreated) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	}, Async(1, 1))
+	if err := Publish(ctx, b, userCreated{ID: 1}); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	<-started
+	if err := Publish(ctx, b, userCreated{ID: 2}); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	// 队列已满，Publish 阻塞时取消订阅：Publish 返回，关闭的队列上不会再发送
+	blocked := make(chan error, 1)
+	go func() { blocked <- Publish(ctx, b, userCreated{ID: 3}) }()
+	time.Sleep(10 * time.Millisecond)
+	unsubscribed := make(chan struct{})
+	go func() {
+		unsubscribe()
+		close(unsubscribed)
+	}()
+	select {
+	case err := <-blocked:
+		if err != nil {
+			t.Fatalf("expected blocked publish to return quietly, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("publish stayed blocked after unsubscribe")
+	}
+	close(release)
+	<-unsubscribed
+	if err := Publish(ctx, b, userCreated{ID: 4}); err != nil {
+		t.Fatalf("publish after unsubscribe: %v", err)
+	}
+}
+
+func TestConcurrentPublishAndUnsubscribe(t *testing.T) {
+	b := NewBus()
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		unsubscribe := mustSubscribe(t, b, func(context.Context, userCreated) error { return nil }, Async(1, 1))
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_ = Publish(ctx, b, userCreated{ID: j})
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			unsubscribe()
+		}()
+	}
+	wg.Wait()
+	if err := b.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+}
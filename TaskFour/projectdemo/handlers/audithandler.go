@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+	"projectdemo/models"
+	"projectdemo/services"
+	"projectdemo/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditHandler struct {
+	auditService *services.AuditService
+}
+
+func NewAuditHandler(auditService *services.AuditService) *AuditHandler {
+	return &AuditHandler{auditService: auditService}
+}
+
+// ListEvents 查询审计事件，format=csv/jsonl 时以流的方式导出全部匹配结果
+func (h *AuditHandler) ListEvents(c *gin.Context) {
+	var q models.AuditQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		utils.ValidationError(c, parseValidationErrors(err))
+		return
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && !q.From.Before(q.To) {
+		utils.Error(c, http.StatusBadRequest, "from must be before to")
+		return
+	}
+
+	ctx := c.Request.Context()
+	filename := "audit-" + time.Now().UTC().Format("20060102T150405Z")
+
+	switch q.Format {
+	case "csv":
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", "attachment; filename="+filename+".csv")
+		c.Status(http.StatusOK)
+		if err := h.auditService.ExportCSV(ctx, q, c.Writer); err != nil {
+			c.Error(err)
+		}
+	case "jsonl":
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Header("Content-Disposition", "attachment; filename="+filename+".jsonl")
+		c.Status(http.StatusOK)
+		if err := h.auditService.ExportJSONL(ctx, q, c.Writer); err != nil {
+			c.Error(err)
+		}
+	default:
+		events, err := h.auditService.List(ctx, q)
+		if err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+		utils.Success(c, events)
+	}
+}
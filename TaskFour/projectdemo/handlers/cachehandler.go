@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"projectdemo/cache"
+	"projectdemo/models"
+	"projectdemo/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CacheHandler struct {
+	users *cache.Loader[models.User]
+}
+
+// NewCacheHandler users 为 nil 表示没有启用用户缓存
+func NewCacheHandler(users *cache.Loader[models.User]) *CacheHandler {
+	return &CacheHandler{users: users}
+}
+
+// Stats 按缓存名称返回命中和未命中次数，只包含已启用的缓存
+func (h *CacheHandler) Stats(c *gin.Context) {
+	stats := map[string]cache.Stats{}
+	if h.users != nil {
+		stats["users"] = h.users.Stats()
+	}
+	utils.Success(c, stats)
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+	"projectdemo/models"
+	"projectdemo/services"
+	"projectdemo/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CartHandler struct {
+	cartService *services.CartService
+}
+
+func NewCartHandler(cartService *services.CartService) *CartHandler {
+	return &CartHandler{cartService: cartService}
+}
+
+func (h *CartHandler) GetCart(c *gin.Context) {
+	items, err := h.cartService.List(c.Request.Context(), c.GetUint("userID"))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, items)
+}
+
+func (h *CartHandler) AddItem(c *gin.Context) {
+	var req models.AddCartItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, parseValidationErrors(err))
+		return
+	}
+
+	items, err := h.cartService.AddItem(c.Request.Context(), c.GetUint("userID"), req)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, items)
+}
+
+func (h *CartHandler) UpdateItem(c *gin.Context) {
+	productID := uintParam(c, "product_id")
+	if productID == 0 {
+		utils.Error(c, http.StatusBadRequest, "Invalid product id")
+		return
+	}
+
+	var req models.UpdateCartItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, parseValidationErrors(err))
+		return
+	}
+
+	items, err := h.cartService.UpdateItem(c.Request.Context(), c.GetUint("userID"), productID, req.Quantity)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, items)
+}
+
+func (h *CartHandler) RemoveItem(c *gin.Context) {
+	productID := uintParam(c, "product_id")
+	if productID == 0 {
+		utils.Error(c, http.StatusBadRequest, "Invalid product id")
+		return
+	}
+
+	items, err := h.cartService.RemoveItem(c.Request.Context(), c.GetUint("userID"), productID)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, items)
+}
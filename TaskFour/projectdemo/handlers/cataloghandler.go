@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"projectdemo/models"
+	"projectdemo/repository"
+	"projectdemo/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CatalogHandler 公开的商品目录，只展示上架中的商品
+type CatalogHandler struct {
+	repo *repository.Repository[models.Product]
+}
+
+func NewCatalogHandler(repo *repository.Repository[models.Product]) *CatalogHandler {
+	return &CatalogHandler{repo: repo}
+}
+
+func (h *CatalogHandler) ListProducts(c *gin.Context) {
+	q := repository.ListQuery{
+		Sort:    c.Query("sort"),
+		Filters: map[string]string{"status": models.ProductStatusActive},
+	}
+	q.Page, _ = strconv.Atoi(c.Query("page"))
+	q.PageSize, _ = strconv.Atoi(c.Query("page_size"))
+	if sku := c.Query("sku"); sku != "" {
+		q.Filters["sku"] = sku
+	}
+
+	page, err := h.repo.List(c.Request.Context(), q)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, page)
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"projectdemo/models"
+	"projectdemo/repository"
+	"projectdemo/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Resource 描述一个 CRUD 资源如何由请求体构造和修改模型
+//
+//	T 为 GORM 模型，C 为创建/整体更新（PUT）的请求体，P 为部分更新（PATCH）的请求体
+type Resource[T any, C any, P any] struct {
+	Name string
+	// Assign 将创建/整体更新请求写入模型
+	Assign func(item *T, req C)
+	// Patch 只写入请求中出现的字段，P 通常使用指针字段表示"未提供"
+	Patch func(item *T, req P)
+}
+
+// CRUDHandler 为资源提供通用的列表、详情、创建、更新、部分更新和软删除接口。
+// 当 T 实现 repository.Owned 时，普通用户只能访问自己创建的记录，管理员不受限制；
+// 当 T 实现 repository.TenantOwned 时，所有操作由 TenantPlugin 限定在当前组织内
+type CRUDHandler[T any, C any, P any] struct {
+	repo     *repository.Repository[T]
+	resource Resource[T, C, P]
+}
+
+func NewCRUDHandler[T any, C any, P any](repo *repository.Repository[T], resource Resource[T, C, P]) *CRUDHandler[T, C, P] {
+	return &CRUDHandler[T, C, P]{repo: repo, resource: resource}
+}
+
+// Register 在 group 下注册 path 对应的六个接口，group 需要已挂载 Auth 中间件
+func (h *CRUDHandler[T, C, P]) Register(group *gin.RouterGroup, path string) {
+	group.GET(path, h.List)
+	group.GET(path+"/:id", h.Get)
+	group.POST(path, h.Create)
+	group.PUT(path+"/:id", h.Update)
+	group.PATCH(path+"/:id", h.PatchItem)
+	group.DELETE(path+"/:id", h.Delete)
+}
+
+func (h *CRUDHandler[T, C, P]) List(c *gin.Context) {
+	q := repository.ListQuery{Filters: map[string]string{}}
+	q.Page, _ = strconv.Atoi(c.Query("page"))
+	q.PageSize, _ = strconv.Atoi(c.Query("page_size"))
+	q.Sort = c.Query("sort")
+	for key, values := range c.Request.URL.Query() {
+		if len(values) > 0 {
+			q.Filters[key] = values[0]
+		}
+	}
+	if h.owned() && !isAdmin(c) {
+		q.OwnerID = c.GetUint("userID")
+	}
+
+	page, err := h.repo.List(c.Request.Context(), q)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, page)
+}
+
+func (h *CRUDHandler[T, C, P]) Get(c *gin.Context) {
+	item, ok := h.load(c)
+	if !ok {
+		return
+	}
+	utils.Success(c, item)
+}
+
+func (h *CRUDHandler[T, C, P]) Create(c *gin.Context) {
+	var req C
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, parseValidationErrors(err))
+		return
+	}
+
+	item := new(T)
+	h.resource.Assign(item, req)
+	if owned, ok := any(item).(repository.Owned); ok {
+		owned.SetOwnerID(c.GetUint("userID"))
+	}
+
+	if err := h.repo.Create(c.Request.Context(), item); err != nil {
+		h.handleRepoError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, utils.Response{
+		Code:    http.StatusCreated,
+		Message: "success",
+		Data:    item,
+	})
+}
+
+func (h *CRUDHandler[T, C, P]) Update(c *gin.Context) {
+	item, ok := h.load(c)
+	if !ok {
+		return
+	}
+
+	var req C
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, parseValidationErrors(err))
+		return
+	}
+	h.resource.Assign(item, req)
+
+	if err := h.repo.Update(c.Request.Context(), item); err != nil {
+		h.handleRepoError(c, err)
+		return
+	}
+	utils.Success(c, item)
+}
+
+func (h *CRUDHandler[T, C, P]) PatchItem(c *gin.Context) {
+	item, ok := h.load(c)
+	if !ok {
+		return
+	}
+
+	var req P
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, parseValidationErrors(err))
+		return
+	}
+	h.resource.Patch(item, req)
+
+	if err := h.repo.Update(c.Request.Context(), item); err != nil {
+		h.handleRepoError(c, err)
+		return
+	}
+	utils.Success(c, item)
+}
+
+func (h *CRUDHandler[T, C, P]) Delete(c *gin.Context) {
+	if _, ok := h.load(c); !ok {
+		return
+	}
+
+	if err := h.repo.Delete(c.Request.Context(), h.idParam(c)); err != nil {
+		h.handleRepoError(c, err)
+		return
+	}
+	utils.Success(c, nil)
+}
+
+// load 读取 :id 对应的记录并做归属校验，失败时已写入响应
+func (h *CRUDHandler[T, C, P]) load(c *gin.Context) (*T, bool) {
+	id := h.idParam(c)
+	if id == 0 {
+		utils.Error(c, http.StatusBadRequest, "Invalid "+h.resource.Name+" id")
+		return nil, false
+	}
+
+	item, err := h.repo.Get(c.Request.Context(), id)
+	if err != nil {
+		h.handleRepoError(c, err)
+		return nil, false
+	}
+
+	// 无权访问时同样返回 404，避免泄露其他用户的记录是否存在
+	if owned, ok := any(item).(repository.Owned); ok && !isAdmin(c) {
+		if owned.GetOwnerID() != c.GetUint("userID") {
+			h.handleRepoError(c, repository.ErrNotFound)
+			return nil, false
+		}
+	}
+	return item, true
+}
+
+func (h *CRUDHandler[T, C, P]) idParam(c *gin.Context) uint {
+	return uintParam(c, "id")
+}
+
+func (h *CRUDHandler[T, C, P]) handleRepoError(c *gin.Context, err error) {
+	var dup *repository.DuplicateKeyError
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		utils.HandleError(c, utils.NewAppError(http.StatusNotFound, h.resource.Name+" not found"))
+	case errors.As(err, &dup):
+		utils.HandleError(c, utils.NewConflictError(dup.Field, h.resource.Name+" with the same "+dup.Field+" already exists"))
+	default:
+		utils.HandleError(c, err)
+	}
+}
+
+func (h *CRUDHandler[T, C, P]) owned() bool {
+	_, ok := any(new(T)).(repository.Owned)
+	return ok
+}
+
+// uintParam 解析路径参数，非法时返回 0
+func uintParam(c *gin.Context, name string) uint {
+	id, err := strconv.ParseUint(c.Param(name), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return uint(id)
+}
+
+// isAdmin 全局管理员，或当前组织的 owner/admin。组织内的数据已由租户范围限定，
+// 组织管理员不受归属限制也不会访问到其他组织的记录
+func isAdmin(c *gin.Context) bool {
+	if c.GetString("role") == models.RoleAdmin {
+		return true
+	}
+	switch c.GetString("orgRole") {
+	case models.OrgRoleOwner, models.OrgRoleAdmin:
+		return true
+	}
+	return false
+}
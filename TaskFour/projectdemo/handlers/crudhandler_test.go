@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"projectdemo/models"
+	"projectdemo/repository"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type note struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	OwnerID   uint           `json:"owner_id"`
+	Title     string         `json:"title"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+func (n *note) GetOwnerID() uint   { return n.OwnerID }
+func (n *note) SetOwnerID(id uint) { n.OwnerID = id }
+
+type noteRequest struct {
+	Title   string `json:"title" binding:"required,max=20"`
+	OwnerID uint   `json:"owner_id"`
+}
+
+type notePatch struct {
+	Title *string `json:"title" binding:"omitempty,max=20"`
+}
+
+// newNoteServer 请求头 X-User-ID 和 X-Role 代替 Auth 中间件写入的用户
+func newNoteServer(t *testing.T) *gin.Engine {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "notes.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err := db.AutoMigrate(&note{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	group := r.Group("/api", func(c *gin.Context) {
+		id, _ := strconv.ParseUint(c.GetHeader("X-User-ID"), 10, 64)
+		c.Set("userID", uint(id))
+		c.Set("role", c.GetHeader("X-Role"))
+	})
+	NewCRUDHandler(repository.New[note](db, repository.Options{}), Resource[note, noteRequest, notePatch]{
+		Name:   "Note",
+		Assign: func(n *note, req noteRequest) { n.Title = req.Title },
+		Patch: func(n *note, req notePatch) {
+			if req.Title != nil {
+				n.Title = *req.Title
+			}
+		},
+	}).Register(group, "/notes")
+	return r
+}
+
+type noteResponse struct {
+	Data json.RawMessage `json:"data"`
+}
+
+func doNote(r *gin.Engine, method, path string, userID uint, role string, body interface{}) (int, json.RawMessage) {
+	var buf bytes.Buffer
+	if body != nil {
+		_ = json.NewEncoder(&buf).Encode(body)
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-ID", strconv.FormatUint(uint64(userID), 10))
+	req.Header.Set("X-Role", role)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var resp noteResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp.Data
+}
+
+func TestCRUDHandlerOwnership(t *testing.T) {
+	r := newNoteServer(t)
+	const owner, other, admin = 1, 2, 3
+
+	// 所有者由当前用户决定，不能通过请求体指定
+	code, data := doNote(r, http.MethodPost, "/api/notes", owner, models.RoleUser, noteRequest{Title: "mine", OwnerID: other})
+	var created note
+	if err := json.Unmarshal(data, &created); code != http.StatusCreated || err != nil || created.OwnerID != owner {
+		t.Fatalf("create: expected 201 owned by %d, got %d %s", owner, code, data)
+	}
+	path := "/api/notes/" + strconv.FormatUint(uint64(created.ID), 10)
+	title := "changed"
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		user   uint
+		role   string
+		body   interface{}
+		want   int
+	}{
+		{"other user get", http.MethodGet, path, other, models.RoleUser, nil, http.StatusNotFound},
+		{"other user put", http.MethodPut, path, other, models.RoleUser, noteRequest{Title: "stolen"}, http.StatusNotFound},
+		{"other user patch", http.MethodPatch, path, other, models.RoleUser, notePatch{Title: &title}, http.StatusNotFound},
+		{"other user delete", http.MethodDelete, path, other, models.RoleUser, nil, http.StatusNotFound},
+		{"invalid id", http.MethodGet, "/api/notes/abc", owner, models.RoleUser, nil, http.StatusBadRequest},
+		{"missing", http.MethodGet, "/api/notes/99", owner, models.RoleUser, nil, http.StatusNotFound},
+		{"invalid body", http.MethodPut, path, owner, models.RoleUser, noteRequest{}, http.StatusUnprocessableEntity},
+		{"owner get", http.MethodGet, path, owner, models.RoleUser, nil, http.StatusOK},
+		{"owner patch", http.MethodPatch, path, owner, models.RoleUser, notePatch{Title: &title}, http.StatusOK},
+		{"admin get", http.MethodGet, path, admin, models.RoleAdmin, nil, http.StatusOK},
+	}
+	for _, tc := range cases {
+		if code, _ := doNote(r, tc.method, tc.path, tc.user, tc.role, tc.body); code != tc.want {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.want, code)
+		}
+	}
+
+	var page repository.Page[note]
+	for user, want := range map[uint]int64{owner: 1, other: 0} {
+		_, data := doNote(r, http.MethodGet, "/api/notes", user, models.RoleUser, nil)
+		if err := json.Unmarshal(data, &page); err != nil || page.Total != want {
+			t.Errorf("list as %d: expected %d items, got %s", user, want, data)
+		}
+	}
+	_, data = doNote(r, http.MethodGet, "/api/notes", admin, models.RoleAdmin, nil)
+	if err := json.Unmarshal(data, &page); err != nil || page.Total != 1 || page.Items[0].Title != title {
+		t.Errorf("list as admin: unexpected %s", data)
+	}
+
+	// 软删除后不再可见
+	if code, _ := doNote(r, http.MethodDelete, path, owner, models.RoleUser, nil); code != http.StatusOK {
+		t.Fatalf("delete: expected 200, got %d", code)
+	}
+	if code, _ := doNote(r, http.MethodGet, path, admin, models.RoleAdmin, nil); code != http.StatusNotFound {
+		t.Fatalf("expected deleted note to be gone, got %d", code)
+	}
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+	"projectdemo/models"
+	"projectdemo/services"
+	"projectdemo/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type JobHandler struct {
+	jobService *services.JobService
+}
+
+func NewJobHandler(jobService *services.JobService) *JobHandler {
+	return &JobHandler{jobService: jobService}
+}
+
+func (h *JobHandler) List(c *gin.Context) {
+	var q models.JobQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		utils.ValidationError(c, parseValidationErrors(err))
+		return
+	}
+
+	list, err := h.jobService.List(c.Request.Context(), q)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, list)
+}
+
+func (h *JobHandler) Stats(c *gin.Context) {
+	stats, err := h.jobService.Stats(c.Request.Context())
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, stats)
+}
+
+func (h *JobHandler) Get(c *gin.Context) {
+	id := uintParam(c, "id")
+	if id == 0 {
+		utils.Error(c, http.StatusBadRequest, "Invalid job id")
+		return
+	}
+
+	job, err := h.jobService.Get(c.Request.Context(), id)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, job)
+}
+
+func (h *JobHandler) Retry(c *gin.Context) {
+	id := uintParam(c, "id")
+	if id == 0 {
+		utils.Error(c, http.StatusBadRequest, "Invalid job id")
+		return
+	}
+
+	job, err := h.jobService.Retry(c.Request.Context(), id)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, job)
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"projectdemo/models"
+	"projectdemo/services"
+	"projectdemo/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OrderHandler struct {
+	orderService     *services.OrderService
+	inventoryService *services.InventoryService
+}
+
+func NewOrderHandler(orderService *services.OrderService, inventoryService *services.InventoryService) *OrderHandler {
+	return &OrderHandler{
+		orderService:     orderService,
+		inventoryService: inventoryService,
+	}
+}
+
+func (h *OrderHandler) PlaceOrder(c *gin.Context) {
+	order, err := h.orderService.PlaceOrder(c.Request.Context(), c.GetUint("userID"))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, utils.Response{
+		Code:    http.StatusCreated,
+		Message: "success",
+		Data:    order,
+	})
+}
+
+func (h *OrderHandler) ListOrders(c *gin.Context) {
+	orders, err := h.orderService.ListOrders(c.Request.Context(), c.GetUint("userID"))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, orders)
+}
+
+func (h *OrderHandler) GetOrder(c *gin.Context) {
+	id := uintParam(c, "id")
+	if id == 0 {
+		utils.Error(c, http.StatusBadRequest, "Invalid order id")
+		return
+	}
+
+	order, err := h.orderService.GetOrder(c.Request.Context(), id, h.scope(c))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, order)
+}
+
+func (h *OrderHandler) Pay(c *gin.Context) {
+	h.transit(c, func(ctx context.Context, id uint) (*models.Order, error) {
+		return h.orderService.Pay(ctx, id, c.GetUint("userID"))
+	})
+}
+
+func (h *OrderHandler) Cancel(c *gin.Context) {
+	h.transit(c, func(ctx context.Context, id uint) (*models.Order, error) {
+		return h.orderService.Cancel(ctx, id, h.scope(c))
+	})
+}
+
+// Ship 仅管理员可用
+func (h *OrderHandler) Ship(c *gin.Context) {
+	h.transit(c, h.orderService.Ship)
+}
+
+func (h *OrderHandler) GetInventory(c *gin.Context) {
+	productID := uintParam(c, "id")
+	if productID == 0 {
+		utils.Error(c, http.StatusBadRequest, "Invalid product id")
+		return
+	}
+
+	inv, err := h.inventoryService.Get(c.Request.Context(), productID)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, inv)
+}
+
+// SetInventory 商品所有者或管理员设置库存
+func (h *OrderHandler) SetInventory(c *gin.Context) {
+	productID := uintParam(c, "id")
+	if productID == 0 {
+		utils.Error(c, http.StatusBadRequest, "Invalid product id")
+		return
+	}
+
+	var req models.SetInventoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, parseValidationErrors(err))
+		return
+	}
+
+	inv, err := h.inventoryService.SetStock(c.Request.Context(), productID, req.Quantity, h.scope(c))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, inv)
+}
+
+func (h *OrderHandler) transit(c *gin.Context, fn func(ctx context.Context, id uint) (*models.Order, error)) {
+	id := uintParam(c, "id")
+	if id == 0 {
+		utils.Error(c, http.StatusBadRequest, "Invalid order id")
+		return
+	}
+
+	order, err := fn(c.Request.Context(), id)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, order)
+}
+
+// scope 管理员不受归属限制，返回 0
+func (h *OrderHandler) scope(c *gin.Context) uint {
+	if isAdmin(c) {
+		return 0
+	}
+	return c.GetUint("userID")
+}
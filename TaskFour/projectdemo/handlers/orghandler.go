@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"net/http"
+	"projectdemo/models"
+	"projectdemo/services"
+	"projectdemo/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OrgHandler struct {
+	orgService *services.OrgService
+}
+
+func NewOrgHandler(orgService *services.OrgService) *OrgHandler {
+	return &OrgHandler{orgService: orgService}
+}
+
+func (h *OrgHandler) Create(c *gin.Context) {
+	var req models.CreateOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, parseValidationErrors(err))
+		return
+	}
+
+	org, err := h.orgService.Create(c.Request.Context(), c.GetUint("userID"), req)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, utils.Response{
+		Code:    http.StatusCreated,
+		Message: "success",
+		Data:    org,
+	})
+}
+
+func (h *OrgHandler) List(c *gin.Context) {
+	orgs, err := h.orgService.ListForUser(c.Request.Context(), c.GetUint("userID"))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, orgs)
+}
+
+func (h *OrgHandler) ListMembers(c *gin.Context) {
+	orgID, ok := h.orgParam(c)
+	if !ok {
+		return
+	}
+
+	members, err := h.orgService.ListMembers(c.Request.Context(), orgID, c.GetUint("userID"))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, members)
+}
+
+func (h *OrgHandler) RemoveMember(c *gin.Context) {
+	orgID, ok := h.orgParam(c)
+	if !ok {
+		return
+	}
+	memberID := uintParam(c, "user_id")
+	if memberID == 0 {
+		utils.Error(c, http.StatusBadRequest, "Invalid user id")
+		return
+	}
+
+	if err := h.orgService.RemoveMember(c.Request.Context(), orgID, c.GetUint("userID"), memberID); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, nil)
+}
+
+// CreateInvitation 响应中的 token 只返回这一次，需要通过其他渠道（例如邮件）发给受邀人
+func (h *OrgHandler) CreateInvitation(c *gin.Context) {
+	orgID, ok := h.orgParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, parseValidationErrors(err))
+		return
+	}
+
+	inv, err := h.orgService.CreateInvitation(c.Request.Context(), orgID, c.GetUint("userID"), req)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, utils.Response{
+		Code:    http.StatusCreated,
+		Message: "success",
+		Data:    inv,
+	})
+}
+
+func (h *OrgHandler) ListInvitations(c *gin.Context) {
+	orgID, ok := h.orgParam(c)
+	if !ok {
+		return
+	}
+
+	invitations, err := h.orgService.ListInvitations(c.Request.Context(), orgID, c.GetUint("userID"))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, invitations)
+}
+
+func (h *OrgHandler) RevokeInvitation(c *gin.Context) {
+	orgID, ok := h.orgParam(c)
+	if !ok {
+		return
+	}
+	invitationID := uintParam(c, "invitation_id")
+	if invitationID == 0 {
+		utils.Error(c, http.StatusBadRequest, "Invalid invitation id")
+		return
+	}
+
+	if err := h.orgService.RevokeInvitation(c.Request.Context(), orgID, c.GetUint("userID"), invitationID); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, nil)
+}
+
+func (h *OrgHandler) AcceptInvitation(c *gin.Context) {
+	var req models.AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, parseValidationErrors(err))
+		return
+	}
+
+	membership, err := h.orgService.AcceptInvitation(c.Request.Context(), c.GetUint("userID"), req.Token)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, membership)
+}
+
+func (h *OrgHandler) orgParam(c *gin.Context) (uint, bool) {
+	id := uintParam(c, "id")
+	if id == 0 {
+		utils.Error(c, http.StatusBadRequest, "Invalid organization id")
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"projectdemo/models"
+	"projectdemo/repository"
+)
+
+type ProductHandler = CRUDHandler[models.Product, models.CreateProductRequest, models.PatchProductRequest]
+
+func NewProductHandler(repo *repository.Repository[models.Product]) *ProductHandler {
+	return NewCRUDHandler(repo, Resource[models.Product, models.CreateProductRequest, models.PatchProductRequest]{
+		Name: "Product",
+		Assign: func(p *models.Product, req models.CreateProductRequest) {
+			p.SKU = req.SKU
+			p.Name = req.Name
+			p.Description = req.Description
+			p.Price = req.Price
+			p.Status = req.Status
+			if p.Status == "" {
+				p.Status = models.ProductStatusDraft
+			}
+		},
+		Patch: func(p *models.Product, req models.PatchProductRequest) {
+			if req.Name != nil {
+				p.Name = *req.Name
+			}
+			if req.Description != nil {
+				p.Description = *req.Description
+			}
+			if req.Price != nil {
+				p.Price = *req.Price
+			}
+			if req.Status != nil {
+				p.Status = *req.Status
+			}
+		},
+	})
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"projectdemo/services"
+	"projectdemo/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ScheduleHandler struct {
+	scheduleService *services.ScheduleService
+}
+
+func NewScheduleHandler(scheduleService *services.ScheduleService) *ScheduleHandler {
+	return &ScheduleHandler{scheduleService: scheduleService}
+}
+
+func (h *ScheduleHandler) List(c *gin.Context) {
+	list, err := h.scheduleService.List(c.Request.Context())
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, list)
+}
+
+func (h *ScheduleHandler) Trigger(c *gin.Context) {
+	task, err := h.scheduleService.Trigger(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, task)
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 	"projectdemo/services"
+	"strconv"
 
 	"projectdemo/models"
 	"projectdemo/utils"
@@ -11,14 +12,18 @@ import (
 )
 
 type UserHandler struct {
-	userService *services.UserService
-	jwtSecret   []byte
+	userService   services.UserService
+	avatarService *services.AvatarService
+	orgService    *services.OrgService
+	jwtSecret     []byte
 }
 
-func NewUserHandler(userService *services.UserService, jwtSecret []byte) *UserHandler {
+func NewUserHandler(userService services.UserService, avatarService *services.AvatarService, orgService *services.OrgService, jwtSecret []byte) *UserHandler {
 	return &UserHandler{
-		userService: userService,
-		jwtSecret:   jwtSecret,
+		userService:   userService,
+		avatarService: avatarService,
+		orgService:    orgService,
+		jwtSecret:     jwtSecret,
 	}
 }
 
@@ -29,18 +34,13 @@ func (h *UserHandler) Register(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.CreateUser(req)
+	user, err := h.userService.CreateUser(c.Request.Context(), req)
 	if err != nil {
 		utils.HandleError(c, err)
 		return
 	}
 
-	utils.Success(c, models.UserResponse{
-		ID:        user.ID,
-		Username:  user.Username,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt,
-	})
+	utils.Success(c, h.toResponse(user))
 }
 
 func (h *UserHandler) Login(c *gin.Context) {
@@ -50,26 +50,70 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.Authenticate(req.Username, req.Password)
+	user, err := h.userService.Authenticate(c.Request.Context(), req.Username, req.Password)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	// 登录后进入个人空间，需要时再切换组织
+	h.issueToken(c, user, 0)
+}
+
+// RefreshToken 用未过期的 token 换取新 token，角色等信息以数据库中的当前值为准，
+// 活动组织保持不变（Tenant 中间件已校验仍是成员）
+func (h *UserHandler) RefreshToken(c *gin.Context) {
+	user, err := h.userService.LoadUser(c.Request.Context(), c.GetUint("userID"))
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	if user.IsDisabled() {
+		utils.Error(c, http.StatusForbidden, "Account disabled")
+		return
+	}
+
+	h.issueToken(c, user, c.GetUint("orgID"))
+}
+
+// SwitchOrganization 签发以指定组织为活动组织的新 token，org_id 为 0 时切换回个人空间
+func (h *UserHandler) SwitchOrganization(c *gin.Context) {
+	var req models.SwitchOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, parseValidationErrors(err))
+		return
+	}
+
+	user, err := h.userService.LoadUser(c.Request.Context(), c.GetUint("userID"))
 	if err != nil {
 		utils.HandleError(c, err)
 		return
 	}
+	if user.IsDisabled() {
+		utils.Error(c, http.StatusForbidden, "Account disabled")
+		return
+	}
+	if req.OrgID != 0 {
+		if _, err := h.orgService.MemberRole(c.Request.Context(), req.OrgID, user.ID); err != nil {
+			utils.HandleError(c, err)
+			return
+		}
+	}
+
+	h.issueToken(c, user, req.OrgID)
+}
 
-	token, err := utils.GenerateToken(h.jwtSecret, user.ID, user.Username)
+func (h *UserHandler) issueToken(c *gin.Context, user *models.User, orgID uint) {
+	token, err := utils.GenerateToken(h.jwtSecret, user.ID, user.Username, user.Role, orgID)
 	if err != nil {
 		utils.HandleError(c, err)
 		return
 	}
 
-	utils.Success(c, gin.H{
-		"token": token,
-		"user": models.UserResponse{
-			ID:        user.ID,
-			Username:  user.Username,
-			Email:     user.Email,
-			CreatedAt: user.CreatedAt,
-		},
+	utils.Success(c, models.LoginResponse{
+		Token: token,
+		User:  h.toResponse(user),
+		OrgID: orgID,
 	})
 }
 
@@ -80,18 +124,13 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.GetUserByID(userID.(uint))
+	user, err := h.userService.GetUserByID(c.Request.Context(), userID.(uint))
 	if err != nil {
 		utils.HandleError(c, err)
 		return
 	}
 
-	utils.Success(c, models.UserResponse{
-		ID:        user.ID,
-		Username:  user.Username,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt,
-	})
+	utils.Success(c, h.toResponse(user))
 }
 
 func (h *UserHandler) UpdateProfile(c *gin.Context) {
@@ -107,18 +146,87 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.UpdateUser(userID.(uint), req)
+	user, err := h.userService.UpdateUser(c.Request.Context(), userID.(uint), req)
 	if err != nil {
 		utils.HandleError(c, err)
 		return
 	}
 
-	utils.Success(c, models.UserResponse{
-		ID:        user.ID,
-		Username:  user.Username,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt,
-	})
+	utils.Success(c, h.toResponse(user))
+}
+
+func (h *UserHandler) UploadAvatar(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	file, err := c.FormFile("avatar")
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "avatar file is required")
+		return
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	defer src.Close()
+
+	user, err := h.avatarService.Upload(c.Request.Context(), userID.(uint), src)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.Success(c, h.toResponse(user))
+}
+
+func (h *UserHandler) DeleteProfile(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if err := h.userService.DeleteUser(c.Request.Context(), userID.(uint)); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.Success(c, nil)
+}
+
+// DeleteUser 管理员删除指定用户
+func (h *UserHandler) DeleteUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "Invalid user id")
+		return
+	}
+
+	if err := h.userService.DeleteUser(c.Request.Context(), uint(id)); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+
+	utils.Success(c, nil)
+}
+
+func (h *UserHandler) toResponse(user *models.User) models.UserResponse {
+	return models.UserResponse{
+		ID:          user.ID,
+		Username:    user.Username,
+		Email:       user.Email,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		Locale:      user.Locale,
+		Timezone:    user.Timezone,
+		Avatars:     h.avatarService.URLs(user.AvatarKey),
+		CreatedAt:   user.CreatedAt,
+	}
 }
 
 func parseValidationErrors(err error) map[string]string {
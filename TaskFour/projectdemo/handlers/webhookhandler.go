@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"net/http"
+	"projectdemo/models"
+	"projectdemo/services"
+	"projectdemo/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	webhookService *services.WebhookService
+}
+
+func NewWebhookHandler(webhookService *services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{webhookService: webhookService}
+}
+
+func (h *WebhookHandler) Create(c *gin.Context) {
+	var req models.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(c, parseValidationErrors(err))
+		return
+	}
+
+	sub, err := h.webhookService.Create(c.Request.Context(), req)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, utils.Response{
+		Code:    http.StatusCreated,
+		Message: "success",
+		Data:    sub,
+	})
+}
+
+func (h *WebhookHandler) List(c *gin.Context) {
+	subs, err := h.webhookService.List(c.Request.Context())
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, subs)
+}
+
+func (h *WebhookHandler) Delete(c *gin.Context) {
+	id := uintParam(c, "id")
+	if id == 0 {
+		utils.Error(c, http.StatusBadRequest, "Invalid webhook id")
+		return
+	}
+
+	if err := h.webhookService.Delete(c.Request.Context(), id); err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, nil)
+}
+
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	id := uintParam(c, "id")
+	if id == 0 {
+		utils.Error(c, http.StatusBadRequest, "Invalid webhook id")
+		return
+	}
+	var q models.WebhookDeliveryQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		utils.ValidationError(c, parseValidationErrors(err))
+		return
+	}
+
+	deliveries, err := h.webhookService.ListDeliveries(c.Request.Context(), id, q)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, deliveries)
+}
+
+// Redeliver 把已成功或失败的投递重新放回队列，由后台 dispatcher 发送
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	id := uintParam(c, "id")
+	if id == 0 {
+		utils.Error(c, http.StatusBadRequest, "Invalid delivery id")
+		return
+	}
+
+	delivery, err := h.webhookService.Redeliver(c.Request.Context(), id)
+	if err != nil {
+		utils.HandleError(c, err)
+		return
+	}
+	utils.Success(c, delivery)
+}
@@ -0,0 +1,87 @@
+package imaging
+
+import (
+	"image"
+	"image/draw"
+)
+
+// Resize 将图片缩放到 width x height。缩小时对源区域求平均（box filter），放大时退化为最近邻
+func Resize(src image.Image, width, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	if width <= 0 || height <= 0 {
+		return dst
+	}
+
+	// 统一转换为 RGBA（预乘 alpha），方便直接按字节平均
+	sb := src.Bounds()
+	rgba, ok := src.(*image.RGBA)
+	if !ok || sb.Min != (image.Point{}) {
+		rgba = image.NewRGBA(image.Rect(0, 0, sb.Dx(), sb.Dy()))
+		draw.Draw(rgba, rgba.Bounds(), src, sb.Min, draw.Src)
+	}
+	sw, sh := rgba.Bounds().Dx(), rgba.Bounds().Dy()
+	if sw == 0 || sh == 0 {
+		return dst
+	}
+
+	for y := 0; y < height; y++ {
+		y0 := y * sh / height
+		y1 := (y + 1) * sh / height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0 := x * sw / width
+			x1 := (x + 1) * sw / width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				off := rgba.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint32(rgba.Pix[off])
+					g += uint32(rgba.Pix[off+1])
+					b += uint32(rgba.Pix[off+2])
+					a += uint32(rgba.Pix[off+3])
+					off += 4
+					n++
+				}
+			}
+
+			d := dst.PixOffset(x, y)
+			dst.Pix[d] = uint8(r / n)
+			dst.Pix[d+1] = uint8(g / n)
+			dst.Pix[d+2] = uint8(b / n)
+			dst.Pix[d+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// Fill 先按目标宽高比居中裁剪，再缩放到 width x height，适合头像等固定尺寸的场景
+func Fill(src image.Image, width, height int) *image.RGBA {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	if sw == 0 || sh == 0 || width <= 0 || height <= 0 {
+		return image.NewRGBA(image.Rect(0, 0, width, height))
+	}
+
+	crop := b
+	if sw*height > sh*width {
+		// 源图更宽，裁掉左右两侧
+		cw := sh * width / height
+		crop.Min.X = b.Min.X + (sw-cw)/2
+		crop.Max.X = crop.Min.X + cw
+	} else {
+		// 源图更高，裁掉上下两侧
+		ch := sw * height / width
+		crop.Min.Y = b.Min.Y + (sh-ch)/2
+		crop.Max.Y = crop.Min.Y + ch
+	}
+
+	cropped := image.NewRGBA(image.Rect(0, 0, crop.Dx(), crop.Dy()))
+	draw.Draw(cropped, cropped.Bounds(), src, crop.Min, draw.Src)
+	return Resize(cropped, width, height)
+}
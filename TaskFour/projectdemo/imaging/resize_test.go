@@ -0,0 +1,44 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestFillCropsToSquare(t *testing.T) {
+	// 左半边红色、右半边蓝色、中间一条绿色，居中裁剪后应只剩绿色与两侧少量红蓝
+	src := image.NewRGBA(image.Rect(0, 0, 300, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 300; x++ {
+			c := color.RGBA{G: 255, A: 255}
+			if x < 100 {
+				c = color.RGBA{R: 255, A: 255}
+			} else if x >= 200 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			src.Set(x, y, c)
+		}
+	}
+
+	dst := Fill(src, 10, 10)
+	if got := dst.Bounds().Size(); got != image.Pt(10, 10) {
+		t.Fatalf("expected 10x10, got %v", got)
+	}
+	for x := 0; x < 10; x++ {
+		if c := dst.RGBAAt(x, 5); c.G != 255 || c.R != 0 || c.B != 0 {
+			t.Fatalf("pixel %d: expected pure green, got %v", x, c)
+		}
+	}
+}
+
+func TestResizeAveragesPixels(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	src.Set(0, 0, color.RGBA{R: 200, A: 255})
+	src.Set(1, 0, color.RGBA{R: 100, A: 255})
+
+	dst := Resize(src, 1, 1)
+	if c := dst.RGBAAt(0, 0); c.R != 150 || c.A != 255 {
+		t.Fatalf("expected averaged red 150, got %v", c)
+	}
+}
@@ -0,0 +1,66 @@
+// Package background 后台任务队列、定时任务和 webhook 投递共用的辅助函数：
+// 租约、执行处理函数、记录错误和失败退避
+package background
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// LeaseMargin 租约在任务超时之后再保留的时间，超时的任务写回结果前不会被其他 worker 或副本接手
+	LeaseMargin = 30 * time.Second
+	// MaxErrorLength 写入数据库的错误信息的最大字节数
+	MaxErrorLength = 1000
+)
+
+// Call 执行 fn 并把 panic 转换为错误
+func Call(ctx context.Context, fn func(context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn(ctx)
+}
+
+// ErrorMessage 返回用于保存的错误信息，最多 MaxErrorLength 字节
+func ErrorMessage(err error) string {
+	return Truncate(err.Error(), MaxErrorLength)
+}
+
+// Truncate 把 s 截断到最多 n 字节，截断位置退回到字符边界，不会留下半个 UTF-8 字符
+func Truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// Backoff 第 n 次失败后的等待时间：base*2^(n-1)，不超过 max
+func Backoff(base, max time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		return max
+	}
+	return delay
+}
+
+// NewOwnerID 生成租约持有者标识：主机名、进程号和随机后缀，同一主机上的多个进程也不会重复
+func NewOwnerID() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("%s:%d:%s", host, os.Getpid(), hex.EncodeToString(b))
+}
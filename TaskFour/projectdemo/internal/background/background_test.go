@@ -0,0 +1,49 @@
+package background
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestBackoff(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 10: 10 * time.Second} {
+		if got := Backoff(time.Second, 10*time.Second, attempts); got != want {
+			t.Fatalf("attempts %d: expected %s, got %s", attempts, want, got)
+		}
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	cases := []struct {
+		name string
+		msg  string
+		want int
+	}{
+		{"short", "boom", 4},
+		{"exact", strings.Repeat("a", MaxErrorLength), MaxErrorLength},
+		{"ascii", strings.Repeat("a", MaxErrorLength+10), MaxErrorLength},
+		// 每个汉字 3 字节，1000 不是 3 的倍数，截断时退回到上一个完整字符
+		{"multibyte", strings.Repeat("错", 400), 999},
+	}
+	for _, tc := range cases {
+		got := ErrorMessage(errors.New(tc.msg))
+		if len(got) != tc.want || !utf8.ValidString(got) || !strings.HasPrefix(tc.msg, got) {
+			t.Errorf("%s: expected a valid %d byte prefix, got %d bytes", tc.name, tc.want, len(got))
+		}
+	}
+}
+
+func TestCallRecoversPanic(t *testing.T) {
+	err := Call(context.Background(), func(context.Context) error { panic("boom") })
+	if err == nil || err.Error() != "panic: boom" {
+		t.Fatalf("expected panic to become an error, got %v", err)
+	}
+	want := errors.New("failed")
+	if err := Call(context.Background(), func(context.Context) error { return want }); err != want {
+		t.Fatalf("expected %v, got %v", want, err)
+	}
+}
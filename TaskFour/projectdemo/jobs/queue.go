@@ -0,0 +1,295 @@
+// Package jobs 基于数据库表的后台任务队列。任务按类型名注册处理函数，多个 worker（可以在不同进程中）
+// 通过条件更新认领任务并持有租约，失败后按指数退避重试，次数用完后进入 dead 状态等待人工处理
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"projectdemo/config"
+	"projectdemo/internal/background"
+	"projectdemo/models"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultMaxAttempts = 5
+	claimRetries       = 3
+)
+
+// EnqueueOption 调整新任务的执行时间和重试次数
+type EnqueueOption func(*models.Job)
+
+// Delay 延迟 d 之后执行
+func Delay(d time.Duration) EnqueueOption {
+	return func(j *models.Job) {
+		j.RunAt = j.RunAt.Add(d)
+	}
+}
+
+// At 在指定时间之后执行
+func At(t time.Time) EnqueueOption {
+	return func(j *models.Job) {
+		j.RunAt = t
+	}
+}
+
+// MaxAttempts 最多执行 n 次（包括第一次）
+func MaxAttempts(n int) EnqueueOption {
+	return func(j *models.Job) {
+		if n > 0 {
+			j.MaxAttempts = n
+		}
+	}
+}
+
+// Enqueue 写入一个任务。db 可以是事务，任务与业务数据一起提交或回滚
+func Enqueue[T any](ctx context.Context, db *gorm.DB, jobType string, payload T, opts ...EnqueueOption) (*models.Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("encode %s payload: %w", jobType, err)
+	}
+	job := models.Job{
+		Type:        jobType,
+		Payload:     string(data),
+		Status:      models.JobStatusQueued,
+		RunAt:       time.Now(),
+		MaxAttempts: DefaultMaxAttempts,
+	}
+	for _, opt := range opts {
+		opt(&job)
+	}
+	if err := db.WithContext(ctx).Create(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent 标记不可重试的错误，任务直接进入 dead 状态
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+type handlerFunc func(ctx context.Context, payload []byte) error
+
+// Queue 零值不可用，使用 NewQueue 创建
+type Queue struct {
+	db       *gorm.DB
+	cfg      config.JobsConfig
+	workerID string
+	now      func() time.Time
+
+	mu       sync.RWMutex
+	handlers map[string]handlerFunc
+}
+
+func NewQueue(db *gorm.DB, cfg config.JobsConfig) *Queue {
+	return &Queue{
+		db:       db,
+		cfg:      cfg,
+		workerID: background.NewOwnerID(),
+		now:      time.Now,
+		handlers: make(map[string]handlerFunc),
+	}
+}
+
+// Register 注册 jobType 的处理函数，payload 按 JSON 解码为 T。
+// 只有注册过的类型才会被这个队列认领，未注册的任务留给其他进程处理
+func Register[T any](q *Queue, jobType string, handler func(ctx context.Context, payload T) error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[jobType] = func(ctx context.Context, data []byte) error {
+		var payload T
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return Permanent(fmt.Errorf("decode payload: %w", err))
+		}
+		return handler(ctx, payload)
+	}
+}
+
+func (q *Queue) types() []string {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	types := make([]string, 0, len(q.handlers))
+	for t := range q.handlers {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+func (q *Queue) handler(jobType string) handlerFunc {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.handlers[jobType]
+}
+
+// Run 启动 cfg.Workers 个 worker，ctx 取消后等待正在执行的任务完成再返回
+func (q *Queue) Run(ctx context.Context) {
+	workers := q.cfg.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (q *Queue) work(ctx context.Context) {
+	for ctx.Err() == nil {
+		worked, err := q.RunOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("jobs: %v", err)
+		}
+		if worked {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(q.cfg.PollInterval):
+		}
+	}
+}
+
+// RunOnce 认领并执行一个到期的任务，没有可执行的任务时返回 false
+func (q *Queue) RunOnce(ctx context.Context) (bool, error) {
+	job, err := q.claim(ctx)
+	if err != nil || job == nil {
+		return false, err
+	}
+	return true, q.process(ctx, job)
+}
+
+// claim 选出一个到期的任务（或租约已过期的 running 任务），再用带原状态条件的更新认领它；
+// 更新影响 0 行说明被其他 worker 抢先，重新选择
+func (q *Queue) claim(ctx context.Context) (*models.Job, error) {
+	types := q.types()
+	if len(types) == 0 {
+		return nil, nil
+	}
+
+	for i := 0; i < claimRetries; i++ {
+		now := q.now()
+		var job models.Job
+		err := q.db.WithContext(ctx).
+			Where("type IN ?", types).
+			Where("((status = ? AND run_at <= ?) OR (status = ? AND locked_until < ?))",
+				models.JobStatusQueued, now, models.JobStatusRunning, now).
+			Order("run_at ASC, id ASC").
+			Take(&job).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		lockedUntil := now.Add(q.cfg.Timeout + background.LeaseMargin)
+		result := q.db.WithContext(ctx).Model(&models.Job{}).
+			Where("id = ? AND status = ? AND attempts = ?", job.ID, job.Status, job.Attempts).
+			Updates(map[string]interface{}{
+				"status":       models.JobStatusRunning,
+				"attempts":     job.Attempts + 1,
+				"locked_by":    q.workerID,
+				"locked_until": lockedUntil,
+			})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			job.Status = models.JobStatusRunning
+			job.Attempts++
+			job.LockedBy = q.workerID
+			job.LockedUntil = &lockedUntil
+			return &job, nil
+		}
+	}
+	return nil, nil
+}
+
+func (q *Queue) process(ctx context.Context, job *models.Job) error {
+	// 进程退出时让正在执行的任务完成，结果仍然需要写回数据库
+	ctx = context.WithoutCancel(ctx)
+
+	// 最后一次执行时 worker 退出，租约过期后被重新认领
+	if job.Attempts > job.MaxAttempts {
+		return q.finish(ctx, job, models.JobStatusDead, errors.New("lease expired on the final attempt"))
+	}
+
+	handler := q.handler(job.Type)
+	if handler == nil {
+		return q.finish(ctx, job, models.JobStatusDead, fmt.Errorf("no handler registered for %q", job.Type))
+	}
+	runCtx, cancel := context.WithTimeout(ctx, q.cfg.Timeout)
+	err := background.Call(runCtx, func(ctx context.Context) error {
+		return handler(ctx, []byte(job.Payload))
+	})
+	cancel()
+
+	var permanent *permanentError
+	switch {
+	case err == nil:
+		return q.finish(ctx, job, models.JobStatusSucceeded, nil)
+	case errors.As(err, &permanent), job.Attempts >= job.MaxAttempts:
+		return q.finish(ctx, job, models.JobStatusDead, err)
+	default:
+		return q.finish(ctx, job, models.JobStatusQueued, err)
+	}
+}
+
+// finish 写回执行结果。条件中带上 locked_by 和 attempts，租约过期后被其他 worker 接手的任务不会被覆盖
+func (q *Queue) finish(ctx context.Context, job *models.Job, status string, jobErr error) error {
+	now := q.now()
+	updates := map[string]interface{}{
+		"status":       status,
+		"locked_by":    "",
+		"locked_until": nil,
+		"last_error":   "",
+	}
+	if jobErr != nil {
+		updates["last_error"] = background.ErrorMessage(jobErr)
+	}
+	switch status {
+	case models.JobStatusQueued:
+		updates["run_at"] = now.Add(background.Backoff(q.cfg.BaseDelay, q.cfg.MaxDelay, job.Attempts))
+	default:
+		updates["finished_at"] = now
+	}
+
+	result := q.db.WithContext(ctx).Model(&models.Job{}).
+		Where("id = ? AND locked_by = ? AND attempts = ?", job.ID, q.workerID, job.Attempts).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("job %d: lease lost before the result was recorded", job.ID)
+	}
+	if status == models.JobStatusDead {
+		log.Printf("jobs: job %d (%s) is dead after %d attempts: %v", job.ID, job.Type, job.Attempts, jobErr)
+	}
+	return nil
+}
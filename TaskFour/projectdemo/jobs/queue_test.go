@@ -0,0 +1,301 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"projectdemo/config"
+	"projectdemo/internal/background"
+	"projectdemo/models"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var testConfig = config.JobsConfig{
+	Workers:      4,
+	PollInterval: 5 * time.Millisecond,
+	Timeout:      time.Second,
+	BaseDelay:    time.Minute,
+	MaxDelay:     time.Hour,
+}
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := filepath.Join(t.TempDir(), "jobs.db") + "?_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("get generic db: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = sqlDB.Close()
+	})
+	if err := db.AutoMigrate(&models.Job{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+// testClock 可以手动推进的时钟，用于验证延迟执行和退避
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newTestQueue(t *testing.T, db *gorm.DB) (*Queue, *testClock) {
+	t.Helper()
+	// Enqueue 使用真实时间，时钟稍微领先，刚写入的任务才算到期
+	clock := &testClock{now: time.Now().Add(time.Second)}
+	q := NewQueue(db, testConfig)
+	q.now = clock.Now
+	return q, clock
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func mustEnqueue[T any](t *testing.T, db *gorm.DB, jobType string, payload T, opts ...EnqueueOption) *models.Job {
+	t.Helper()
+	job, err := Enqueue(context.Background(), db, jobType, payload, opts...)
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	return job
+}
+
+func runOnce(t *testing.T, q *Queue, want bool) {
+	t.Helper()
+	worked, err := q.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("run once: %v", err)
+	}
+	if worked != want {
+		t.Fatalf("expected worked=%v, got %v", want, worked)
+	}
+}
+
+func reload(t *testing.T, db *gorm.DB, id uint) models.Job {
+	t.Helper()
+	var job models.Job
+	if err := db.First(&job, id).Error; err != nil {
+		t.Fatalf("reload job %d: %v", id, err)
+	}
+	return job
+}
+
+type welcomeEmail struct {
+	UserID uint   `json:"user_id"`
+	Email  string `json:"email"`
+}
+
+func TestQueueRunsTypedHandler(t *testing.T) {
+	db := newTestDB(t)
+	q, _ := newTestQueue(t, db)
+
+	var got []welcomeEmail
+	Register(q, "email.welcome", func(_ context.Context, p welcomeEmail) error {
+		got = append(got, p)
+		return nil
+	})
+	job := mustEnqueue(t, db, "email.welcome", welcomeEmail{UserID: 7, Email: "a@example.com"})
+	// 没有注册处理函数的类型不会被认领
+	other := mustEnqueue(t, db, "report.build", struct{}{})
+
+	runOnce(t, q, true)
+	runOnce(t, q, false)
+
+	if len(got) != 1 || got[0].UserID != 7 || got[0].Email != "a@example.com" {
+		t.Fatalf("unexpected payloads: %+v", got)
+	}
+	done := reload(t, db, job.ID)
+	if done.Status != models.JobStatusSucceeded || done.Attempts != 1 || done.FinishedAt == nil || done.LockedBy != "" {
+		t.Fatalf("unexpected finished job: %+v", done)
+	}
+	if status := reload(t, db, other.ID).Status; status != models.JobStatusQueued {
+		t.Fatalf("expected unregistered job to stay queued, got %s", status)
+	}
+}
+
+func TestQueueRetriesWithBackoffThenDeadLetters(t *testing.T) {
+	db := newTestDB(t)
+	q, clock := newTestQueue(t, db)
+	Register(q, "flaky", func(context.Context, struct{}) error { return errors.New("smtp timeout") })
+	job := mustEnqueue(t, db, "flaky", struct{}{}, MaxAttempts(3))
+
+	for attempt, delay := range []time.Duration{time.Minute, 2 * time.Minute} {
+		runOnce(t, q, true)
+		j := reload(t, db, job.ID)
+		if j.Status != models.JobStatusQueued || j.Attempts != attempt+1 || j.LastError != "smtp timeout" {
+			t.Fatalf("attempt %d: unexpected job %+v", attempt+1, j)
+		}
+		if got := j.RunAt.Sub(clock.Now()); got != delay {
+			t.Fatalf("attempt %d: expected backoff %s, got %s", attempt+1, delay, got)
+		}
+		// 退避时间未到，不会被执行
+		runOnce(t, q, false)
+		clock.Advance(delay)
+	}
+
+	runOnce(t, q, true)
+	if j := reload(t, db, job.ID); j.Status != models.JobStatusDead || j.Attempts != 3 || j.FinishedAt == nil {
+		t.Fatalf("expected dead job after 3 attempts, got %+v", j)
+	}
+	runOnce(t, q, false)
+}
+
+func TestQueuePermanentErrorsAndPanics(t *testing.T) {
+	db := newTestDB(t)
+	q, _ := newTestQueue(t, db)
+	Register(q, "invalid", func(context.Context, struct{}) error { return Permanent(errors.New("user deleted")) })
+	Register(q, "panicky", func(context.Context, struct{}) error { panic("nil map") })
+	Register(q, "typed", func(context.Context, welcomeEmail) error { return nil })
+
+	invalid := mustEnqueue(t, db, "invalid", struct{}{})
+	panicky := mustEnqueue(t, db, "panicky", struct{}{})
+	// payload 无法解码为处理函数的类型
+	undecodable := mustEnqueue(t, db, "typed", "not an object")
+	for i := 0; i < 3; i++ {
+		runOnce(t, q, true)
+	}
+
+	if j := reload(t, db, invalid.ID); j.Status != models.JobStatusDead || j.Attempts != 1 {
+		t.Fatalf("expected permanent error to dead-letter immediately, got %+v", j)
+	}
+	if j := reload(t, db, panicky.ID); j.Status != models.JobStatusQueued || !strings.Contains(j.LastError, "panic: nil map") {
+		t.Fatalf("expected panic to be retried, got %+v", j)
+	}
+	if j := reload(t, db, undecodable.ID); j.Status != models.JobStatusDead || !strings.Contains(j.LastError, "decode payload") {
+		t.Fatalf("expected undecodable payload to dead-letter, got %+v", j)
+	}
+}
+
+func TestQueueDelayedJobs(t *testing.T) {
+	db := newTestDB(t)
+	q, clock := newTestQueue(t, db)
+	var ran []string
+	Register(q, "remind", func(_ context.Context, name string) error {
+		ran = append(ran, name)
+		return nil
+	})
+
+	mustEnqueue(t, db, "remind", "later", Delay(time.Hour))
+	mustEnqueue(t, db, "remind", "at", At(clock.Now().Add(30*time.Minute)))
+	runOnce(t, q, false)
+
+	clock.Advance(31 * time.Minute)
+	runOnce(t, q, true)
+	runOnce(t, q, false)
+	clock.Advance(30 * time.Minute)
+	runOnce(t, q, true)
+
+	if strings.Join(ran, ",") != "at,later" {
+		t.Fatalf("unexpected execution order: %v", ran)
+	}
+}
+
+func TestQueueReclaimsExpiredLease(t *testing.T) {
+	db := newTestDB(t)
+	crashed, clock := newTestQueue(t, db)
+	Register(crashed, "cleanup", func(context.Context, struct{}) error { return nil })
+	job := mustEnqueue(t, db, "cleanup", struct{}{})
+
+	// 第一个 worker 认领后没有写回结果（例如进程被杀死）
+	claimed, err := crashed.claim(context.Background())
+	if err != nil || claimed == nil {
+		t.Fatalf("claim: %v", err)
+	}
+
+	other := NewQueue(db, testConfig)
+	other.now = clock.Now
+	Register(other, "cleanup", func(context.Context, struct{}) error { return nil })
+	runOnce(t, other, false)
+
+	clock.Advance(testConfig.Timeout + background.LeaseMargin + time.Second)
+	runOnce(t, other, true)
+	if j := reload(t, db, job.ID); j.Status != models.JobStatusSucceeded || j.Attempts != 2 {
+		t.Fatalf("expected reclaimed job to succeed on attempt 2, got %+v", j)
+	}
+
+	// 原 worker 恢复后不能覆盖结果
+	if err := crashed.finish(context.Background(), claimed, models.JobStatusQueued, errors.New("late")); err == nil {
+		t.Fatal("expected finish with a lost lease to fail")
+	}
+}
+
+func TestQueueWorkersProcessEachJobOnce(t *testing.T) {
+	db := newTestDB(t)
+	const total = 40
+
+	var mu sync.Mutex
+	runs := make(map[int]int)
+	done := make(chan struct{})
+	handler := func(_ context.Context, n int) error {
+		mu.Lock()
+		defer mu.Unlock()
+		runs[n]++
+		if len(runs) == total {
+			select {
+			case <-done:
+			default:
+				close(done)
+			}
+		}
+		return nil
+	}
+
+	for i := 0; i < total; i++ {
+		mustEnqueue(t, db, "count", i)
+	}
+
+	// 两个队列模拟两个进程，各自有多个 worker
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		q := NewQueue(db, testConfig)
+		Register(q, "count", handler)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.Run(ctx)
+		}()
+	}
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for jobs")
+	}
+	cancel()
+	wg.Wait()
+
+	for n, count := range runs {
+		if count != 1 {
+			t.Fatalf("job %d ran %d times", n, count)
+		}
+	}
+	var succeeded int64
+	if err := db.Model(&models.Job{}).Where("status = ?", models.JobStatusSucceeded).Count(&succeeded).Error; err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if succeeded != total {
+		t.Fatalf("expected %d succeeded jobs, got %d", total, succeeded)
+	}
+}
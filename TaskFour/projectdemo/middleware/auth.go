@@ -1,14 +1,24 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"projectdemo/models"
 	"projectdemo/utils"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-func Auth(jwtSecret []byte) gin.HandlerFunc {
+// UserLookup 查询 token 对应的用户，Auth 据此拒绝已删除或禁用的用户
+type UserLookup interface {
+	GetUserByID(ctx context.Context, id uint) (*models.User, error)
+}
+
+// Auth 校验 token，并确认用户仍然存在且没有被禁用。通过服务禁用会清除缓存，
+// 旧 token 立即失效；直接修改数据库（如 CLI）在缓存过期后生效
+func Auth(jwtSecret []byte, users UserLookup) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 从 Header 获取 Token
 		authHeader := c.GetHeader("Authorization")
@@ -36,10 +46,84 @@ func Auth(jwtSecret []byte) gin.HandlerFunc {
 			return
 		}
 
+		user, err := users.GetUserByID(c.Request.Context(), claims.UserID)
+		var appErr *utils.AppError
+		switch {
+		case errors.As(err, &appErr) && appErr.Code == http.StatusNotFound:
+			utils.Error(c, http.StatusUnauthorized, "Invalid token")
+			c.Abort()
+			return
+		case err != nil:
+			utils.HandleError(c, err)
+			c.Abort()
+			return
+		case user.IsDisabled():
+			utils.Error(c, http.StatusForbidden, "Account disabled")
+			c.Abort()
+			return
+		}
+
 		// 将用户信息存储到 Context
 		c.Set("userID", claims.UserID)
 		c.Set("username", claims.Username)
+		// 角色以数据库为准，降级后旧 token 不再保留原来的权限
+		c.Set("role", user.Role)
+		c.Set("orgID", claims.OrgID)
+
+		meta := utils.RequestMetaFrom(c.Request.Context())
+		meta.UserID = claims.UserID
+		meta.Username = claims.Username
+		ctx := utils.WithRequestMeta(c.Request.Context(), meta)
+		// 已认证请求的数据库操作一律限定在 token 的活动组织内
+		ctx = utils.WithTenant(ctx, claims.OrgID)
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
+
+// RequireRole 必须在 Auth 之后使用
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		for _, r := range roles {
+			if role == r {
+				c.Next()
+				return
+			}
+		}
+		utils.Error(c, http.StatusForbidden, "Forbidden")
+		c.Abort()
+	}
+}
 
+// Unscoped 必须在 Auth 和 RequireRole 之后使用，取消 Auth 设置的租户限定，
+// 管理接口据此可以处理任意组织的数据
+func Unscoped() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(utils.WithoutTenant(c.Request.Context()))
+		c.Next()
+	}
+}
+
+// MembershipLookup 查询用户在组织内的角色，用户不是成员时返回错误
+type MembershipLookup interface {
+	MemberRole(ctx context.Context, orgID, userID uint) (string, error)
+}
+
+// Tenant 必须在 Auth 之后使用。token 指定了组织时校验用户仍是其成员（成员被移除后旧 token 立即失效），
+// 并把组织内角色写入 orgRole
+func Tenant(lookup MembershipLookup) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if orgID := c.GetUint("orgID"); orgID != 0 {
+			role, err := lookup.MemberRole(c.Request.Context(), orgID, c.GetUint("userID"))
+			if err != nil {
+				utils.HandleError(c, err)
+				c.Abort()
+				return
+			}
+			c.Set("orgRole", role)
+		}
 		c.Next()
 	}
 }
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	AuditUserRegister      = "user.register"
+	AuditUserLoginSuccess  = "user.login.success"
+	AuditUserLoginFailure  = "user.login.failure"
+	AuditUserEmailChange   = "user.email.change"
+	AuditUserEmailVerify   = "user.email.verify"
+	AuditUserProfileUpdate = "user.profile.update"
+	AuditUserAvatarChange  = "user.avatar.change"
+	AuditUserDelete        = "user.delete"
+	AuditUserDisable       = "user.disable"
+	AuditUserPasswordReset = "user.password.reset"
+	AuditUserRoleChange    = "user.role.change"
+
+	AuditOrgCreate       = "org.create"
+	AuditOrgMemberRemove = "org.member.remove"
+	AuditOrgInviteCreate = "org.invite.create"
+	AuditOrgInviteRevoke = "org.invite.revoke"
+	AuditOrgInviteAccept = "org.invite.accept"
+
+	AuditWebhookCreate = "webhook.create"
+	AuditWebhookDelete = "webhook.delete"
+)
+
+var ErrAuditAppendOnly = errors.New("audit events are append-only")
+
+// AuditEvent 审计事件，只允许追加，不允许修改或删除
+type AuditEvent struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	ActorID    *uint     `json:"actor_id" gorm:"index"`
+	ActorName  string    `json:"actor_name" gorm:"size:50"`
+	Action     string    `json:"action" gorm:"size:64;not null;index"`
+	TargetType string    `json:"target_type" gorm:"size:32"`
+	TargetID   string    `json:"target_id" gorm:"size:64;index"`
+	IP         string    `json:"ip" gorm:"size:64"`
+	RequestID  string    `json:"request_id" gorm:"size:64;index"`
+	Before     string    `json:"before,omitempty" gorm:"type:text"`
+	After      string    `json:"after,omitempty" gorm:"type:text"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+}
+
+func (e *AuditEvent) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAuditAppendOnly
+}
+
+func (e *AuditEvent) BeforeDelete(tx *gorm.DB) error {
+	return ErrAuditAppendOnly
+}
+
+type AuditQuery struct {
+	From     time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To       time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Action   string    `form:"action"`
+	ActorID  uint      `form:"actor_id"`
+	TargetID string    `form:"target_id"`
+	Format   string    `form:"format" binding:"omitempty,oneof=json csv jsonl"`
+	Limit    int       `form:"limit" binding:"omitempty,min=1,max=1000"`
+}
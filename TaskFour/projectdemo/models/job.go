@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+const (
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	// JobStatusDead 重试次数用完或遇到不可重试的错误，需要人工处理
+	JobStatusDead = "dead"
+)
+
+// Job 后台任务。RunAt 之前不会被执行；running 状态的任务在 LockedUntil 之后视为 worker 已退出，
+// 可以被其他 worker 重新认领
+type Job struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	Type        string     `json:"type" gorm:"size:100;not null;index"`
+	Payload     string     `json:"payload" gorm:"type:text;not null"`
+	Status      string     `json:"status" gorm:"size:20;not null;index:idx_jobs_claim,priority:1"`
+	RunAt       time.Time  `json:"run_at" gorm:"not null;index:idx_jobs_claim,priority:2"`
+	Attempts    int        `json:"attempts" gorm:"not null;default:0"`
+	MaxAttempts int        `json:"max_attempts" gorm:"not null"`
+	LockedBy    string     `json:"locked_by,omitempty" gorm:"size:100"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	LastError   string     `json:"last_error,omitempty" gorm:"size:1000"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+type JobQuery struct {
+	Status string `form:"status" binding:"omitempty,oneof=queued running succeeded dead"`
+	Type   string `form:"type"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=500"`
+}
+
+// JobStats 各状态的任务数量
+type JobStats struct {
+	Queued    int64 `json:"queued"`
+	Running   int64 `json:"running"`
+	Succeeded int64 `json:"succeeded"`
+	Dead      int64 `json:"dead"`
+}
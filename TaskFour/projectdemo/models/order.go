@@ -0,0 +1,92 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	OrderStatusPending   = "pending"
+	OrderStatusPaid      = "paid"
+	OrderStatusShipped   = "shipped"
+	OrderStatusCancelled = "cancelled"
+)
+
+// OrderTransitions 允许的订单状态流转
+var OrderTransitions = map[string][]string{
+	OrderStatusPending: {OrderStatusPaid, OrderStatusCancelled},
+	OrderStatusPaid:    {OrderStatusShipped, OrderStatusCancelled},
+}
+
+func CanTransitOrder(from, to string) bool {
+	for _, s := range OrderTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Inventory 商品库存，Version 用于乐观锁
+type Inventory struct {
+	ProductID uint      `json:"product_id" gorm:"primaryKey;autoIncrement:false"`
+	Quantity  int       `json:"quantity" gorm:"not null;default:0"`
+	Version   uint      `json:"version" gorm:"not null;default:0"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type CartItem struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_cart_user_product"`
+	ProductID uint      `json:"product_id" gorm:"not null;uniqueIndex:idx_cart_user_product"`
+	Quantity  int       `json:"quantity" gorm:"not null"`
+	Product   Product   `json:"product" gorm:"foreignKey:ProductID"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type Order struct {
+	ID          uint        `json:"id" gorm:"primaryKey"`
+	OrgID       uint        `json:"org_id" gorm:"not null;default:0;index"`
+	UserID      uint        `json:"user_id" gorm:"not null;index"`
+	Status      string      `json:"status" gorm:"size:20;not null;index"`
+	Total       int64       `json:"total" gorm:"not null"` // 单位：分
+	Version     uint        `json:"version" gorm:"not null;default:0"`
+	Items       []OrderItem `json:"items" gorm:"constraint:OnDelete:CASCADE"`
+	PaidAt      *time.Time  `json:"paid_at,omitempty"`
+	ShippedAt   *time.Time  `json:"shipped_at,omitempty"`
+	CancelledAt *time.Time  `json:"cancelled_at,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+func (o *Order) GetOrgID() uint {
+	return o.OrgID
+}
+
+func (o *Order) SetOrgID(id uint) {
+	o.OrgID = id
+}
+
+// OrderItem 下单时的商品快照，商品后续改价不影响历史订单
+type OrderItem struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	OrderID   uint   `json:"order_id" gorm:"not null;index"`
+	ProductID uint   `json:"product_id" gorm:"not null"`
+	SKU       string `json:"sku" gorm:"size:64"`
+	Name      string `json:"name" gorm:"size:100"`
+	UnitPrice int64  `json:"unit_price" gorm:"not null"`
+	Quantity  int    `json:"quantity" gorm:"not null"`
+}
+
+type SetInventoryRequest struct {
+	Quantity int `json:"quantity" binding:"min=0"`
+}
+
+type AddCartItemRequest struct {
+	ProductID uint `json:"product_id" binding:"required"`
+	Quantity  int  `json:"quantity" binding:"required,min=1,max=999"`
+}
+
+type UpdateCartItemRequest struct {
+	Quantity int `json:"quantity" binding:"required,min=1,max=999"`
+}
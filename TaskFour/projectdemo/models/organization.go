@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+)
+
+// 组织内角色，与全局角色 RoleUser/RoleAdmin 相互独立
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleAdmin  = "admin"
+	OrgRoleMember = "member"
+)
+
+type Organization struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"not null;size:100"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type Membership struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	OrgID     uint      `json:"org_id" gorm:"not null;uniqueIndex:idx_memberships_org_user"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_memberships_org_user;index"`
+	Role      string    `json:"role" gorm:"size:20;not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Invitation 邀请指定邮箱加入组织。数据库只保存令牌的 SHA-256，明文令牌仅在创建时返回一次
+type Invitation struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	OrgID      uint       `json:"org_id" gorm:"not null;index"`
+	Email      string     `json:"email" gorm:"not null;size:100"`
+	Role       string     `json:"role" gorm:"size:20;not null"`
+	TokenHash  string     `json:"-" gorm:"not null;size:64;uniqueIndex"`
+	Token      string     `json:"token,omitempty" gorm:"-"`
+	InvitedBy  uint       `json:"invited_by" gorm:"not null"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (i *Invitation) Expired(now time.Time) bool {
+	return !now.Before(i.ExpiresAt)
+}
+
+type CreateOrganizationRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// OrganizationResponse 当前用户所在的组织及其在组织内的角色
+type OrganizationResponse struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type MemberResponse struct {
+	UserID   uint      `json:"user_id"`
+	Username string    `json:"username"`
+	Email    string    `json:"email"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+type CreateInvitationRequest struct {
+	Email string `json:"email" binding:"required,email,max=100"`
+	Role  string `json:"role" binding:"omitempty,oneof=admin member"`
+}
+
+type AcceptInvitationRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// SwitchOrganizationRequest OrgID 为 0 时切换回个人空间
+type SwitchOrganizationRequest struct {
+	OrgID uint `json:"org_id"`
+}
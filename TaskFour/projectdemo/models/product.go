@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ProductStatusDraft    = "draft"
+	ProductStatusActive   = "active"
+	ProductStatusArchived = "archived"
+)
+
+type Product struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	OrgID       uint           `json:"org_id" gorm:"not null;default:0;uniqueIndex:idx_products_org_sku"` // SKU 在组织内唯一
+	OwnerID     uint           `json:"owner_id" gorm:"index;not null"`
+	SKU         string         `json:"sku" gorm:"not null;size:64;uniqueIndex:idx_products_org_sku"`
+	Name        string         `json:"name" gorm:"not null;size:100"`
+	Description string         `json:"description" gorm:"size:1000"`
+	Price       int64          `json:"price" gorm:"not null"` // 单位：分
+	Status      string         `json:"status" gorm:"size:20;not null;index"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+func (p *Product) GetOwnerID() uint {
+	return p.OwnerID
+}
+
+func (p *Product) SetOwnerID(id uint) {
+	p.OwnerID = id
+}
+
+func (p *Product) GetOrgID() uint {
+	return p.OrgID
+}
+
+func (p *Product) SetOrgID(id uint) {
+	p.OrgID = id
+}
+
+type CreateProductRequest struct {
+	SKU         string `json:"sku" binding:"required,max=64"`
+	Name        string `json:"name" binding:"required,max=100"`
+	Description string `json:"description" binding:"max=1000"`
+	Price       int64  `json:"price" binding:"min=0"`
+	Status      string `json:"status" binding:"omitempty,oneof=draft active archived"`
+}
+
+type PatchProductRequest struct {
+	Name        *string `json:"name" binding:"omitempty,max=100"`
+	Description *string `json:"description" binding:"omitempty,max=1000"`
+	Price       *int64  `json:"price" binding:"omitempty,min=0"`
+	Status      *string `json:"status" binding:"omitempty,oneof=draft active archived"`
+}
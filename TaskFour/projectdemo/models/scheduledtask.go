@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+const (
+	TaskStatusRunning   = "running"
+	TaskStatusSucceeded = "succeeded"
+	TaskStatusFailed    = "failed"
+)
+
+// ScheduledTask 定时任务的调度状态，每个任务一行。LeaseOwner/LeaseUntil 是执行租约：
+// 多个副本中只有拿到租约的那个执行，执行期间也不会再次触发
+type ScheduledTask struct {
+	Name           string     `json:"name" gorm:"primaryKey;size:100"`
+	Schedule       string     `json:"schedule" gorm:"size:100;not null"`
+	NextRunAt      time.Time  `json:"next_run_at" gorm:"not null"`
+	LeaseOwner     string     `json:"lease_owner,omitempty" gorm:"size:100"`
+	LeaseUntil     *time.Time `json:"lease_until,omitempty"`
+	LastStatus     string     `json:"last_status,omitempty" gorm:"size:20"`
+	LastStartedAt  *time.Time `json:"last_started_at,omitempty"`
+	LastFinishedAt *time.Time `json:"last_finished_at,omitempty"`
+	LastDurationMS int64      `json:"last_duration_ms"`
+	LastError      string     `json:"last_error,omitempty" gorm:"size:1000"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
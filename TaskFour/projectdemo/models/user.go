@@ -6,14 +6,33 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	Username  string         `json:"username" gorm:"uniqueIndex;not null;size:50"`
-	Email     string         `json:"email" gorm:"uniqueIndex;not null;size:100"`
-	Password  string         `json:"-" gorm:"not null"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	Username    string `json:"username" gorm:"uniqueIndex;not null;size:50"`
+	Email       string `json:"email" gorm:"uniqueIndex;not null;size:100"`
+	Password    string `json:"-" gorm:"not null"`
+	Role        string `json:"role" gorm:"size:20;not null;default:user"`
+	DisplayName string `json:"display_name" gorm:"size:100"`
+	Bio         string `json:"bio" gorm:"size:500"`
+	Locale      string `json:"locale" gorm:"size:35"`
+	Timezone    string `json:"timezone" gorm:"size:64"`
+	AvatarKey   string `json:"-" gorm:"size:255"`
+	// EmailVerifiedAt 邮箱验证时间，修改邮箱后清空
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	// DisabledAt 不为空时禁止登录和刷新 token
+	DisabledAt *time.Time     `json:"disabled_at,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+func (u *User) IsDisabled() bool {
+	return u.DisabledAt != nil
 }
 
 type CreateUserRequest struct {
@@ -26,8 +45,17 @@ type GetUserRequest struct {
 	UserID   uint   `uri:"id" binding:"required"`
 }
 
+// UpdateUserRequest 中的指针字段为 nil 表示不修改
 type UpdateUserRequest struct {
-	Email string `json:"email" binding:"omitempty,email"`
+	Email       string  `json:"email" binding:"omitempty,email"`
+	DisplayName *string `json:"display_name" binding:"omitempty,max=100"`
+	Bio         *string `json:"bio" binding:"omitempty,max=500"`
+	Locale      *string `json:"locale" binding:"omitempty,bcp47_language_tag"`
+	Timezone    *string `json:"timezone" binding:"omitempty,timezone"`
+}
+
+type ResetPasswordRequest struct {
+	Password string `json:"password" binding:"required,min=6"`
 }
 
 type LoginRequest struct {
@@ -35,9 +63,21 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type LoginResponse struct {
+	Token string       `json:"token"`
+	User  UserResponse `json:"user"`
+	// OrgID token 对应的活动组织，0 表示个人空间
+	OrgID uint `json:"org_id"`
+}
+
 type UserResponse struct {
-	ID        uint      `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
+	ID          uint              `json:"id"`
+	Username    string            `json:"username"`
+	Email       string            `json:"email"`
+	DisplayName string            `json:"display_name,omitempty"`
+	Bio         string            `json:"bio,omitempty"`
+	Locale      string            `json:"locale,omitempty"`
+	Timezone    string            `json:"timezone,omitempty"`
+	Avatars     map[string]string `json:"avatars,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
 }
@@ -0,0 +1,137 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Webhook 事件类型，同时作为订阅的过滤条件
+const (
+	EventUserRegistered   = "user.registered"
+	EventUserVerified     = "user.verified"
+	EventUserEmailChanged = "user.email_changed"
+	EventUserDeleted      = "user.deleted"
+)
+
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusSucceeded = "succeeded"
+	DeliveryStatusFailed    = "failed"
+)
+
+// StringList 以 JSON 数组形式存储在单个文本列中
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal([]string(l))
+	return string(b), err
+}
+
+func (l *StringList) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), l)
+	case []byte:
+		return json.Unmarshal(v, l)
+	}
+	return errors.New("unsupported StringList source")
+}
+
+// WebhookSubscription Events 为空时接收所有事件。Secret 用于签名，创建后不再返回
+type WebhookSubscription struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	URL       string     `json:"url" gorm:"not null;size:2048"`
+	Secret    string     `json:"-" gorm:"not null;size:128"`
+	Events    StringList `json:"events" gorm:"type:text"`
+	Active    bool       `json:"active" gorm:"not null;default:true"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+func (s *WebhookSubscription) Accepts(eventType string) bool {
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, e := range s.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// OutboxEvent 与业务数据在同一事务中写入，由 WebhookDispatcher 异步分发给订阅者。
+// DispatchedAt 不为空表示已经为所有匹配的订阅生成了投递记录
+type OutboxEvent struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	Type         string     `json:"type" gorm:"size:64;not null"`
+	Payload      string     `json:"payload" gorm:"type:text;not null"`
+	CreatedAt    time.Time  `json:"created_at"`
+	DispatchedAt *time.Time `json:"dispatched_at,omitempty" gorm:"index"`
+}
+
+// WebhookDelivery 一个事件对一个订阅的投递，失败后按退避时间在 NextAttemptAt 重试
+type WebhookDelivery struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	SubscriptionID uint       `json:"subscription_id" gorm:"not null;uniqueIndex:idx_webhook_deliveries_sub_event"`
+	EventID        uint       `json:"event_id" gorm:"not null;uniqueIndex:idx_webhook_deliveries_sub_event"`
+	EventType      string     `json:"event_type" gorm:"size:64;not null"`
+	Status         string     `json:"status" gorm:"size:20;not null;index:idx_webhook_deliveries_due,priority:1"`
+	Attempts       int        `json:"attempts" gorm:"not null;default:0"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at" gorm:"index:idx_webhook_deliveries_due,priority:2"`
+	LockedBy       string     `json:"locked_by,omitempty" gorm:"size:100"`
+	LastStatusCode int        `json:"last_status_code"`
+	LastError      string     `json:"last_error,omitempty" gorm:"size:500"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	Logs []WebhookDeliveryLog `json:"logs,omitempty" gorm:"foreignKey:DeliveryID;constraint:OnDelete:CASCADE"`
+}
+
+// WebhookDeliveryLog 每次投递尝试的记录，响应体只保留开头部分
+type WebhookDeliveryLog struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	DeliveryID   uint      `json:"delivery_id" gorm:"not null;index"`
+	Attempt      int       `json:"attempt" gorm:"not null"`
+	StatusCode   int       `json:"status_code"`
+	Error        string    `json:"error,omitempty" gorm:"size:500"`
+	ResponseBody string    `json:"response_body,omitempty" gorm:"size:1024"`
+	DurationMS   int64     `json:"duration_ms"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// UserEventData 用户生命周期事件的 data 字段
+type UserEventData struct {
+	ID            uint   `json:"id"`
+	Username      string `json:"username"`
+	Email         string `json:"email"`
+	PreviousEmail string `json:"previous_email,omitempty"`
+}
+
+// WebhookPayload 投递的请求体
+type WebhookPayload struct {
+	ID        uint            `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" binding:"required,url,max=2048"`
+	Secret string   `json:"secret" binding:"required,min=16,max=128"`
+	Events []string `json:"events" binding:"omitempty,dive,oneof=user.registered user.verified user.email_changed user.deleted"`
+}
+
+type WebhookDeliveryQuery struct {
+	Status string `form:"status" binding:"omitempty,oneof=pending succeeded failed"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
@@ -0,0 +1,267 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Operation 描述一个接口，通过 Method + Path 与 Gin 路由对应
+type Operation struct {
+	Method string
+	// Path Gin 风格的路径，例如 /api/v1/orders/:id
+	Path        string
+	ID          string
+	Summary     string
+	Description string
+	Tags        []string
+
+	// Auth 需要 Bearer Token，Roles 不为空时还要求具有其中一个角色
+	Auth  bool
+	Roles []string
+
+	// Query 带 form 标签的查询参数结构体，Params 为无法用结构体描述的额外参数
+	Query  interface{}
+	Params []Parameter
+	// Body JSON 请求体，Upload 为 multipart 上传的文件字段名
+	Body   interface{}
+	Upload string
+
+	// Response 成功时 utils.Response 中 data 字段的类型，nil 表示不返回 data
+	Response interface{}
+	// Status 成功时的状态码，默认 200
+	Status int
+	// Produces 除 JSON 外成功响应可能使用的其他媒体类型
+	Produces []string
+	// Raw 成功响应不使用 utils.Response 信封，媒体类型取 Produces
+	Raw bool
+	// Errors 除自动推导（401/403/422/500）之外可能返回的错误状态码
+	Errors []int
+}
+
+func (op Operation) key() string {
+	return op.Method + " " + op.Path
+}
+
+// Build 根据已注册的 Gin 路由和接口描述生成文档，只包含两者都存在的接口
+func Build(info Info, routes gin.RoutesInfo, ops []Operation) *Document {
+	g := newGenerator()
+	g.schemas["ErrorResponse"] = errorSchema()
+
+	byKey := make(map[string]Operation, len(ops))
+	for _, op := range ops {
+		byKey[op.key()] = op
+	}
+
+	doc := &Document{
+		OpenAPI: "3.1.0",
+		Info:    info,
+		Paths:   map[string]*PathItem{},
+		Components: Components{
+			Schemas: g.schemas,
+			SecuritySchemes: map[string]SecurityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+
+	for _, route := range routes {
+		op, ok := byKey[route.Method+" "+route.Path]
+		if !ok {
+			continue
+		}
+		p := specPath(route.Path)
+		item, ok := doc.Paths[p]
+		if !ok {
+			item = &PathItem{}
+			doc.Paths[p] = item
+		}
+		(*item)[strings.ToLower(route.Method)] = g.operation(op)
+	}
+	return doc
+}
+
+// Diff 比较路由和接口描述，返回不一致的条目，为空表示文档与路由一致
+func Diff(routes gin.RoutesInfo, ops []Operation) []string {
+	registered := map[string]bool{}
+	for _, route := range routes {
+		registered[route.Method+" "+route.Path] = true
+	}
+
+	var problems []string
+	documented := map[string]bool{}
+	for _, op := range ops {
+		key := op.key()
+		if documented[key] {
+			problems = append(problems, key+": documented more than once")
+		}
+		documented[key] = true
+		if !registered[key] {
+			problems = append(problems, key+": documented but no route is registered")
+		}
+	}
+	for key := range registered {
+		if !documented[key] {
+			problems = append(problems, key+": route is registered but not documented")
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+func (g *generator) operation(op Operation) *OperationObject {
+	o := &OperationObject{
+		OperationID: op.ID,
+		Summary:     op.Summary,
+		Description: op.Description,
+		Tags:        op.Tags,
+		Responses:   map[string]Response{},
+	}
+	if o.OperationID == "" {
+		o.OperationID = defaultOperationID(op)
+	}
+	if len(op.Roles) > 0 {
+		note := "Requires role: " + strings.Join(op.Roles, ", ") + "."
+		o.Description = strings.TrimSpace(o.Description + "\n\n" + note)
+	}
+
+	// 路径参数
+	for _, segment := range strings.Split(op.Path, "/") {
+		if len(segment) < 2 || (segment[0] != ':' && segment[0] != '*') {
+			continue
+		}
+		name := segment[1:]
+		schema := &Schema{Type: "string"}
+		if name == "id" || strings.HasSuffix(name, "_id") {
+			schema = &Schema{Type: "integer", Minimum: float(1)}
+		}
+		o.Parameters = append(o.Parameters, Parameter{Name: name, In: "path", Required: true, Schema: schema})
+	}
+	if op.Query != nil {
+		o.Parameters = append(o.Parameters, g.queryParameters(reflect.TypeOf(op.Query))...)
+	}
+	o.Parameters = append(o.Parameters, op.Params...)
+
+	switch {
+	case op.Body != nil:
+		o.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{
+			"application/json": {Schema: g.schemaOf(reflect.TypeOf(op.Body))},
+		}}
+	case op.Upload != "":
+		o.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{
+			"multipart/form-data": {Schema: &Schema{
+				Type:       "object",
+				Properties: map[string]*Schema{op.Upload: {Type: "string", Format: "binary"}},
+				Required:   []string{op.Upload},
+			}},
+		}}
+	}
+
+	if op.Auth {
+		o.Security = []map[string][]string{{"bearerAuth": {}}}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	o.Responses[strconv.Itoa(status)] = g.successResponse(op, status)
+
+	errs := append([]int(nil), op.Errors...)
+	if op.Auth {
+		errs = append(errs, http.StatusUnauthorized)
+	}
+	if len(op.Roles) > 0 {
+		errs = append(errs, http.StatusForbidden)
+	}
+	if op.Body != nil || op.Query != nil {
+		errs = append(errs, http.StatusUnprocessableEntity)
+	}
+	errs = append(errs, http.StatusInternalServerError)
+	for _, code := range errs {
+		o.Responses[strconv.Itoa(code)] = Response{
+			Description: http.StatusText(code),
+			Content: map[string]MediaType{
+				"application/json": {Schema: &Schema{Ref: "#/components/schemas/ErrorResponse"}},
+			},
+		}
+	}
+	return o
+}
+
+func (g *generator) successResponse(op Operation, status int) Response {
+	resp := Response{Description: http.StatusText(status), Content: map[string]MediaType{}}
+
+	if op.Raw {
+		schema := &Schema{Type: "string"}
+		if op.Response != nil {
+			schema = g.schemaOf(reflect.TypeOf(op.Response))
+		}
+		for _, mt := range op.Produces {
+			resp.Content[mt] = MediaType{Schema: schema}
+		}
+		return resp
+	}
+
+	envelope := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"code":    {Type: "integer", Enum: []interface{}{status}},
+			"message": {Type: "string"},
+		},
+		Required: []string{"code", "message"},
+	}
+	if op.Response != nil {
+		envelope.Properties["data"] = g.schemaOf(reflect.TypeOf(op.Response))
+		envelope.Required = append(envelope.Required, "data")
+	}
+	resp.Content["application/json"] = MediaType{Schema: envelope}
+	for _, mt := range op.Produces {
+		resp.Content[mt] = MediaType{Schema: &Schema{Type: "string"}}
+	}
+	return resp
+}
+
+// errorSchema 与 utils.Error / utils.ValidationError / utils.HandleError 的输出一致
+func errorSchema() *Schema {
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"code":    {Type: "integer"},
+			"message": {Type: "string"},
+			"error": {
+				Description: "Error detail, or a field-to-message map for validation errors",
+				OneOf: []*Schema{
+					{Type: "string"},
+					{Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+				},
+			},
+			"field": {Type: "string", Description: "Request field that caused the error, e.g. a conflicting username"},
+		},
+		Required: []string{"code", "message"},
+	}
+}
+
+// specPath 将 Gin 的 :id、*path 转换为 OpenAPI 的 {id}、{path}
+func specPath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, s := range segments {
+		if len(s) > 1 && (s[0] == ':' || s[0] == '*') {
+			segments[i] = "{" + s[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// defaultOperationID 例如 GET /api/v1/orders/:id 生成 getApiV1OrdersId
+func defaultOperationID(op Operation) string {
+	id := strings.ToLower(op.Method) + exportedName(op.Path)
+	if id == strings.ToLower(op.Method) {
+		return id + "Root"
+	}
+	return id
+}
@@ -0,0 +1,279 @@
+package openapi
+
+import (
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// generator 通过反射把 Go 类型转换为 JSON Schema，具名结构体放入 components 并以 $ref 引用
+type generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newGenerator() *generator {
+	return &generator{schemas: map[string]*Schema{}, names: map[reflect.Type]string{}}
+}
+
+func (g *generator) schemaOf(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Minimum: float(0)}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + g.register(t)}
+	default:
+		// interface{} 等无法确定的类型不做约束
+		return &Schema{}
+	}
+}
+
+// register 为具名结构体生成组件，返回组件名。先登记名字再展开字段，以支持自引用类型
+func (g *generator) register(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+
+	name := componentName(t)
+	if _, taken := g.schemas[name]; taken {
+		name = exportedName(path.Base(t.PkgPath())) + name
+	}
+	g.names[t] = name
+	g.schemas[name] = &Schema{}
+	*g.schemas[name] = *g.structSchema(t)
+	return name
+}
+
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	g.collectFields(t, s)
+	return s
+}
+
+// collectFields 按 encoding/json 的规则收集字段，匿名嵌入的结构体字段会被展开
+func (g *generator) collectFields(t reflect.Type, s *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.collectFields(ft, s)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		prop := g.schemaOf(f.Type)
+		if applyBinding(prop, f.Type, f.Tag.Get("binding")) {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = prop
+	}
+}
+
+// queryParameters 将带 form 标签的结构体转换为查询参数
+func (g *generator) queryParameters(t reflect.Type) []Parameter {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var params []Parameter
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("form"), ",")[0]
+		if name == "" || name == "-" || !f.IsExported() {
+			continue
+		}
+		schema := g.schemaOf(f.Type)
+		required := applyBinding(schema, f.Type, f.Tag.Get("binding"))
+		params = append(params, Parameter{Name: name, In: "query", Required: required, Schema: schema})
+	}
+	return params
+}
+
+// applyBinding 把 validator 的 binding 规则映射为 Schema 约束，返回字段是否必填。
+// 无法表达的规则（如 bcp47_language_tag）以自定义 format 标注
+func applyBinding(s *Schema, t reflect.Type, tag string) (required bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	kind := t.Kind()
+
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "dive":
+			// dive 之后的规则作用于元素，这里不再处理
+			return required
+		case "required":
+			required = true
+		}
+		// $ref 引用的组件不能附加约束
+		if s.Ref != "" {
+			continue
+		}
+		switch name {
+		case "min", "max", "len":
+			n, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				continue
+			}
+			if name != "max" {
+				setLower(s, kind, n)
+			}
+			if name != "min" {
+				setUpper(s, kind, n)
+			}
+		case "gte":
+			if n, err := strconv.ParseFloat(param, 64); err == nil {
+				setLower(s, kind, n)
+			}
+		case "lte":
+			if n, err := strconv.ParseFloat(param, 64); err == nil {
+				setUpper(s, kind, n)
+			}
+		case "gt":
+			if n, err := strconv.ParseFloat(param, 64); err == nil && isNumeric(kind) {
+				s.Minimum = nil
+				s.ExclusiveMinimum = float(n)
+			}
+		case "lt":
+			if n, err := strconv.ParseFloat(param, 64); err == nil && isNumeric(kind) {
+				s.ExclusiveMaximum = float(n)
+			}
+		case "oneof":
+			for _, v := range strings.Fields(param) {
+				if n, err := strconv.ParseFloat(v, 64); err == nil && isNumeric(kind) {
+					s.Enum = append(s.Enum, n)
+				} else {
+					s.Enum = append(s.Enum, v)
+				}
+			}
+		case "email":
+			s.Format = "email"
+		case "url", "uri":
+			s.Format = "uri"
+		case "uuid":
+			s.Format = "uuid"
+		case "bcp47_language_tag":
+			s.Format = "bcp47"
+		case "timezone":
+			s.Format = "iana-timezone"
+		}
+	}
+	return required
+}
+
+func setLower(s *Schema, kind reflect.Kind, n float64) {
+	switch {
+	case kind == reflect.String:
+		s.MinLength = intPtr(n)
+	case kind == reflect.Slice || kind == reflect.Array || kind == reflect.Map:
+		s.MinItems = intPtr(n)
+	case isNumeric(kind):
+		s.Minimum = float(n)
+	}
+}
+
+func setUpper(s *Schema, kind reflect.Kind, n float64) {
+	switch {
+	case kind == reflect.String:
+		s.MaxLength = intPtr(n)
+	case kind == reflect.Slice || kind == reflect.Array || kind == reflect.Map:
+		s.MaxItems = intPtr(n)
+	case isNumeric(kind):
+		s.Maximum = float(n)
+	}
+}
+
+func isNumeric(kind reflect.Kind) bool {
+	return kind >= reflect.Int && kind <= reflect.Float64
+}
+
+// componentName 泛型类型 Page[projectdemo/models.Product] 转换为 PageProduct
+func componentName(t reflect.Type) string {
+	base, args, generic := strings.Cut(t.Name(), "[")
+	if !generic {
+		return base
+	}
+	var b strings.Builder
+	b.WriteString(base)
+	for _, arg := range strings.Split(strings.TrimSuffix(args, "]"), ",") {
+		arg = arg[strings.LastIndex(arg, ".")+1:]
+		b.WriteString(exportedName(arg))
+	}
+	return b.String()
+}
+
+func exportedName(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func float(n float64) *float64 {
+	return &n
+}
+
+func intPtr(n float64) *int {
+	i := int(n)
+	return &i
+}
@@ -0,0 +1,104 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type bindingExample struct {
+	Name     string            `json:"name" binding:"required,min=3,max=20"`
+	Email    string            `json:"email" binding:"omitempty,email"`
+	Age      int               `json:"age" binding:"gte=18,lt=130"`
+	Score    float64           `json:"score" binding:"gt=0"`
+	Status   string            `json:"status" binding:"oneof=draft active"`
+	Level    int               `json:"level" binding:"oneof=1 2 3"`
+	Tags     []string          `json:"tags" binding:"max=5,dive,max=10"`
+	Code     string            `json:"code" binding:"len=6"`
+	Nickname *string           `json:"nickname,omitempty" binding:"omitempty,max=30"`
+	Labels   map[string]string `json:"labels"`
+	Secret   string            `json:"-"`
+	At       time.Time         `json:"at"`
+	Child    *bindingChild     `json:"child" binding:"required"`
+	embedded
+}
+
+type bindingChild struct {
+	Parent *bindingChild `json:"parent"`
+}
+
+type embedded struct {
+	Extra string `json:"extra"`
+}
+
+type page[T any] struct {
+	Items []T `json:"items"`
+}
+
+func TestSchemaFromBindingTags(t *testing.T) {
+	g := newGenerator()
+	ref := g.schemaOf(reflect.TypeOf(bindingExample{}))
+	if ref.Ref != "#/components/schemas/bindingExample" {
+		t.Fatalf("expected a component reference, got %+v", ref)
+	}
+	s := g.schemas["bindingExample"]
+
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{"name", `{"type":"string","minLength":3,"maxLength":20}`},
+		{"email", `{"type":"string","format":"email"}`},
+		{"age", `{"type":"integer","format":"int32","minimum":18,"exclusiveMaximum":130}`},
+		{"score", `{"type":"number","exclusiveMinimum":0}`},
+		{"status", `{"type":"string","enum":["draft","active"]}`},
+		{"level", `{"type":"integer","format":"int32","enum":[1,2,3]}`},
+		{"tags", `{"type":"array","items":{"type":"string"},"maxItems":5}`},
+		{"code", `{"type":"string","minLength":6,"maxLength":6}`},
+		{"nickname", `{"type":"string","maxLength":30}`},
+		{"labels", `{"type":"object","additionalProperties":{"type":"string"}}`},
+		{"at", `{"type":"string","format":"date-time"}`},
+		{"child", `{"$ref":"#/components/schemas/bindingChild"}`},
+		{"extra", `{"type":"string"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			prop, ok := s.Properties[tt.field]
+			if !ok {
+				t.Fatalf("property %s missing", tt.field)
+			}
+			got, _ := json.Marshal(prop)
+			if string(got) != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, ok := s.Properties["-"]; ok {
+		t.Fatalf(`fields tagged json:"-" must be skipped`)
+	}
+	if want := []string{"name", "child"}; !reflect.DeepEqual(s.Required, want) {
+		t.Fatalf("required = %v, want %v", s.Required, want)
+	}
+	// 自引用类型只生成一次组件
+	if parent := g.schemas["bindingChild"].Properties["parent"]; parent.Ref != "#/components/schemas/bindingChild" {
+		t.Fatalf("self reference not resolved: %+v", parent)
+	}
+}
+
+func TestGenericComponentName(t *testing.T) {
+	g := newGenerator()
+	if got := g.schemaOf(reflect.TypeOf(page[bindingChild]{})).Ref; got != "#/components/schemas/pageBindingChild" {
+		t.Fatalf("unexpected reference %s", got)
+	}
+}
+
+func TestSpecPath(t *testing.T) {
+	if got := specPath("/api/v1/cart/items/:product_id"); got != "/api/v1/cart/items/{product_id}" {
+		t.Fatalf("unexpected path %s", got)
+	}
+	if got := specPath("/media/*filepath"); got != "/media/{filepath}" {
+		t.Fatalf("unexpected path %s", got)
+	}
+}
@@ -0,0 +1,44 @@
+package openapi
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed ui/index.html
+var uiHTML string
+
+var uiTemplate = template.Must(template.New("docs").Parse(uiHTML))
+
+// Mount 注册 specPath（JSON 文档）和 docsPath（内置文档页面，不依赖外部 CDN）两个路由。
+// 需要在其他路由全部注册之后调用，文档根据调用时已注册的路由生成
+func Mount(r *gin.Engine, info Info, ops []Operation, specPath, docsPath string) (*Document, error) {
+	var page bytes.Buffer
+	if err := uiTemplate.Execute(&page, map[string]string{"Title": info.Title, "SpecURL": specPath}); err != nil {
+		return nil, err
+	}
+
+	var spec []byte
+	r.GET(specPath, func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", spec)
+	})
+	r.GET(docsPath, func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", page.Bytes())
+	})
+
+	doc := Build(info, r.Routes(), ops)
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	spec = buf.Bytes()
+	return doc, nil
+}
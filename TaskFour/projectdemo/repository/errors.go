@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotFound     = errors.New("record not found")
+	ErrDuplicateKey = errors.New("duplicate key")
+)
+
+// DuplicateKeyError 违反唯一约束，Field 为冲突的字段名（无法识别时为空）
+type DuplicateKeyError struct {
+	Field string
+	Err   error
+}
+
+func (e *DuplicateKeyError) Error() string {
+	if e.Field == "" {
+		return "duplicate key"
+	}
+	return "duplicate key on " + e.Field
+}
+
+func (e *DuplicateKeyError) Unwrap() error {
+	return e.Err
+}
+
+func (e *DuplicateKeyError) Is(target error) bool {
+	return target == ErrDuplicateKey
+}
+
+var (
+	// SQLite: UNIQUE constraint failed: users.username
+	// 复合约束列出所有列：UNIQUE constraint failed: products.org_id, products.sku
+	sqliteUniqueRe = regexp.MustCompile(`UNIQUE constraint failed: ([\w.]+(?:, [\w.]+)*)`)
+	// MySQL: Error 1062 (23000): Duplicate entry 'alice' for key 'users.idx_users_username'
+	mysqlUniqueRe = regexp.MustCompile(`Duplicate entry '.*' for key '([\w.]+)'`)
+	// PostgreSQL: duplicate key value violates unique constraint "idx_users_username" (SQLSTATE 23505)
+	postgresUniqueRe = regexp.MustCompile(`duplicate key value violates unique constraint "([\w.]+)"`)
+)
+
+// translateError 将各数据库驱动的唯一约束错误转换为 DuplicateKeyError。
+// 只依赖错误信息文本，避免为识别错误码而引入所有驱动
+func translateError(table string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+
+	msg := err.Error()
+	for _, re := range []*regexp.Regexp{sqliteUniqueRe, mysqlUniqueRe, postgresUniqueRe} {
+		if m := re.FindStringSubmatch(msg); m != nil {
+			return &DuplicateKeyError{Field: constraintField(table, m[1]), Err: err}
+		}
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return &DuplicateKeyError{Err: err}
+	}
+	return err
+}
+
+// constraintField 从列名或索引名中提取字段名：
+// users.username、users.idx_users_username、idx_users_username 均返回 username。
+// 复合约束取最后一列，例如 products.org_id, products.sku 返回 sku
+func constraintField(table, name string) string {
+	if i := strings.LastIndex(name, ", "); i >= 0 {
+		name = name[i+2:]
+	}
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	for _, prefix := range []string{"idx_" + table + "_", "uni_" + table + "_"} {
+		if strings.HasPrefix(name, prefix) {
+			return strings.TrimPrefix(name, prefix)
+		}
+	}
+	return name
+}
+
+// IsTxConflict 判断是否为并发事务冲突，重试整个事务可能成功：
+// SQLite 事务从读升级为写时，如果其他连接已经提交了写入，会立即返回 SQLITE_BUSY 而不等待 busy_timeout；
+// MySQL 和 PostgreSQL 则是死锁或序列化失败。与 translateError 一样只依赖错误信息文本
+func IsTxConflict(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	for _, s := range []string{"SQLITE_BUSY", "Error 1213", "SQLSTATE 40001", "SQLSTATE 40P01"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestTranslateError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantField string
+		wantDup   bool
+	}{
+		{
+			name:      "sqlite",
+			err:       errors.New("constraint failed: UNIQUE constraint failed: users.username (2067)"),
+			wantField: "username",
+			wantDup:   true,
+		},
+		{
+			name:      "sqlite composite",
+			err:       errors.New("constraint failed: UNIQUE constraint failed: users.org_id, users.username (2067)"),
+			wantField: "username",
+			wantDup:   true,
+		},
+		{
+			name:      "mysql",
+			err:       errors.New("Error 1062 (23000): Duplicate entry 'a@example.com' for key 'users.idx_users_email'"),
+			wantField: "email",
+			wantDup:   true,
+		},
+		{
+			name:      "postgres",
+			err:       errors.New(`ERROR: duplicate key value violates unique constraint "idx_users_username" (SQLSTATE 23505)`),
+			wantField: "username",
+			wantDup:   true,
+		},
+		{
+			name:    "gorm translated",
+			err:     gorm.ErrDuplicatedKey,
+			wantDup: true,
+		},
+		{
+			name: "other error",
+			err:  errors.New("connection refused"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := translateError("users", tt.err)
+			if got := errors.Is(err, ErrDuplicateKey); got != tt.wantDup {
+				t.Fatalf("errors.Is(ErrDuplicateKey) = %v, want %v", got, tt.wantDup)
+			}
+			if !tt.wantDup {
+				return
+			}
+			var dup *DuplicateKeyError
+			if !errors.As(err, &dup) {
+				t.Fatalf("expected *DuplicateKeyError, got %T", err)
+			}
+			if dup.Field != tt.wantField {
+				t.Fatalf("expected field %q, got %q", tt.wantField, dup.Field)
+			}
+		})
+	}
+
+	if err := translateError("users", gorm.ErrRecordNotFound); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestIsTxConflict(t *testing.T) {
+	for msg, want := range map[string]bool{
+		"database is locked (5) (SQLITE_BUSY)":                               true,
+		"Error 1213 (40001): Deadlock found when trying to get lock":         true,
+		"ERROR: could not serialize access (SQLSTATE 40001)":                 true,
+		"constraint failed: UNIQUE constraint failed: users.username (2067)": false,
+	} {
+		if got := IsTxConflict(errors.New(msg)); got != want {
+			t.Errorf("%q: expected %v, got %v", msg, want, got)
+		}
+	}
+	if IsTxConflict(nil) {
+		t.Error("expected nil not to be a conflict")
+	}
+}
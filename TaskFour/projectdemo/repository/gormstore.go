@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"projectdemo/models"
+
+	"gorm.io/gorm"
+)
+
+type gormStore struct {
+	db *gorm.DB
+}
+
+func NewGormStore(db *gorm.DB) Store {
+	return &gormStore{db: db}
+}
+
+func (s *gormStore) Users() UserRepository {
+	return &gormUserRepository{db: s.db}
+}
+
+func (s *gormStore) Audit() AuditRepository {
+	return &gormAuditRepository{db: s.db}
+}
+
+func (s *gormStore) Outbox() OutboxRepository {
+	return &gormOutboxRepository{db: s.db}
+}
+
+func (s *gormStore) Transaction(ctx context.Context, fn func(tx Store) error) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&gormStore{db: tx})
+	})
+}
+
+type gormUserRepository struct {
+	db *gorm.DB
+}
+
+func NewGormUserRepository(db *gorm.DB) UserRepository {
+	return &gormUserRepository{db: db}
+}
+
+func (r *gormUserRepository) FindByID(ctx context.Context, id uint) (*models.User, error) {
+	return r.first(ctx, "id = ?", id)
+}
+
+func (r *gormUserRepository) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	return r.first(ctx, "username = ?", username)
+}
+
+func (r *gormUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.first(ctx, "email = ?", email)
+}
+
+func (r *gormUserRepository) List(ctx context.Context, offset, limit int) ([]models.User, int64, error) {
+	db := r.db.WithContext(ctx).Model(&models.User{})
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	users := make([]models.User, 0)
+	if err := db.Order("id ASC").Offset(offset).Limit(limit).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+func (r *gormUserRepository) Create(ctx context.Context, user *models.User) error {
+	return translateError("users", r.db.WithContext(ctx).Create(user).Error)
+}
+
+func (r *gormUserRepository) Save(ctx context.Context, user *models.User) error {
+	return translateError("users", r.db.WithContext(ctx).Save(user).Error)
+}
+
+func (r *gormUserRepository) Update(ctx context.Context, id uint, fields map[string]interface{}) error {
+	result := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Updates(fields)
+	if result.Error != nil {
+		return translateError("users", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormUserRepository) Delete(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Delete(user).Error
+}
+
+func (r *gormUserRepository) first(ctx context.Context, query string, args ...interface{}) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).Where(query, args...).First(&user).Error; err != nil {
+		return nil, translateError("users", err)
+	}
+	return &user, nil
+}
+
+type gormAuditRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormAuditRepository) Create(ctx context.Context, event *models.AuditEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+type gormOutboxRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormOutboxRepository) Create(ctx context.Context, event *models.OutboxEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
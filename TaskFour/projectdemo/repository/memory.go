@@ -0,0 +1,275 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"projectdemo/models"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// MemoryStore 内存实现，主要用于单元测试。
+// 事务通过快照实现：回调返回错误时恢复到事务开始前的数据；同一时间只允许一个事务执行
+type MemoryStore struct {
+	txMu sync.Mutex
+	mu   sync.RWMutex
+	data *memoryData
+}
+
+type memoryData struct {
+	users      map[uint]models.User
+	nextUserID uint
+	audit      []models.AuditEvent
+	outbox     []models.OutboxEvent
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{data: &memoryData{users: map[uint]models.User{}, nextUserID: 1}}
+}
+
+func (s *MemoryStore) Users() UserRepository {
+	return &memoryUserRepository{store: s}
+}
+
+func (s *MemoryStore) Audit() AuditRepository {
+	return &memoryAuditRepository{store: s}
+}
+
+func (s *MemoryStore) Outbox() OutboxRepository {
+	return &memoryOutboxRepository{store: s}
+}
+
+func (s *MemoryStore) Transaction(ctx context.Context, fn func(tx Store) error) error {
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+
+	s.mu.RLock()
+	snapshot := s.data.clone()
+	s.mu.RUnlock()
+
+	if err := fn(memoryTx{s}); err != nil {
+		s.mu.Lock()
+		s.data = snapshot
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// AuditEvents 返回已写入的审计事件，供测试断言
+func (s *MemoryStore) AuditEvents() []models.AuditEvent {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]models.AuditEvent(nil), s.data.audit...)
+}
+
+// OutboxEvents 返回已写入的 outbox 事件，供测试断言
+func (s *MemoryStore) OutboxEvents() []models.OutboxEvent {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]models.OutboxEvent(nil), s.data.outbox...)
+}
+
+func (d *memoryData) clone() *memoryData {
+	c := &memoryData{
+		users:      make(map[uint]models.User, len(d.users)),
+		nextUserID: d.nextUserID,
+		audit:      append([]models.AuditEvent(nil), d.audit...),
+		outbox:     append([]models.OutboxEvent(nil), d.outbox...),
+	}
+	for id, u := range d.users {
+		c.users[id] = u
+	}
+	return c
+}
+
+// memoryTx 事务内的 Store，嵌套事务直接在外层事务中执行
+type memoryTx struct {
+	*MemoryStore
+}
+
+func (t memoryTx) Transaction(ctx context.Context, fn func(tx Store) error) error {
+	return fn(t)
+}
+
+type memoryUserRepository struct {
+	store *MemoryStore
+}
+
+func (r *memoryUserRepository) FindByID(ctx context.Context, id uint) (*models.User, error) {
+	return r.find(func(u *models.User) bool { return u.ID == id })
+}
+
+func (r *memoryUserRepository) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	return r.find(func(u *models.User) bool { return u.Username == username })
+}
+
+func (r *memoryUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.find(func(u *models.User) bool { return u.Email == email })
+}
+
+func (r *memoryUserRepository) List(ctx context.Context, offset, limit int) ([]models.User, int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	users := make([]models.User, 0, len(r.store.data.users))
+	for _, u := range r.store.data.users {
+		if !u.DeletedAt.Valid {
+			users = append(users, u)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+
+	total := int64(len(users))
+	if offset >= len(users) {
+		return []models.User{}, total, nil
+	}
+	users = users[offset:]
+	if limit >= 0 && limit < len(users) {
+		users = users[:limit]
+	}
+	return users, total, nil
+}
+
+func (r *memoryUserRepository) Create(ctx context.Context, user *models.User) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	d := r.store.data
+	if err := d.checkUnique(user); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	user.ID = d.nextUserID
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	d.nextUserID++
+	d.users[user.ID] = *user
+	return nil
+}
+
+func (r *memoryUserRepository) Save(ctx context.Context, user *models.User) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	d := r.store.data
+	if _, ok := d.users[user.ID]; !ok {
+		return ErrNotFound
+	}
+	if err := d.checkUnique(user); err != nil {
+		return err
+	}
+	user.UpdatedAt = time.Now()
+	d.users[user.ID] = *user
+	return nil
+}
+
+func (r *memoryUserRepository) Update(ctx context.Context, id uint, fields map[string]interface{}) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	d := r.store.data
+	user, ok := d.users[id]
+	if !ok || user.DeletedAt.Valid {
+		return ErrNotFound
+	}
+	// 按 GORM 的命名规则把列名对应到字段
+	sch, err := schema.Parse(&user, &userSchemaCache, schema.NamingStrategy{})
+	if err != nil {
+		return err
+	}
+	for column, value := range fields {
+		field := sch.LookUpField(column)
+		if field == nil {
+			return fmt.Errorf("unknown column %s", column)
+		}
+		if err := field.Set(ctx, reflect.ValueOf(&user), value); err != nil {
+			return err
+		}
+	}
+	if err := d.checkUnique(&user); err != nil {
+		return err
+	}
+	user.UpdatedAt = time.Now()
+	d.users[id] = user
+	return nil
+}
+
+var userSchemaCache sync.Map
+
+func (r *memoryUserRepository) Delete(ctx context.Context, user *models.User) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.data.users[user.ID]
+	if !ok || stored.DeletedAt.Valid {
+		return ErrNotFound
+	}
+	stored.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	r.store.data.users[user.ID] = stored
+	return nil
+}
+
+// find 与 GORM 的软删除语义一致，已删除的用户查询不到
+func (r *memoryUserRepository) find(match func(u *models.User) bool) (*models.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, u := range r.store.data.users {
+		if !u.DeletedAt.Valid && match(&u) {
+			found := u
+			return &found, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// checkUnique 模拟数据库唯一索引，软删除的记录同样占用用户名和邮箱
+func (d *memoryData) checkUnique(user *models.User) error {
+	for id, u := range d.users {
+		if id == user.ID {
+			continue
+		}
+		if u.Username == user.Username {
+			return &DuplicateKeyError{Field: "username"}
+		}
+		if u.Email == user.Email {
+			return &DuplicateKeyError{Field: "email"}
+		}
+	}
+	return nil
+}
+
+type memoryAuditRepository struct {
+	store *MemoryStore
+}
+
+func (r *memoryAuditRepository) Create(ctx context.Context, event *models.AuditEvent) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	event.ID = uint(len(r.store.data.audit) + 1)
+	event.CreatedAt = time.Now()
+	r.store.data.audit = append(r.store.data.audit, *event)
+	return nil
+}
+
+type memoryOutboxRepository struct {
+	store *MemoryStore
+}
+
+func (r *memoryOutboxRepository) Create(ctx context.Context, event *models.OutboxEvent) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	event.ID = uint(len(r.store.data.outbox) + 1)
+	event.CreatedAt = time.Now()
+	r.store.data.outbox = append(r.store.data.outbox, *event)
+	return nil
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"projectdemo/models"
+
+	"gorm.io/gorm"
+)
+
+func NewProductRepository(db *gorm.DB) *Repository[models.Product] {
+	return New[models.Product](db, Options{
+		Filterable: map[string]string{
+			"sku":    "sku",
+			"status": "status",
+		},
+		Sortable: map[string]string{
+			"id":         "id",
+			"name":       "name",
+			"price":      "price",
+			"created_at": "created_at",
+		},
+	})
+}
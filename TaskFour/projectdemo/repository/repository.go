@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// Owned 由带有所有者的模型实现，CRUDHandler 据此做归属校验
+type Owned interface {
+	GetOwnerID() uint
+	SetOwnerID(id uint)
+}
+
+// Options 配置允许客户端过滤和排序的字段，key 为查询参数名，value 为数据库列名
+type Options struct {
+	Filterable  map[string]string
+	Sortable    map[string]string
+	DefaultSort string
+}
+
+type ListQuery struct {
+	Page     int
+	PageSize int
+	// Sort 形如 "-price,name"，前缀 "-" 表示降序
+	Sort    string
+	Filters map[string]string
+	// OwnerID 不为 0 时只返回该用户拥有的记录
+	OwnerID uint
+}
+
+type Page[T any] struct {
+	Items    []T   `json:"items"`
+	Total    int64 `json:"total"`
+	Page     int   `json:"page"`
+	PageSize int   `json:"page_size"`
+}
+
+// Repository 基于 GORM 的通用仓储，T 为 GORM 模型类型
+type Repository[T any] struct {
+	db    *gorm.DB
+	opts  Options
+	table string
+}
+
+func New[T any](db *gorm.DB, opts Options) *Repository[T] {
+	if opts.DefaultSort == "" {
+		opts.DefaultSort = "id DESC"
+	}
+
+	// 解析表名，用于从唯一约束错误中识别冲突字段
+	stmt := &gorm.Statement{DB: db}
+	var table string
+	if err := stmt.Parse(new(T)); err == nil {
+		table = stmt.Schema.Table
+	}
+	return &Repository[T]{db: db, opts: opts, table: table}
+}
+
+// DB 返回带 context 的查询句柄，供需要自定义查询的调用方使用
+func (r *Repository[T]) DB(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx)
+}
+
+func (r *Repository[T]) List(ctx context.Context, q ListQuery) (*Page[T], error) {
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PageSize < 1 {
+		q.PageSize = DefaultPageSize
+	}
+	if q.PageSize > MaxPageSize {
+		q.PageSize = MaxPageSize
+	}
+
+	db := r.db.WithContext(ctx).Model(new(T))
+	for key, value := range q.Filters {
+		column, ok := r.opts.Filterable[key]
+		if !ok {
+			continue
+		}
+		db = db.Where(column+" = ?", value)
+	}
+	if q.OwnerID != 0 {
+		db = db.Where("owner_id = ?", q.OwnerID)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	items := make([]T, 0)
+	err := db.Order(r.orderBy(q.Sort)).
+		Offset((q.Page - 1) * q.PageSize).
+		Limit(q.PageSize).
+		Find(&items).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &Page[T]{Items: items, Total: total, Page: q.Page, PageSize: q.PageSize}, nil
+}
+
+func (r *Repository[T]) Get(ctx context.Context, id uint) (*T, error) {
+	item := new(T)
+	if err := r.db.WithContext(ctx).First(item, id).Error; err != nil {
+		return nil, translateError(r.table, err)
+	}
+	return item, nil
+}
+
+func (r *Repository[T]) Create(ctx context.Context, item *T) error {
+	return translateError(r.table, r.db.WithContext(ctx).Create(item).Error)
+}
+
+// Update 保存整条记录（包括零值字段）
+func (r *Repository[T]) Update(ctx context.Context, item *T) error {
+	return translateError(r.table, r.db.WithContext(ctx).Save(item).Error)
+}
+
+// Delete 对带有 gorm.DeletedAt 字段的模型执行软删除
+func (r *Repository[T]) Delete(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(new(T), id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// orderBy 只接受 Sortable 中声明的字段，防止 SQL 注入
+func (r *Repository[T]) orderBy(sort string) string {
+	var clauses []string
+	for _, field := range strings.Split(sort, ",") {
+		field = strings.TrimSpace(field)
+		direction := "ASC"
+		if strings.HasPrefix(field, "-") {
+			direction = "DESC"
+			field = field[1:]
+		}
+		column, ok := r.opts.Sortable[field]
+		if !ok {
+			continue
+		}
+		clauses = append(clauses, column+" "+direction)
+	}
+	if len(clauses) == 0 {
+		return r.opts.DefaultSort
+	}
+	return strings.Join(clauses, ", ")
+}
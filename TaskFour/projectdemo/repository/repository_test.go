@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type item struct {
+	ID        uint `gorm:"primaryKey"`
+	OwnerID   uint
+	Name      string
+	Color     string
+	Secret    string
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func newItemRepo(t *testing.T, n int) *Repository[item] {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "items.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err := db.AutoMigrate(&item{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	repo := New[item](db, Options{
+		Filterable: map[string]string{"color": "color"},
+		Sortable:   map[string]string{"id": "id", "name": "name"},
+	})
+	colors := []string{"red", "blue"}
+	for i := 1; i <= n; i++ {
+		it := item{OwnerID: uint(i%2 + 1), Name: fmt.Sprintf("item-%03d", n-i), Color: colors[i%2], Secret: fmt.Sprint(i)}
+		if err := repo.Create(context.Background(), &it); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+	return repo
+}
+
+func TestRepositoryListPaging(t *testing.T) {
+	repo := newItemRepo(t, 150)
+	cases := []struct {
+		name               string
+		page, pageSize     int
+		wantPage, wantSize int
+		wantItems          int
+	}{
+		{"defaults", 0, 0, 1, DefaultPageSize, DefaultPageSize},
+		{"negative", -3, -1, 1, DefaultPageSize, DefaultPageSize},
+		{"capped page size", 1, 1000, 1, MaxPageSize, MaxPageSize},
+		{"last page", 2, MaxPageSize, 2, MaxPageSize, 50},
+		{"past the end", 9, 50, 9, 50, 0},
+	}
+	for _, tc := range cases {
+		page, err := repo.List(context.Background(), ListQuery{Page: tc.page, PageSize: tc.pageSize})
+		if err != nil {
+			t.Fatalf("%s: list: %v", tc.name, err)
+		}
+		if page.Page != tc.wantPage || page.PageSize != tc.wantSize || len(page.Items) != tc.wantItems || page.Total != 150 {
+			t.Errorf("%s: unexpected page %d size %d with %d of %d items", tc.name, page.Page, page.PageSize, len(page.Items), page.Total)
+		}
+	}
+}
+
+func TestRepositoryListWhitelist(t *testing.T) {
+	repo := newItemRepo(t, 10)
+	ctx := context.Background()
+	cases := []struct {
+		name    string
+		q       ListQuery
+		total   int64
+		firstID uint
+	}{
+		{"default sort", ListQuery{}, 10, 10},
+		{"sort ascending", ListQuery{Sort: "id"}, 10, 1},
+		{"sort by name", ListQuery{Sort: "name"}, 10, 10},
+		{"sort descending", ListQuery{Sort: "-name,id"}, 10, 1},
+		// 未声明的字段被忽略，不会拼进 SQL
+		{"unlisted sort", ListQuery{Sort: "secret"}, 10, 10},
+		{"injected sort", ListQuery{Sort: "id; DROP TABLE items"}, 10, 10},
+		{"filter", ListQuery{Filters: map[string]string{"color": "red"}}, 5, 10},
+		{"unlisted filter", ListQuery{Filters: map[string]string{"secret": "1", "name": "item-009"}}, 10, 10},
+		{"owner", ListQuery{OwnerID: 2}, 5, 9},
+	}
+	for _, tc := range cases {
+		page, err := repo.List(ctx, tc.q)
+		if err != nil {
+			t.Fatalf("%s: list: %v", tc.name, err)
+		}
+		if page.Total != tc.total || len(page.Items) == 0 || page.Items[0].ID != tc.firstID {
+			t.Errorf("%s: expected %d items starting at %d, got %+v", tc.name, tc.total, tc.firstID, page)
+		}
+	}
+	if _, err := repo.Get(ctx, 1); err != nil {
+		t.Fatalf("expected the table to survive, got %v", err)
+	}
+}
+
+func TestRepositorySoftDelete(t *testing.T) {
+	repo := newItemRepo(t, 3)
+	ctx := context.Background()
+
+	if err := repo.Delete(ctx, 2); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := repo.Get(ctx, 2); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected deleted item to be hidden, got %v", err)
+	}
+	if err := repo.Delete(ctx, 2); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected second delete to report not found, got %v", err)
+	}
+	if err := repo.Delete(ctx, 99); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected missing item to report not found, got %v", err)
+	}
+	page, err := repo.List(ctx, ListQuery{})
+	if err != nil || page.Total != 2 {
+		t.Fatalf("expected 2 items after delete, got %+v (%v)", page, err)
+	}
+
+	var deleted item
+	if err := repo.DB(ctx).Unscoped().First(&deleted, 2).Error; err != nil || !deleted.DeletedAt.Valid {
+		t.Fatalf("expected the row to be kept with deleted_at set, got %+v (%v)", deleted, err)
+	}
+}
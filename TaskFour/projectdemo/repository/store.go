@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+	"projectdemo/models"
+)
+
+// UserRepository 用户的持久化操作，查询不到时返回 ErrNotFound，
+// 违反唯一约束时返回 *DuplicateKeyError
+type UserRepository interface {
+	FindByID(ctx context.Context, id uint) (*models.User, error)
+	FindByUsername(ctx context.Context, username string) (*models.User, error)
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	// List 按 ID 升序分页返回未删除的用户及总数
+	List(ctx context.Context, offset, limit int) ([]models.User, int64, error)
+	Create(ctx context.Context, user *models.User) error
+	Save(ctx context.Context, user *models.User) error
+	// Update 只更新 fields 中的列（key 为列名），不会覆盖其他请求同时修改的字段；
+	// 用户不存在时返回 ErrNotFound
+	Update(ctx context.Context, id uint, fields map[string]interface{}) error
+	// Delete 软删除
+	Delete(ctx context.Context, user *models.User) error
+}
+
+type AuditRepository interface {
+	Create(ctx context.Context, event *models.AuditEvent) error
+}
+
+// OutboxRepository 待分发的事件，必须与产生事件的业务写入在同一事务中创建
+type OutboxRepository interface {
+	Create(ctx context.Context, event *models.OutboxEvent) error
+}
+
+// Store 聚合同一数据源上的各个仓储。Transaction 回调中拿到的 Store 绑定同一个事务，
+// 回调返回错误时事务内的所有写入一起回滚
+type Store interface {
+	Users() UserRepository
+	Audit() AuditRepository
+	Outbox() OutboxRepository
+	Transaction(ctx context.Context, fn func(tx Store) error) error
+}
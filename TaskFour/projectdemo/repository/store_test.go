@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"projectdemo/models"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestUserRepositoryUpdate(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "users.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	stores := map[string]Store{"gorm": NewGormStore(db), "memory": NewMemoryStore()}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			users := store.Users()
+			verified := time.Now()
+			alice := models.User{Username: "alice", Email: "alice@example.com", Password: "hash", Role: models.RoleUser, EmailVerifiedAt: &verified}
+			bob := models.User{Username: "bob", Email: "bob@example.com", Password: "hash", Role: models.RoleUser}
+			for _, u := range []*models.User{&alice, &bob} {
+				if err := users.Create(ctx, u); err != nil {
+					t.Fatalf("create: %v", err)
+				}
+			}
+
+			err := users.Update(ctx, alice.ID, map[string]interface{}{"email": "new@example.com", "email_verified_at": nil, "bio": "hi"})
+			if err != nil {
+				t.Fatalf("update: %v", err)
+			}
+			got, err := users.FindByID(ctx, alice.ID)
+			if err != nil {
+				t.Fatalf("find: %v", err)
+			}
+			if got.Email != "new@example.com" || got.EmailVerifiedAt != nil || got.Bio != "hi" || got.Password != "hash" || got.Username != "alice" {
+				t.Fatalf("unexpected user %+v", got)
+			}
+
+			var dup *DuplicateKeyError
+			if err := users.Update(ctx, bob.ID, map[string]interface{}{"email": "new@example.com"}); !errors.As(err, &dup) || dup.Field != "email" {
+				t.Fatalf("expected duplicate email, got %v", err)
+			}
+			if err := users.Update(ctx, 99, map[string]interface{}{"role": models.RoleAdmin}); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("expected ErrNotFound, got %v", err)
+			}
+		})
+	}
+}
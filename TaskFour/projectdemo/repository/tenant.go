@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"projectdemo/utils"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// TenantOwned 由属于某个组织的模型实现，模型需要有 org_id 列
+type TenantOwned interface {
+	GetOrgID() uint
+	SetOrgID(id uint)
+}
+
+var tenantOwnedType = reflect.TypeOf((*TenantOwned)(nil)).Elem()
+
+// TenantPlugin 按 context 中的租户（utils.WithTenant）自动限定 TenantOwned 模型：
+// 查询、更新、删除追加 org_id 条件，创建时写入 org_id。
+// context 中没有租户时不做限制；Raw/Exec 执行的 SQL 不经过模型，同样不受限制
+type TenantPlugin struct{}
+
+func (TenantPlugin) Name() string {
+	return "projectdemo:tenant"
+}
+
+func (TenantPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Create().Before("gorm:create").Register("tenant:create", assignTenant); err != nil {
+		return err
+	}
+	if err := cb.Query().Before("gorm:query").Register("tenant:query", scopeTenant); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("tenant:update", scopeTenant); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("tenant:delete", scopeTenant); err != nil {
+		return err
+	}
+	return cb.Row().Before("gorm:row").Register("tenant:row", scopeTenant)
+}
+
+// tenantField 返回当前语句需要限定的租户和 org_id 字段，ok 为 false 时不需要处理
+func tenantField(db *gorm.DB) (orgID uint, field *schema.Field, ok bool) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return 0, nil, false
+	}
+	orgID, ok = utils.TenantFrom(db.Statement.Context)
+	if !ok || !reflect.PointerTo(db.Statement.Schema.ModelType).Implements(tenantOwnedType) {
+		return 0, nil, false
+	}
+	field = db.Statement.Schema.LookUpField("org_id")
+	return orgID, field, field != nil
+}
+
+func scopeTenant(db *gorm.DB) {
+	orgID, field, ok := tenantField(db)
+	if !ok {
+		return
+	}
+	// Count 之后再 Find 会复用同一个 Statement，避免重复追加条件
+	if _, done := db.InstanceGet("tenant:scoped"); done {
+		return
+	}
+	db.InstanceSet("tenant:scoped", true)
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: orgID},
+	}})
+}
+
+// assignTenant 创建时总是使用 context 中的租户，忽略调用方设置的值
+func assignTenant(db *gorm.DB) {
+	orgID, field, ok := tenantField(db)
+	if !ok {
+		return
+	}
+	set := func(rv reflect.Value) {
+		if err := field.Set(db.Statement.Context, rv, orgID); err != nil {
+			_ = db.AddError(err)
+		}
+	}
+	rv := db.Statement.ReflectValue
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			set(reflect.Indirect(rv.Index(i)))
+		}
+	case reflect.Struct:
+		set(rv)
+	}
+}
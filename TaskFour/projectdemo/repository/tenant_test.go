@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"projectdemo/models"
+	"projectdemo/utils"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTenantDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "tenant.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err := db.Use(TenantPlugin{}); err != nil {
+		t.Fatalf("register plugin: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Product{}, &models.Membership{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+func TestTenantPlugin(t *testing.T) {
+	db := newTenantDB(t)
+	repo := NewProductRepository(db)
+	org1 := utils.WithTenant(context.Background(), 1)
+	org2 := utils.WithTenant(context.Background(), 2)
+
+	// 调用方设置的 OrgID 会被 context 中的租户覆盖
+	p1 := models.Product{OrgID: 2, OwnerID: 1, SKU: "SKU-1", Name: "one", Status: models.ProductStatusActive}
+	if err := repo.Create(org1, &p1); err != nil {
+		t.Fatalf("create in org 1: %v", err)
+	}
+	if p1.OrgID != 1 {
+		t.Fatalf("expected org_id 1, got %d", p1.OrgID)
+	}
+	// SKU 只在组织内唯一
+	p2 := models.Product{OwnerID: 2, SKU: "SKU-1", Name: "two", Status: models.ProductStatusActive}
+	if err := repo.Create(org2, &p2); err != nil {
+		t.Fatalf("create same sku in org 2: %v", err)
+	}
+	var dupErr *DuplicateKeyError
+	dup := models.Product{OwnerID: 1, SKU: "SKU-1", Name: "dup"}
+	if err := repo.Create(org1, &dup); !errors.As(err, &dupErr) || dupErr.Field != "sku" {
+		t.Fatalf("expected duplicate key on sku in org 1, got %v", err)
+	}
+
+	page, err := repo.List(org1, ListQuery{})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if page.Total != 1 || len(page.Items) != 1 || page.Items[0].ID != p1.ID {
+		t.Fatalf("expected only org 1 product, got total %d", page.Total)
+	}
+
+	if _, err := repo.Get(org1, p2.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected cross-tenant get to be not found, got %v", err)
+	}
+	if err := repo.Delete(org1, p2.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected cross-tenant delete to be not found, got %v", err)
+	}
+	result := db.WithContext(org1).Model(&models.Product{}).Where("id = ?", p2.ID).Update("name", "hijacked")
+	if result.Error != nil || result.RowsAffected != 0 {
+		t.Fatalf("expected cross-tenant update to affect no rows, got %d (%v)", result.RowsAffected, result.Error)
+	}
+
+	// 没有租户的 context 不受限制
+	var total int64
+	if err := db.WithContext(context.Background()).Model(&models.Product{}).Count(&total).Error; err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if total != 2 {
+		t.Fatalf("expected 2 products without tenant, got %d", total)
+	}
+	reloaded, err := repo.Get(context.Background(), p2.ID)
+	if err != nil || reloaded.Name != "two" {
+		t.Fatalf("expected org 2 product unchanged, got %+v (%v)", reloaded, err)
+	}
+}
+
+func TestTenantPluginIgnoresOtherModels(t *testing.T) {
+	db := newTenantDB(t)
+
+	// Membership 有 org_id 列但没有实现 TenantOwned，不应被限定
+	if err := db.Create(&models.Membership{OrgID: 2, UserID: 1, Role: models.OrgRoleMember}).Error; err != nil {
+		t.Fatalf("create membership: %v", err)
+	}
+	var memberships []models.Membership
+	if err := db.WithContext(utils.WithTenant(context.Background(), 1)).Find(&memberships).Error; err != nil {
+		t.Fatalf("find: %v", err)
+	}
+	if len(memberships) != 1 {
+		t.Fatalf("expected membership to be visible from another tenant, got %d", len(memberships))
+	}
+}
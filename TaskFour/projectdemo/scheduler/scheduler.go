@@ -0,0 +1,246 @@
+// Package scheduler 按 cron 表达式周期执行已注册的任务。每个任务在数据库中有一行调度状态，
+// 执行前通过条件更新获取租约，多个副本同时运行时每次只有一个副本执行，同一任务也不会重叠执行
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"projectdemo/config"
+	"projectdemo/internal/background"
+	"projectdemo/models"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultTimeout = 5 * time.Minute
+)
+
+// Task 一个定时任务。Run 收到的 context 在 Timeout 后取消
+type Task struct {
+	Name     string
+	Schedule string
+	Timeout  time.Duration
+	Run      func(ctx context.Context) error
+}
+
+type task struct {
+	Task
+	schedule *Schedule
+}
+
+// Scheduler 零值不可用，使用 New 创建
+type Scheduler struct {
+	db    *gorm.DB
+	cfg   config.SchedulerConfig
+	owner string
+	now   func() time.Time
+
+	mu      sync.Mutex
+	tasks   []*task
+	synced  bool
+	running map[string]bool
+	wg      sync.WaitGroup
+}
+
+func New(db *gorm.DB, cfg config.SchedulerConfig) *Scheduler {
+	return &Scheduler{
+		db:      db,
+		cfg:     cfg,
+		owner:   background.NewOwnerID(),
+		now:     time.Now,
+		running: make(map[string]bool),
+	}
+}
+
+// Register 注册任务，表达式无效、永远不会满足或名称重复时返回错误
+func (s *Scheduler) Register(t Task) error {
+	schedule, err := Parse(t.Schedule)
+	if err != nil {
+		return err
+	}
+	if schedule.Next(s.now()).IsZero() {
+		return fmt.Errorf("scheduler: task %q: schedule %q never matches", t.Name, t.Schedule)
+	}
+	if t.Timeout <= 0 {
+		t.Timeout = DefaultTimeout
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, other := range s.tasks {
+		if other.Name == t.Name {
+			return fmt.Errorf("scheduler: task %q already registered", t.Name)
+		}
+	}
+	s.tasks = append(s.tasks, &task{Task: t, schedule: schedule})
+	s.synced = false
+	return nil
+}
+
+// Run 每隔 PollInterval 启动到期的任务，ctx 取消后等待正在执行的任务结束再返回
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		if err := s.tick(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("scheduler: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			s.wg.Wait()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue 启动所有到期的任务并等待它们完成
+func (s *Scheduler) RunDue(ctx context.Context) error {
+	err := s.tick(ctx)
+	s.wg.Wait()
+	return err
+}
+
+// tick 为每个到期且本进程内没有在执行的任务获取租约，拿到租约的任务在单独的 goroutine 中执行
+func (s *Scheduler) tick(ctx context.Context) error {
+	if err := s.sync(ctx); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	tasks := append([]*task(nil), s.tasks...)
+	s.mu.Unlock()
+
+	var errs []error
+	for _, t := range tasks {
+		// 表达式不再有下次执行时间的任务视为停用，不能按零值的 next_run_at 每次都执行
+		if t.schedule.Next(s.now()).IsZero() {
+			continue
+		}
+		if !s.markRunning(t.Name) {
+			continue
+		}
+		acquired, err := s.acquire(ctx, t)
+		if err != nil || !acquired {
+			s.clearRunning(t.Name)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("acquire %s: %w", t.Name, err))
+			}
+			continue
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer s.clearRunning(t.Name)
+			if err := s.execute(ctx, t); err != nil {
+				log.Printf("scheduler: %s: %v", t.Name, err)
+			}
+		}()
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Scheduler) markRunning(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running[name] {
+		return false
+	}
+	s.running[name] = true
+	return true
+}
+
+func (s *Scheduler) clearRunning(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, name)
+}
+
+// sync 为新注册的任务创建调度状态；表达式变化时重新计算下次执行时间
+func (s *Scheduler) sync(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.synced {
+		return nil
+	}
+
+	now := s.now()
+	for _, t := range s.tasks {
+		next := t.schedule.Next(now)
+		if next.IsZero() {
+			log.Printf("scheduler: %s: schedule %q never matches, task disabled", t.Name, t.Task.Schedule)
+			continue
+		}
+		row := models.ScheduledTask{Name: t.Name, Schedule: t.Task.Schedule, NextRunAt: next}
+		result := s.db.WithContext(ctx).Where(models.ScheduledTask{Name: t.Name}).FirstOrCreate(&row)
+		if result.Error != nil {
+			return result.Error
+		}
+		if row.Schedule != t.Task.Schedule {
+			err := s.db.WithContext(ctx).Model(&models.ScheduledTask{}).Where("name = ?", t.Name).
+				Updates(map[string]interface{}{"schedule": t.Task.Schedule, "next_run_at": next}).Error
+			if err != nil {
+				return err
+			}
+		}
+	}
+	s.synced = true
+	return nil
+}
+
+// acquire 任务到期且没有有效租约时获取租约，其他副本同时尝试时只有一个更新成功
+func (s *Scheduler) acquire(ctx context.Context, t *task) (bool, error) {
+	now := s.now()
+	result := s.db.WithContext(ctx).Model(&models.ScheduledTask{}).
+		Where("name = ? AND next_run_at <= ? AND (lease_until IS NULL OR lease_until < ?)", t.Name, now, now).
+		Updates(map[string]interface{}{
+			"lease_owner":     s.owner,
+			"lease_until":     now.Add(t.Timeout + background.LeaseMargin),
+			"last_status":     models.TaskStatusRunning,
+			"last_started_at": now,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+func (s *Scheduler) execute(ctx context.Context, t *task) error {
+	started := time.Now()
+	runCtx, cancel := context.WithTimeout(ctx, t.Timeout)
+	err := background.Call(runCtx, t.Run)
+	cancel()
+
+	finished := s.now()
+	updates := map[string]interface{}{
+		"lease_owner":      "",
+		"lease_until":      nil,
+		"last_status":      models.TaskStatusSucceeded,
+		"last_finished_at": finished,
+		"last_duration_ms": time.Since(started).Milliseconds(),
+		"last_error":       "",
+	}
+	if err != nil {
+		updates["last_status"] = models.TaskStatusFailed
+		updates["last_error"] = background.ErrorMessage(err)
+	}
+	// 没有下次执行时间时不写入零值，tick 会跳过该任务
+	if next := t.schedule.Next(finished); !next.IsZero() {
+		updates["next_run_at"] = next
+	}
+
+	// 关闭过程中被取消的任务也要释放租约，否则要等租约过期才能再次执行
+	result := s.db.WithContext(context.WithoutCancel(ctx)).Model(&models.ScheduledTask{}).
+		Where("name = ? AND lease_owner = ?", t.Name, s.owner).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("lease lost before the result was recorded")
+	}
+	return nil
+}
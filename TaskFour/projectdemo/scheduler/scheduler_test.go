@@ -0,0 +1,301 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"projectdemo/config"
+	"projectdemo/internal/background"
+	"projectdemo/models"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var testConfig = config.SchedulerConfig{PollInterval: 5 * time.Millisecond}
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := filepath.Join(t.TempDir(), "scheduler.db") + "?_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("get generic db: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = sqlDB.Close()
+	})
+	if err := db.AutoMigrate(&models.ScheduledTask{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+// testClock 可以手动推进的时钟，多个 Scheduler 共用一个时钟模拟多个副本
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestScheduler(t *testing.T, db *gorm.DB, clock *testClock, tasks ...Task) *Scheduler {
+	t.Helper()
+	s := New(db, testConfig)
+	s.now = clock.Now
+	for _, task := range tasks {
+		if err := s.Register(task); err != nil {
+			t.Fatalf("register %s: %v", task.Name, err)
+		}
+	}
+	return s
+}
+
+func runDue(t *testing.T, s *Scheduler) {
+	t.Helper()
+	if err := s.RunDue(context.Background()); err != nil {
+		t.Fatalf("run due: %v", err)
+	}
+}
+
+func reload(t *testing.T, db *gorm.DB, name string) models.ScheduledTask {
+	t.Helper()
+	var task models.ScheduledTask
+	if err := db.First(&task, "name = ?", name).Error; err != nil {
+		t.Fatalf("reload task %s: %v", name, err)
+	}
+	return task
+}
+
+func TestSchedulerRunsDueTasksOnSchedule(t *testing.T) {
+	db := newTestDB(t)
+	clock := &testClock{now: time.Date(2024, 1, 31, 10, 17, 0, 0, time.UTC)}
+	var runs int
+	s := newTestScheduler(t, db, clock, Task{Name: "cleanup", Schedule: "*/15 * * * *", Run: func(context.Context) error {
+		runs++
+		return nil
+	}})
+
+	runDue(t, s)
+	if runs != 0 {
+		t.Fatal("expected task not to run before it is due")
+	}
+	if task := reload(t, db, "cleanup"); !task.NextRunAt.Equal(time.Date(2024, 1, 31, 10, 30, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected next run %s", task.NextRunAt)
+	}
+
+	clock.Advance(13 * time.Minute)
+	runDue(t, s)
+	runDue(t, s)
+	if runs != 1 {
+		t.Fatalf("expected one run, got %d", runs)
+	}
+	task := reload(t, db, "cleanup")
+	if task.LastStatus != models.TaskStatusSucceeded || task.LastFinishedAt == nil || task.LeaseOwner != "" || task.LeaseUntil != nil {
+		t.Fatalf("unexpected task state %+v", task)
+	}
+	if !task.NextRunAt.Equal(time.Date(2024, 1, 31, 10, 45, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected next run %s", task.NextRunAt)
+	}
+}
+
+func TestSchedulerRecordsFailuresTimeoutsAndPanics(t *testing.T) {
+	db := newTestDB(t)
+	clock := &testClock{now: time.Now()}
+	s := newTestScheduler(t, db, clock,
+		Task{Name: "failing", Schedule: "@hourly", Run: func(context.Context) error { return errors.New("disk full") }},
+		Task{Name: "slow", Schedule: "@hourly", Timeout: 10 * time.Millisecond, Run: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+		Task{Name: "panicky", Schedule: "@hourly", Run: func(context.Context) error { panic("nil map") }},
+	)
+	runDue(t, s)
+	clock.Advance(time.Hour)
+	runDue(t, s)
+
+	for name, want := range map[string]string{"failing": "disk full", "slow": "context deadline exceeded", "panicky": "panic: nil map"} {
+		task := reload(t, db, name)
+		if task.LastStatus != models.TaskStatusFailed || !strings.Contains(task.LastError, want) || task.LeaseOwner != "" {
+			t.Fatalf("%s: unexpected task state %+v", name, task)
+		}
+		// 失败的任务照常安排下一次执行
+		if !task.NextRunAt.After(clock.Now()) {
+			t.Fatalf("%s: expected next run after now, got %s", name, task.NextRunAt)
+		}
+	}
+}
+
+func TestSchedulerPreventsOverlapAcrossReplicas(t *testing.T) {
+	db := newTestDB(t)
+	clock := &testClock{now: time.Now()}
+	var runs atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	task := Task{Name: "report", Schedule: "* * * * *", Run: func(context.Context) error {
+		if runs.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		return nil
+	}}
+	a := newTestScheduler(t, db, clock, task)
+	b := newTestScheduler(t, db, clock, task)
+	runDue(t, a)
+	runDue(t, b)
+	clock.Advance(time.Minute)
+
+	ctx := context.Background()
+	if err := a.tick(ctx); err != nil {
+		t.Fatalf("tick: %v", err)
+	}
+	<-started
+
+	// 任务执行期间再次到期，本进程和其他副本都不会再启动
+	clock.Advance(5 * time.Minute)
+	if err := a.tick(ctx); err != nil {
+		t.Fatalf("tick: %v", err)
+	}
+	if err := b.tick(ctx); err != nil {
+		t.Fatalf("tick: %v", err)
+	}
+	if got := reload(t, db, "report"); got.LastStatus != models.TaskStatusRunning || got.LeaseOwner != a.owner {
+		t.Fatalf("expected replica a to hold the lease, got %+v", got)
+	}
+	close(release)
+	a.wg.Wait()
+	b.wg.Wait()
+	if n := runs.Load(); n != 1 {
+		t.Fatalf("expected one run while the task was running, got %d", n)
+	}
+
+	// 释放租约后由先拿到租约的副本执行下一次
+	clock.Advance(time.Minute)
+	var wg sync.WaitGroup
+	for _, s := range []*Scheduler{a, b} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runDue(t, s)
+		}()
+	}
+	wg.Wait()
+	if n := runs.Load(); n != 2 {
+		t.Fatalf("expected exactly one more run, got %d", n-1)
+	}
+}
+
+func TestSchedulerReclaimsExpiredLease(t *testing.T) {
+	db := newTestDB(t)
+	clock := &testClock{now: time.Now()}
+	var runs int
+	task := Task{Name: "sync", Schedule: "* * * * *", Timeout: time.Minute, Run: func(context.Context) error {
+		runs++
+		return nil
+	}}
+	crashed := newTestScheduler(t, db, clock, task)
+	runDue(t, crashed)
+	clock.Advance(time.Minute)
+
+	// 拿到租约后进程退出，没有写回结果
+	if acquired, err := crashed.acquire(context.Background(), crashed.tasks[0]); !acquired || err != nil {
+		t.Fatalf("acquire: %v %v", acquired, err)
+	}
+
+	other := newTestScheduler(t, db, clock, task)
+	runDue(t, other)
+	if runs != 0 {
+		t.Fatal("expected the lease to block other replicas")
+	}
+	clock.Advance(time.Minute + background.LeaseMargin + time.Second)
+	runDue(t, other)
+	if runs != 1 {
+		t.Fatalf("expected the expired lease to be reclaimed, got %d runs", runs)
+	}
+
+	// 原进程的结果不能覆盖接手者的
+	if err := crashed.execute(context.Background(), crashed.tasks[0]); err == nil {
+		t.Fatal("expected execute with a lost lease to fail")
+	}
+}
+
+func TestSchedulerRegister(t *testing.T) {
+	s := New(newTestDB(t), testConfig)
+	noop := func(context.Context) error { return nil }
+	if err := s.Register(Task{Name: "a", Schedule: "@daily", Run: noop}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if err := s.Register(Task{Name: "a", Schedule: "@hourly", Run: noop}); err == nil {
+		t.Fatal("expected duplicate task name to be rejected")
+	}
+	if err := s.Register(Task{Name: "b", Schedule: "every day", Run: noop}); err == nil {
+		t.Fatal("expected invalid schedule to be rejected")
+	}
+	if err := s.Register(Task{Name: "c", Schedule: "0 0 30 2 *", Run: noop}); err == nil {
+		t.Fatal("expected a schedule that never matches to be rejected")
+	}
+	if s.tasks[0].Timeout != DefaultTimeout {
+		t.Fatalf("expected default timeout, got %s", s.tasks[0].Timeout)
+	}
+}
+
+func TestSchedulerUpdatesChangedSchedule(t *testing.T) {
+	db := newTestDB(t)
+	clock := &testClock{now: time.Date(2024, 1, 31, 10, 17, 0, 0, time.UTC)}
+	noop := func(context.Context) error { return nil }
+	runDue(t, newTestScheduler(t, db, clock, Task{Name: "digest", Schedule: "@daily", Run: noop}))
+	runDue(t, newTestScheduler(t, db, clock, Task{Name: "digest", Schedule: "@hourly", Run: noop}))
+
+	task := reload(t, db, "digest")
+	if task.Schedule != "@hourly" || !task.NextRunAt.Equal(time.Date(2024, 1, 31, 11, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected schedule change to reschedule the task, got %+v", task)
+	}
+}
+
+// 没有下次执行时间的任务不写入零值的 next_run_at，也不会在每次轮询时执行
+func TestSchedulerSkipsScheduleThatNeverMatches(t *testing.T) {
+	db := newTestDB(t)
+	clock := &testClock{now: time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC)}
+	s := newTestScheduler(t, db, clock)
+	schedule, err := Parse("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	var runs atomic.Int32
+	s.tasks = append(s.tasks, &task{Task: Task{Name: "never", Timeout: time.Minute, Run: func(context.Context) error {
+		runs.Add(1)
+		return nil
+	}}, schedule: schedule})
+
+	for i := 0; i < 3; i++ {
+		runDue(t, s)
+		clock.Advance(time.Hour)
+	}
+	if n := runs.Load(); n != 0 {
+		t.Fatalf("expected the task never to run, got %d runs", n)
+	}
+	var rows int64
+	db.Model(&models.ScheduledTask{}).Where("name = ?", "never").Count(&rows)
+	if rows != 0 {
+		t.Fatalf("expected no schedule row, got %d", rows)
+	}
+}
@@ -0,0 +1,220 @@
+package server
+
+import (
+	"net/http"
+	"projectdemo/cache"
+	"projectdemo/config"
+	"projectdemo/models"
+	"projectdemo/openapi"
+	"projectdemo/repository"
+	"strings"
+)
+
+const (
+	specPath = "/openapi.json"
+	docsPath = "/docs"
+)
+
+var apiInfo = openapi.Info{
+	Title:   "projectdemo API",
+	Version: "1.0.0",
+	Description: "Successful responses are wrapped in {code, message, data}; errors use the ErrorResponse schema. " +
+		"Authenticated endpoints expect an `Authorization: Bearer <token>` header obtained from the login endpoint. " +
+		"Tokens carry an active organization (0 for the personal workspace); products and orders are limited to it. " +
+		"A token for an organization the user no longer belongs to is rejected with 403.",
+}
+
+// apiOperations 每个注册的路由都需要在这里有对应的描述，TestOpenAPIMatchesRoutes 会检查两者是否一致。
+// 请求/响应的 schema 由结构体及其 binding 标签生成，无需手写
+func apiOperations(cfg *config.Config) []openapi.Operation {
+	ops := []openapi.Operation{
+		{Method: http.MethodGet, Path: "/health", ID: "health", Summary: "Health check", Tags: []string{"system"},
+			Response: map[string]string{}},
+		{Method: http.MethodGet, Path: specPath, ID: "getOpenAPISpec", Summary: "OpenAPI document", Tags: []string{"system"},
+			Raw: true, Response: map[string]interface{}{}, Produces: []string{"application/json"}},
+		{Method: http.MethodGet, Path: docsPath, ID: "getDocs", Summary: "Interactive API documentation", Tags: []string{"system"},
+			Raw: true, Produces: []string{"text/html"}},
+
+		// 用户
+		{Method: http.MethodPost, Path: "/api/v1/users/register", ID: "registerUser", Summary: "Register a new user", Tags: []string{"users"},
+			Body: models.CreateUserRequest{}, Response: models.UserResponse{}, Errors: []int{http.StatusConflict}},
+		{Method: http.MethodPost, Path: "/api/v1/users/login", ID: "login", Summary: "Log in and obtain a JWT", Tags: []string{"users"},
+			Body: models.LoginRequest{}, Response: models.LoginResponse{}, Errors: []int{http.StatusUnauthorized, http.StatusForbidden}},
+		{Method: http.MethodPost, Path: "/api/v1/users/refresh", ID: "refreshToken", Summary: "Exchange a valid token for a new one", Tags: []string{"users"},
+			Auth: true, Response: models.LoginResponse{}, Errors: []int{http.StatusForbidden, http.StatusNotFound}},
+		{Method: http.MethodPut, Path: "/api/v1/users/me/organization", ID: "switchOrganization", Summary: "Issue a token for another active organization", Tags: []string{"users"},
+			Description: "org_id 0 switches back to the personal workspace.",
+			Auth:        true, Body: models.SwitchOrganizationRequest{}, Response: models.LoginResponse{},
+			Errors: []int{http.StatusForbidden, http.StatusNotFound}},
+		{Method: http.MethodGet, Path: "/api/v1/users/me", ID: "getProfile", Summary: "Get the current user", Tags: []string{"users"},
+			Auth: true, Response: models.UserResponse{}, Errors: []int{http.StatusNotFound}},
+		{Method: http.MethodPut, Path: "/api/v1/users/me", ID: "updateProfile", Summary: "Update email and profile fields", Tags: []string{"users"},
+			Description: "Omitted profile fields are left unchanged.",
+			Auth:        true, Body: models.UpdateUserRequest{}, Response: models.UserResponse{},
+			Errors: []int{http.StatusNotFound, http.StatusConflict}},
+		{Method: http.MethodPut, Path: "/api/v1/users/me/avatar", ID: "uploadAvatar", Summary: "Upload an avatar image", Tags: []string{"users"},
+			Description: "Accepts JPEG, PNG or GIF. The image is resized to the configured sizes.",
+			Auth:        true, Upload: "avatar", Response: models.UserResponse{},
+			Errors: []int{http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity}},
+		{Method: http.MethodDelete, Path: "/api/v1/users/me", ID: "deleteProfile", Summary: "Delete the current user", Tags: []string{"users"},
+			Auth: true, Errors: []int{http.StatusNotFound}},
+
+		// 商品目录
+		{Method: http.MethodGet, Path: "/api/v1/catalog/products", ID: "listCatalogProducts", Summary: "List active products", Tags: []string{"catalog"},
+			Params: append(pageParams(), queryParam("sku", "Filter by SKU")), Response: repository.Page[models.Product]{}},
+
+		// 库存
+		{Method: http.MethodGet, Path: "/api/v1/products/:id/inventory", ID: "getInventory", Summary: "Get product stock", Tags: []string{"inventory"},
+			Auth: true, Response: models.Inventory{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+		{Method: http.MethodPut, Path: "/api/v1/products/:id/inventory", ID: "setInventory", Summary: "Set product stock", Tags: []string{"inventory"},
+			Description: "Only the product owner or an admin can change stock.",
+			Auth:        true, Body: models.SetInventoryRequest{}, Response: models.Inventory{},
+			Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+
+		// 购物车
+		{Method: http.MethodGet, Path: "/api/v1/cart", ID: "getCart", Summary: "List cart items", Tags: []string{"cart"},
+			Auth: true, Response: []models.CartItem{}},
+		{Method: http.MethodPost, Path: "/api/v1/cart/items", ID: "addCartItem", Summary: "Add a product to the cart", Tags: []string{"cart"},
+			Description: "Adding a product already in the cart increases its quantity.",
+			Auth:        true, Body: models.AddCartItemRequest{}, Response: []models.CartItem{},
+			Errors: []int{http.StatusNotFound, http.StatusConflict}},
+		{Method: http.MethodPut, Path: "/api/v1/cart/items/:product_id", ID: "updateCartItem", Summary: "Change the quantity of a cart item", Tags: []string{"cart"},
+			Auth: true, Body: models.UpdateCartItemRequest{}, Response: []models.CartItem{},
+			Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+		{Method: http.MethodDelete, Path: "/api/v1/cart/items/:product_id", ID: "removeCartItem", Summary: "Remove a cart item", Tags: []string{"cart"},
+			Auth: true, Response: []models.CartItem{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+
+		// 订单
+		{Method: http.MethodPost, Path: "/api/v1/orders", ID: "placeOrder", Summary: "Place an order from the cart", Tags: []string{"orders"},
+			Auth: true, Status: http.StatusCreated, Response: models.Order{}, Errors: []int{http.StatusBadRequest, http.StatusConflict}},
+		{Method: http.MethodGet, Path: "/api/v1/orders", ID: "listOrders", Summary: "List the current user's orders", Tags: []string{"orders"},
+			Auth: true, Response: []models.Order{}},
+		{Method: http.MethodGet, Path: "/api/v1/orders/:id", ID: "getOrder", Summary: "Get an order", Tags: []string{"orders"},
+			Auth: true, Response: models.Order{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+		{Method: http.MethodPost, Path: "/api/v1/orders/:id/pay", ID: "payOrder", Summary: "Pay a pending order", Tags: []string{"orders"},
+			Auth: true, Response: models.Order{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict}},
+		{Method: http.MethodPost, Path: "/api/v1/orders/:id/cancel", ID: "cancelOrder", Summary: "Cancel an order and restore stock", Tags: []string{"orders"},
+			Auth: true, Response: models.Order{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict}},
+
+		// 组织
+		{Method: http.MethodPost, Path: "/api/v1/orgs", ID: "createOrganization", Summary: "Create an organization", Tags: []string{"organizations"},
+			Description: "The creator becomes the owner.",
+			Auth:        true, Body: models.CreateOrganizationRequest{}, Status: http.StatusCreated, Response: models.Organization{}},
+		{Method: http.MethodGet, Path: "/api/v1/orgs", ID: "listOrganizations", Summary: "List the current user's organizations", Tags: []string{"organizations"},
+			Auth: true, Response: []models.OrganizationResponse{}},
+		{Method: http.MethodGet, Path: "/api/v1/orgs/:id/members", ID: "listMembers", Summary: "List organization members", Tags: []string{"organizations"},
+			Auth: true, Response: []models.MemberResponse{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+		{Method: http.MethodDelete, Path: "/api/v1/orgs/:id/members/:user_id", ID: "removeMember", Summary: "Remove a member or leave the organization", Tags: []string{"organizations"},
+			Description: "Owners and admins can remove members; only owners can remove an owner and the last owner cannot be removed.",
+			Auth:        true, Errors: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict}},
+		{Method: http.MethodPost, Path: "/api/v1/orgs/:id/invitations", ID: "createInvitation", Summary: "Invite an email address", Tags: []string{"organizations"},
+			Description: "Requires the owner or admin role. The token is only returned in this response and expires after 7 days.",
+			Auth:        true, Body: models.CreateInvitationRequest{}, Status: http.StatusCreated, Response: models.Invitation{},
+			Errors: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound}},
+		{Method: http.MethodGet, Path: "/api/v1/orgs/:id/invitations", ID: "listInvitations", Summary: "List pending invitations", Tags: []string{"organizations"},
+			Auth: true, Response: []models.Invitation{}, Errors: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound}},
+		{Method: http.MethodDelete, Path: "/api/v1/orgs/:id/invitations/:invitation_id", ID: "revokeInvitation", Summary: "Revoke a pending invitation", Tags: []string{"organizations"},
+			Auth: true, Errors: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound}},
+		{Method: http.MethodPost, Path: "/api/v1/invitations/accept", ID: "acceptInvitation", Summary: "Join an organization with an invitation token", Tags: []string{"organizations"},
+			Description: "The invitation must have been sent to the current user's email.",
+			Auth:        true, Body: models.AcceptInvitationRequest{}, Response: models.Membership{},
+			Errors: []int{http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusGone}},
+
+		// 管理员
+		{Method: http.MethodDelete, Path: "/api/v1/admin/users/:id", ID: "adminDeleteUser", Summary: "Delete a user", Tags: []string{"admin"},
+			Auth: true, Roles: []string{models.RoleAdmin}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+		{Method: http.MethodGet, Path: "/api/v1/admin/audit-events", ID: "listAuditEvents", Summary: "Query audit events", Tags: []string{"admin"},
+			Description: "format=csv or format=jsonl streams every matching event as a download.",
+			Auth:        true, Roles: []string{models.RoleAdmin}, Query: models.AuditQuery{}, Response: []models.AuditEvent{},
+			Produces: []string{"text/csv", "application/x-ndjson"}, Errors: []int{http.StatusBadRequest}},
+		{Method: http.MethodPost, Path: "/api/v1/admin/orders/:id/ship", ID: "shipOrder", Summary: "Mark a paid order as shipped", Tags: []string{"admin"},
+			Auth: true, Roles: []string{models.RoleAdmin}, Response: models.Order{},
+			Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict}},
+
+		// Webhook
+		{Method: http.MethodPost, Path: "/api/v1/admin/webhooks", ID: "createWebhook", Summary: "Subscribe a URL to user events", Tags: []string{"webhooks"},
+			Description: "Deliveries are signed with X-Webhook-Signature: sha256=HMAC-SHA256(secret, timestamp + \".\" + body). An empty events list receives every event.",
+			Auth:        true, Roles: []string{models.RoleAdmin}, Body: models.CreateWebhookRequest{}, Response: models.WebhookSubscription{}, Status: http.StatusCreated},
+		{Method: http.MethodGet, Path: "/api/v1/admin/webhooks", ID: "listWebhooks", Summary: "List webhook subscriptions", Tags: []string{"webhooks"},
+			Auth: true, Roles: []string{models.RoleAdmin}, Response: []models.WebhookSubscription{}},
+		{Method: http.MethodDelete, Path: "/api/v1/admin/webhooks/:id", ID: "deleteWebhook", Summary: "Delete a webhook subscription", Tags: []string{"webhooks"},
+			Auth: true, Roles: []string{models.RoleAdmin}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+		{Method: http.MethodGet, Path: "/api/v1/admin/webhooks/:id/deliveries", ID: "listWebhookDeliveries", Summary: "List recent deliveries with attempt logs", Tags: []string{"webhooks"},
+			Auth: true, Roles: []string{models.RoleAdmin}, Query: models.WebhookDeliveryQuery{}, Response: []models.WebhookDelivery{},
+			Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+		{Method: http.MethodPost, Path: "/api/v1/admin/webhook-deliveries/:id/redeliver", ID: "redeliverWebhook", Summary: "Queue a finished delivery again", Tags: []string{"webhooks"},
+			Auth: true, Roles: []string{models.RoleAdmin}, Response: models.WebhookDelivery{},
+			Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict}},
+
+		// 后台任务
+		{Method: http.MethodGet, Path: "/api/v1/admin/jobs", ID: "listJobs", Summary: "List background jobs, newest first", Tags: []string{"jobs"},
+			Auth: true, Roles: []string{models.RoleAdmin}, Query: models.JobQuery{}, Response: []models.Job{}},
+		{Method: http.MethodGet, Path: "/api/v1/admin/jobs/stats", ID: "getJobStats", Summary: "Count jobs by status", Tags: []string{"jobs"},
+			Auth: true, Roles: []string{models.RoleAdmin}, Response: models.JobStats{}},
+		{Method: http.MethodGet, Path: "/api/v1/admin/jobs/:id", ID: "getJob", Summary: "Get a background job", Tags: []string{"jobs"},
+			Auth: true, Roles: []string{models.RoleAdmin}, Response: models.Job{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+		{Method: http.MethodPost, Path: "/api/v1/admin/jobs/:id/retry", ID: "retryJob", Summary: "Queue a dead job again", Tags: []string{"jobs"},
+			Auth: true, Roles: []string{models.RoleAdmin}, Response: models.Job{},
+			Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict}},
+
+		{Method: http.MethodGet, Path: "/api/v1/admin/scheduler/tasks", ID: "listScheduledTasks", Summary: "List scheduled tasks with their last run status", Tags: []string{"scheduler"},
+			Auth: true, Roles: []string{models.RoleAdmin}, Response: []models.ScheduledTask{}},
+		{Method: http.MethodPost, Path: "/api/v1/admin/scheduler/tasks/:name/run", ID: "runScheduledTask", Summary: "Run a scheduled task on the next poll", Tags: []string{"scheduler"},
+			Auth: true, Roles: []string{models.RoleAdmin}, Response: models.ScheduledTask{}, Errors: []int{http.StatusNotFound}},
+
+		{Method: http.MethodGet, Path: "/api/v1/admin/cache/stats", ID: "getCacheStats", Summary: "Hit and miss counters of each enabled cache", Tags: []string{"cache"},
+			Auth: true, Roles: []string{models.RoleAdmin}, Response: map[string]cache.Stats{}},
+	}
+
+	ops = append(ops, crudOperations[models.Product, models.CreateProductRequest, models.PatchProductRequest](
+		"/api/v1/products", "products", "Product",
+		append(pageParams(), queryParam("sku", "Filter by SKU"), queryParam("status", "Filter by status")))...)
+
+	// 本地存储的公开文件，StaticFS 同时注册 GET 和 HEAD
+	for method, id := range map[string]string{http.MethodGet: "downloadMedia", http.MethodHead: "headMedia"} {
+		ops = append(ops, openapi.Operation{
+			Method: method, Path: cfg.Storage.BaseURL + "/*filepath", ID: id, Summary: "Download a stored file",
+			Tags: []string{"media"}, Raw: true, Produces: []string{"application/octet-stream"}, Errors: []int{http.StatusNotFound},
+		})
+	}
+	return ops
+}
+
+// crudOperations 对应 CRUDHandler.Register 注册的六个接口，类型参数与 CRUDHandler 相同
+func crudOperations[T any, C any, P any](path, tag, name string, listParams []openapi.Parameter) []openapi.Operation {
+	var model T
+	item := path + "/:id"
+	noun := strings.ToLower(name)
+	notFound := []int{http.StatusBadRequest, http.StatusNotFound}
+	return []openapi.Operation{
+		{Method: http.MethodGet, Path: path, ID: "list" + name + "s", Summary: "List " + noun + "s", Tags: []string{tag},
+			Description: "Limited to the active organization. Users other than admins and organization owners/admins only see records they own.",
+			Auth:        true, Params: listParams, Response: repository.Page[T]{}},
+		{Method: http.MethodGet, Path: item, ID: "get" + name, Summary: "Get a " + noun, Tags: []string{tag},
+			Auth: true, Response: model, Errors: notFound},
+		{Method: http.MethodPost, Path: path, ID: "create" + name, Summary: "Create a " + noun, Tags: []string{tag},
+			Auth: true, Body: *new(C), Status: http.StatusCreated, Response: model, Errors: []int{http.StatusConflict}},
+		{Method: http.MethodPut, Path: item, ID: "replace" + name, Summary: "Replace a " + noun, Tags: []string{tag},
+			Auth: true, Body: *new(C), Response: model, Errors: append(notFound, http.StatusConflict)},
+		{Method: http.MethodPatch, Path: item, ID: "patch" + name, Summary: "Partially update a " + noun, Tags: []string{tag},
+			Auth: true, Body: *new(P), Response: model, Errors: append(notFound, http.StatusConflict)},
+		{Method: http.MethodDelete, Path: item, ID: "delete" + name, Summary: "Delete a " + noun, Tags: []string{tag},
+			Auth: true, Errors: notFound},
+	}
+}
+
+func pageParams() []openapi.Parameter {
+	return []openapi.Parameter{
+		{Name: "page", In: "query", Description: "Page number, starting at 1", Schema: &openapi.Schema{Type: "integer", Minimum: floatPtr(1)}},
+		{Name: "page_size", In: "query", Description: "Items per page", Schema: &openapi.Schema{Type: "integer", Minimum: floatPtr(1), Maximum: floatPtr(repository.MaxPageSize)}},
+		{Name: "sort", In: "query", Description: "Comma separated fields, prefix with - for descending, e.g. -price,name", Schema: &openapi.Schema{Type: "string"}},
+	}
+}
+
+func queryParam(name, description string) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "query", Description: description, Schema: &openapi.Schema{Type: "string"}}
+}
+
+func floatPtr(n float64) *float64 {
+	return &n
+}
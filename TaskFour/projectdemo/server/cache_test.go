@@ -0,0 +1,36 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestAdminCacheStats(t *testing.T) {
+	h := newHarness(t)
+	admin := aUser("root").asAdmin().create(t, h)
+	alice := aUser("alice").create(t, h)
+
+	cacheStats := func() map[string]interface{} {
+		t.Helper()
+		w := h.do(http.MethodGet, "/api/v1/admin/cache/stats", nil, admin.Token)
+		expectStatus(t, w, http.StatusOK)
+		return decodeData(t, w)["users"].(map[string]interface{})
+	}
+	before := cacheStats()
+
+	for i := 0; i < 3; i++ {
+		w := h.do(http.MethodGet, "/api/v1/users/me", nil, alice.Token)
+		expectStatus(t, w, http.StatusOK)
+	}
+	after := cacheStats()
+	// Auth 和 /me 各查询一次用户，再加上第二次统计时管理员的 Auth
+	if hits := after["hits"].(float64) - before["hits"].(float64); hits != 6 {
+		t.Fatalf("expected 6 hits from repeated profile reads, got %v", hits)
+	}
+	if misses := after["misses"].(float64) - before["misses"].(float64); misses != 1 {
+		t.Fatalf("expected 1 miss, got %v", misses)
+	}
+
+	w := h.do(http.MethodGet, "/api/v1/admin/cache/stats", nil, alice.Token)
+	expectStatus(t, w, http.StatusForbidden)
+}
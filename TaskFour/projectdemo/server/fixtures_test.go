@@ -0,0 +1,72 @@
+package server
+
+import (
+	"net/http"
+	"projectdemo/models"
+	"testing"
+)
+
+// userFixture 构造测试用户，默认值可通过 with* 方法覆盖
+type userFixture struct {
+	username string
+	email    string
+	password string
+	role     string
+}
+
+type testUser struct {
+	ID       uint
+	Username string
+	Email    string
+	Password string
+	Token    string
+}
+
+func aUser(username string) *userFixture {
+	return &userFixture{
+		username: username,
+		email:    username + "@example.com",
+		password: "secret123",
+		role:     models.RoleUser,
+	}
+}
+
+func (f *userFixture) withEmail(email string) *userFixture {
+	f.email = email
+	return f
+}
+
+func (f *userFixture) asAdmin() *userFixture {
+	f.role = models.RoleAdmin
+	return f
+}
+
+func (f *userFixture) registerRequest() models.CreateUserRequest {
+	return models.CreateUserRequest{Username: f.username, Email: f.email, Password: f.password}
+}
+
+// create 通过 HTTP 接口注册并登录，角色直接写库（接口不允许自行提升权限）
+func (f *userFixture) create(t *testing.T, h *harness) *testUser {
+	t.Helper()
+
+	w := h.do(http.MethodPost, "/api/v1/users/register", f.registerRequest(), "")
+	expectStatus(t, w, http.StatusOK)
+	id := uint(decodeData(t, w)["id"].(float64))
+
+	if f.role != models.RoleUser {
+		if err := h.db.Model(&models.User{}).Where("id = ?", id).Update("role", f.role).Error; err != nil {
+			t.Fatalf("set role: %v", err)
+		}
+	}
+
+	w = h.do(http.MethodPost, "/api/v1/users/login", models.LoginRequest{Username: f.username, Password: f.password}, "")
+	expectStatus(t, w, http.StatusOK)
+
+	return &testUser{
+		ID:       id,
+		Username: f.username,
+		Email:    f.email,
+		Password: f.password,
+		Token:    decodeData(t, w)["token"].(string),
+	}
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"projectdemo/config"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+// harness 持有一个完整的服务实例和它使用的临时数据库
+type harness struct {
+	t      *testing.T
+	cfg    *config.Config
+	db     *gorm.DB
+	engine *gin.Engine
+}
+
+// newTestDB 在测试临时目录中创建 SQLite 数据库并完成迁移，测试结束后自动关闭
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("get generic db: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = sqlDB.Close()
+	})
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+func newHarness(t *testing.T) *harness {
+	t.Helper()
+
+	cfg := config.Load()
+	cfg.Server.Mode = gin.TestMode
+	cfg.Storage.LocalDir = filepath.Join(t.TempDir(), "blobs")
+
+	db := newTestDB(t)
+	engine, err := NewServer(cfg, db)
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	return &harness{t: t, cfg: cfg, db: db, engine: engine}
+}
+
+// do 发送 JSON 请求，token 为空时不携带 Authorization 头
+func (h *harness) do(method, path string, body interface{}, token string) *httptest.ResponseRecorder {
+	h.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			h.t.Fatalf("marshal request body: %v", err)
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return h.serve(req)
+}
+
+func (h *harness) serve(req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.engine.ServeHTTP(w, req)
+	return w
+}
+
+// expectStatus 断言状态码，失败时打印响应体方便排查
+func expectStatus(t *testing.T, w *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if w.Code != want {
+		t.Fatalf("expected status %d, got %d: %s", want, w.Code, w.Body.String())
+	}
+}
+
+// decodeData 解析 utils.Response 信封并返回 data 字段
+func decodeData(t *testing.T, w *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v: %s", err, w.Body.String())
+	}
+	return resp.Data
+}
+
+// volatileFields 每次运行都会变化的字段，比较前统一替换为占位符
+var volatileFields = map[string]string{
+	"created_at": "<time>",
+	"updated_at": "<time>",
+	"token":      "<token>",
+	"request_id": "<request-id>",
+}
+
+func normalize(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, child := range val {
+			if placeholder, ok := volatileFields[k]; ok && child != nil {
+				val[k] = placeholder
+				continue
+			}
+			val[k] = normalize(child)
+		}
+	case []interface{}:
+		for i := range val {
+			val[i] = normalize(val[i])
+		}
+	}
+	return v
+}
+
+// assertGolden 将响应体规范化后与 testdata/<name>.golden.json 比较，
+// 使用 go test ./server -update 重新生成
+func assertGolden(t *testing.T, name string, w *httptest.ResponseRecorder) {
+	t.Helper()
+
+	var body interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode response: %v: %s", err, w.Body.String())
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(normalize(body)); err != nil {
+		t.Fatalf("marshal normalized body: %v", err)
+	}
+	compareGolden(t, name, buf.Bytes())
+}
+
+// compareGolden 将 got 与 testdata/<name>.golden.json 逐字节比较，-update 时改为写入
+func compareGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name+".golden.json")
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatalf("write golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden file (run with -update to create it): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("response does not match %s\n--- got ---\n%s--- want ---\n%s", path, got, want)
+	}
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"projectdemo/jobs"
+	"projectdemo/models"
+	"projectdemo/services"
+	"testing"
+	"time"
+)
+
+func TestAdminJobs(t *testing.T) {
+	h := newHarness(t)
+	admin := aUser("root").asAdmin().create(t, h)
+	alice := aUser("alice").create(t, h)
+	ctx := context.Background()
+
+	// 一个早已过期的邀请和一个仍然有效的邀请
+	orgID := createOrg(t, h, alice.Token, "Acme")
+	invite(t, h, alice.Token, orgID, "old@example.com")
+	invite(t, h, alice.Token, orgID, "new@example.com")
+	expired := time.Now().Add(-services.InvitationRetention - time.Hour)
+	if err := h.db.Model(&models.Invitation{}).Where("email = ?", "old@example.com").Update("expires_at", expired).Error; err != nil {
+		t.Fatalf("expire invitation: %v", err)
+	}
+
+	purge, err := jobs.Enqueue(ctx, h.db, services.JobPurgeInvitations, struct{}{})
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	unknown, err := jobs.Enqueue(ctx, h.db, "unknown", struct{}{}, jobs.Delay(time.Hour))
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+
+	q := jobs.NewQueue(h.db, h.cfg.Jobs)
+	services.RegisterJobs(q, services.NewOrgService(h.db))
+	if worked, err := q.RunOnce(ctx); !worked || err != nil {
+		t.Fatalf("expected purge job to run, got %v (%v)", worked, err)
+	}
+	var remaining int64
+	if err := h.db.Model(&models.Invitation{}).Count(&remaining).Error; err != nil {
+		t.Fatalf("count invitations: %v", err)
+	}
+	if remaining != 1 {
+		t.Fatalf("expected only the valid invitation to remain, got %d", remaining)
+	}
+
+	w := h.do(http.MethodGet, "/api/v1/admin/jobs", nil, alice.Token)
+	expectStatus(t, w, http.StatusForbidden)
+	w = h.do(http.MethodGet, "/api/v1/admin/jobs?status=succeeded", nil, admin.Token)
+	expectStatus(t, w, http.StatusOK)
+	if list := decodeList(t, w); len(list) != 1 || uint(list[0]["id"].(float64)) != purge.ID {
+		t.Fatalf("expected the purge job, got %v", list)
+	}
+	w = h.do(http.MethodGet, "/api/v1/admin/jobs?status=bogus", nil, admin.Token)
+	expectStatus(t, w, http.StatusUnprocessableEntity)
+
+	w = h.do(http.MethodGet, "/api/v1/admin/jobs/stats", nil, admin.Token)
+	expectStatus(t, w, http.StatusOK)
+	if stats := decodeData(t, w); stats["queued"].(float64) != 1 || stats["succeeded"].(float64) != 1 {
+		t.Fatalf("unexpected stats %v", stats)
+	}
+
+	w = h.do(http.MethodPost, fmt.Sprintf("/api/v1/admin/jobs/%d/retry", unknown.ID), nil, admin.Token)
+	expectStatus(t, w, http.StatusConflict)
+	if err := h.db.Model(&models.Job{}).Where("id = ?", unknown.ID).Updates(map[string]interface{}{"status": models.JobStatusDead, "attempts": 5}).Error; err != nil {
+		t.Fatalf("mark job dead: %v", err)
+	}
+	w = h.do(http.MethodPost, fmt.Sprintf("/api/v1/admin/jobs/%d/retry", unknown.ID), nil, admin.Token)
+	expectStatus(t, w, http.StatusOK)
+	if job := decodeData(t, w); job["status"] != models.JobStatusQueued || job["attempts"].(float64) != 0 {
+		t.Fatalf("expected retried job to be queued from scratch, got %v", job)
+	}
+
+	w = h.do(http.MethodGet, "/api/v1/admin/jobs/9999", nil, admin.Token)
+	expectStatus(t, w, http.StatusNotFound)
+}
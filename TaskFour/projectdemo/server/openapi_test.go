@@ -0,0 +1,93 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"projectdemo/openapi"
+	"strings"
+	"testing"
+)
+
+// TestOpenAPIMatchesRoutes 新增或删除路由时必须同步修改 apiOperations
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	h := newHarness(t)
+
+	if problems := openapi.Diff(h.engine.Routes(), apiOperations(h.cfg)); len(problems) > 0 {
+		t.Fatalf("routes and OpenAPI operations have drifted:\n  %s", strings.Join(problems, "\n  "))
+	}
+}
+
+// TestOpenAPIDocument 请求/响应结构体或 binding 标签变化时文档随之变化，
+// 确认变化符合预期后使用 go test ./server -update 更新快照
+func TestOpenAPIDocument(t *testing.T) {
+	h := newHarness(t)
+
+	w := h.do(http.MethodGet, specPath, nil, "")
+	expectStatus(t, w, http.StatusOK)
+
+	var doc openapi.Document
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("decode document: %v", err)
+	}
+	if doc.OpenAPI != "3.1.0" {
+		t.Fatalf("expected OpenAPI 3.1.0, got %q", doc.OpenAPI)
+	}
+
+	// 所有 $ref 都必须指向已生成的组件
+	for _, ref := range findRefs(w.Body.Bytes()) {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Errorf("dangling reference %s", ref)
+		}
+	}
+
+	register := (*doc.Paths["/api/v1/users/register"])["post"]
+	body := register.RequestBody.Content["application/json"].Schema
+	if body.Ref != "#/components/schemas/CreateUserRequest" {
+		t.Fatalf("unexpected register body schema: %+v", body)
+	}
+	username := doc.Components.Schemas["CreateUserRequest"].Properties["username"]
+	if username.MinLength == nil || *username.MinLength != 3 || username.MaxLength == nil || *username.MaxLength != 20 {
+		t.Fatalf("binding constraints not mapped for username: %+v", username)
+	}
+
+	compareGolden(t, "openapi", w.Body.Bytes())
+}
+
+func TestDocsUI(t *testing.T) {
+	h := newHarness(t)
+
+	w := h.do(http.MethodGet, docsPath, nil, "")
+	expectStatus(t, w, http.StatusOK)
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Fatalf("expected HTML, got %q", ct)
+	}
+	if !strings.Contains(w.Body.String(), "openapi.json") {
+		t.Fatalf("docs page does not reference the spec URL")
+	}
+}
+
+func findRefs(body []byte) []string {
+	var refs []string
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch val := v.(type) {
+		case map[string]interface{}:
+			for k, child := range val {
+				if ref, ok := child.(string); ok && k == "$ref" {
+					refs = append(refs, ref)
+					continue
+				}
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range val {
+				walk(child)
+			}
+		}
+	}
+	var v interface{}
+	_ = json.Unmarshal(body, &v)
+	walk(v)
+	return refs
+}
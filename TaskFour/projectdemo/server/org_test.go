@@ -0,0 +1,229 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"projectdemo/models"
+	"testing"
+	"time"
+)
+
+// decodeList 解析 data 为数组的响应
+func decodeList(t *testing.T, w *httptest.ResponseRecorder) []map[string]interface{} {
+	t.Helper()
+	var resp struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v: %s", err, w.Body.String())
+	}
+	return resp.Data
+}
+
+// switchOrg 切换活动组织并返回新 token
+func switchOrg(t *testing.T, h *harness, token string, orgID uint) string {
+	t.Helper()
+	w := h.do(http.MethodPut, "/api/v1/users/me/organization", models.SwitchOrganizationRequest{OrgID: orgID}, token)
+	expectStatus(t, w, http.StatusOK)
+	data := decodeData(t, w)
+	if got := uint(data["org_id"].(float64)); got != orgID {
+		t.Fatalf("expected org_id %d, got %d", orgID, got)
+	}
+	return data["token"].(string)
+}
+
+func createOrg(t *testing.T, h *harness, token, name string) uint {
+	t.Helper()
+	w := h.do(http.MethodPost, "/api/v1/orgs", models.CreateOrganizationRequest{Name: name}, token)
+	expectStatus(t, w, http.StatusCreated)
+	return uint(decodeData(t, w)["id"].(float64))
+}
+
+func invite(t *testing.T, h *harness, token string, orgID uint, email string) string {
+	t.Helper()
+	w := h.do(http.MethodPost, fmt.Sprintf("/api/v1/orgs/%d/invitations", orgID), models.CreateInvitationRequest{Email: email}, token)
+	expectStatus(t, w, http.StatusCreated)
+	return decodeData(t, w)["token"].(string)
+}
+
+func createProduct(t *testing.T, h *harness, token, sku string) uint {
+	t.Helper()
+	w := h.do(http.MethodPost, "/api/v1/products", models.CreateProductRequest{SKU: sku, Name: sku, Price: 100}, token)
+	expectStatus(t, w, http.StatusCreated)
+	return uint(decodeData(t, w)["id"].(float64))
+}
+
+func TestOrganizationInvitationFlow(t *testing.T) {
+	h := newHarness(t)
+	alice := aUser("alice").create(t, h)
+	bob := aUser("bob").create(t, h)
+	carol := aUser("carol").create(t, h)
+
+	orgID := createOrg(t, h, alice.Token, "Acme")
+	w := h.do(http.MethodGet, "/api/v1/orgs", nil, alice.Token)
+	expectStatus(t, w, http.StatusOK)
+	if orgs := decodeList(t, w); len(orgs) != 1 || orgs[0]["role"] != models.OrgRoleOwner {
+		t.Fatalf("expected alice to own one organization, got %v", orgs)
+	}
+
+	// 普通成员之外的人看不到组织，也不能邀请
+	w = h.do(http.MethodGet, fmt.Sprintf("/api/v1/orgs/%d/members", orgID), nil, bob.Token)
+	expectStatus(t, w, http.StatusNotFound)
+	w = h.do(http.MethodPost, fmt.Sprintf("/api/v1/orgs/%d/invitations", orgID), models.CreateInvitationRequest{Email: "x@example.com"}, bob.Token)
+	expectStatus(t, w, http.StatusNotFound)
+
+	token := invite(t, h, alice.Token, orgID, bob.Email)
+	w = h.do(http.MethodGet, fmt.Sprintf("/api/v1/orgs/%d/invitations", orgID), nil, alice.Token)
+	expectStatus(t, w, http.StatusOK)
+	if pending := decodeList(t, w); len(pending) != 1 || pending[0]["token"] != nil {
+		t.Fatalf("expected one pending invitation without token, got %v", pending)
+	}
+
+	// 邀请绑定邮箱，其他用户不能使用
+	w = h.do(http.MethodPost, "/api/v1/invitations/accept", models.AcceptInvitationRequest{Token: token}, carol.Token)
+	expectStatus(t, w, http.StatusForbidden)
+	w = h.do(http.MethodPost, "/api/v1/invitations/accept", models.AcceptInvitationRequest{Token: token}, bob.Token)
+	expectStatus(t, w, http.StatusOK)
+	w = h.do(http.MethodPost, "/api/v1/invitations/accept", models.AcceptInvitationRequest{Token: token}, bob.Token)
+	expectStatus(t, w, http.StatusConflict)
+	w = h.do(http.MethodPost, "/api/v1/invitations/accept", models.AcceptInvitationRequest{Token: "unknown"}, bob.Token)
+	expectStatus(t, w, http.StatusNotFound)
+
+	bobOrg := switchOrg(t, h, bob.Token, orgID)
+	w = h.do(http.MethodGet, fmt.Sprintf("/api/v1/orgs/%d/members", orgID), nil, bobOrg)
+	expectStatus(t, w, http.StatusOK)
+	if members := decodeList(t, w); len(members) != 2 || members[1]["username"] != "bob" || members[1]["role"] != models.OrgRoleMember {
+		t.Fatalf("unexpected members: %v", members)
+	}
+
+	// 刷新 token 保留活动组织
+	w = h.do(http.MethodPost, "/api/v1/users/refresh", nil, bobOrg)
+	expectStatus(t, w, http.StatusOK)
+	if got := uint(decodeData(t, w)["org_id"].(float64)); got != orgID {
+		t.Fatalf("expected refresh to keep org %d, got %d", orgID, got)
+	}
+
+	// 非成员不能切换到该组织
+	w = h.do(http.MethodPut, "/api/v1/users/me/organization", models.SwitchOrganizationRequest{OrgID: orgID}, carol.Token)
+	expectStatus(t, w, http.StatusForbidden)
+
+	// 过期的邀请
+	expiredToken := invite(t, h, alice.Token, orgID, carol.Email)
+	if err := h.db.Model(&models.Invitation{}).Where("email = ?", carol.Email).Update("expires_at", time.Now().Add(-time.Minute)).Error; err != nil {
+		t.Fatalf("expire invitation: %v", err)
+	}
+	w = h.do(http.MethodPost, "/api/v1/invitations/accept", models.AcceptInvitationRequest{Token: expiredToken}, carol.Token)
+	expectStatus(t, w, http.StatusGone)
+
+	// 成员不能移除他人，组织至少保留一个 owner
+	w = h.do(http.MethodDelete, fmt.Sprintf("/api/v1/orgs/%d/members/%d", orgID, alice.ID), nil, bobOrg)
+	expectStatus(t, w, http.StatusForbidden)
+	w = h.do(http.MethodDelete, fmt.Sprintf("/api/v1/orgs/%d/members/%d", orgID, alice.ID), nil, alice.Token)
+	expectStatus(t, w, http.StatusConflict)
+
+	// 被移除后，指向该组织的旧 token 立即失效
+	w = h.do(http.MethodDelete, fmt.Sprintf("/api/v1/orgs/%d/members/%d", orgID, bob.ID), nil, alice.Token)
+	expectStatus(t, w, http.StatusOK)
+	w = h.do(http.MethodGet, "/api/v1/products", nil, bobOrg)
+	expectStatus(t, w, http.StatusForbidden)
+}
+
+func TestTenantIsolation(t *testing.T) {
+	h := newHarness(t)
+	alice := aUser("alice").create(t, h)
+	bob := aUser("bob").create(t, h)
+
+	orgID := createOrg(t, h, alice.Token, "Acme")
+	accept := invite(t, h, alice.Token, orgID, bob.Email)
+	w := h.do(http.MethodPost, "/api/v1/invitations/accept", models.AcceptInvitationRequest{Token: accept}, bob.Token)
+	expectStatus(t, w, http.StatusOK)
+	aliceOrg := switchOrg(t, h, alice.Token, orgID)
+	bobOrg := switchOrg(t, h, bob.Token, orgID)
+
+	personal := createProduct(t, h, alice.Token, "P-1")
+	// SKU 只在组织内唯一
+	orgProduct := createProduct(t, h, bobOrg, "P-1")
+
+	w = h.do(http.MethodGet, fmt.Sprintf("/api/v1/products/%d", orgProduct), nil, bobOrg)
+	expectStatus(t, w, http.StatusOK)
+	if got := uint(decodeData(t, w)["org_id"].(float64)); got != orgID {
+		t.Fatalf("expected product in org %d, got %d", orgID, got)
+	}
+
+	// 组织 owner 能看到组织内所有成员的商品，但看不到个人空间的商品
+	w = h.do(http.MethodGet, "/api/v1/products", nil, aliceOrg)
+	expectStatus(t, w, http.StatusOK)
+	page := decodeData(t, w)
+	items := page["items"].([]interface{})
+	if page["total"].(float64) != 1 || uint(items[0].(map[string]interface{})["id"].(float64)) != orgProduct {
+		t.Fatalf("expected only the org product, got %v", page)
+	}
+
+	// 个人空间与组织之间互相不可见
+	for _, tc := range []struct {
+		token string
+		id    uint
+	}{{alice.Token, orgProduct}, {aliceOrg, personal}, {bobOrg, personal}} {
+		w = h.do(http.MethodGet, fmt.Sprintf("/api/v1/products/%d", tc.id), nil, tc.token)
+		expectStatus(t, w, http.StatusNotFound)
+		w = h.do(http.MethodDelete, fmt.Sprintf("/api/v1/products/%d", tc.id), nil, tc.token)
+		expectStatus(t, w, http.StatusNotFound)
+		w = h.do(http.MethodGet, fmt.Sprintf("/api/v1/products/%d/inventory", tc.id), nil, tc.token)
+		expectStatus(t, w, http.StatusNotFound)
+	}
+
+	// 组织成员的订单同样限定在组织内
+	w = h.do(http.MethodPut, fmt.Sprintf("/api/v1/products/%d/inventory", orgProduct), models.SetInventoryRequest{Quantity: 5}, bobOrg)
+	expectStatus(t, w, http.StatusOK)
+	w = h.do(http.MethodPatch, fmt.Sprintf("/api/v1/products/%d", orgProduct), map[string]string{"status": models.ProductStatusActive}, bobOrg)
+	expectStatus(t, w, http.StatusOK)
+	w = h.do(http.MethodPost, "/api/v1/cart/items", models.AddCartItemRequest{ProductID: orgProduct, Quantity: 1}, bobOrg)
+	expectStatus(t, w, http.StatusOK)
+	w = h.do(http.MethodPost, "/api/v1/orders", nil, bobOrg)
+	expectStatus(t, w, http.StatusCreated)
+	orderID := uint(decodeData(t, w)["id"].(float64))
+
+	w = h.do(http.MethodGet, fmt.Sprintf("/api/v1/orders/%d", orderID), nil, bob.Token)
+	expectStatus(t, w, http.StatusNotFound)
+	w = h.do(http.MethodGet, "/api/v1/orders", nil, bob.Token)
+	expectStatus(t, w, http.StatusOK)
+	if orders := decodeList(t, w); len(orders) != 0 {
+		t.Fatalf("expected no orders in the personal workspace, got %d", len(orders))
+	}
+}
+
+// 管理接口不限定租户，管理员可以处理组织内的订单
+func TestAdminShipsOrgOrder(t *testing.T) {
+	h := newHarness(t)
+	alice := aUser("alice").create(t, h)
+	admin := aUser("root").asAdmin().create(t, h)
+
+	orgID := createOrg(t, h, alice.Token, "Acme")
+	aliceOrg := switchOrg(t, h, alice.Token, orgID)
+	product := createProduct(t, h, aliceOrg, "P-1")
+	w := h.do(http.MethodPut, fmt.Sprintf("/api/v1/products/%d/inventory", product), models.SetInventoryRequest{Quantity: 5}, aliceOrg)
+	expectStatus(t, w, http.StatusOK)
+	w = h.do(http.MethodPatch, fmt.Sprintf("/api/v1/products/%d", product), map[string]string{"status": models.ProductStatusActive}, aliceOrg)
+	expectStatus(t, w, http.StatusOK)
+	w = h.do(http.MethodPost, "/api/v1/cart/items", models.AddCartItemRequest{ProductID: product, Quantity: 1}, aliceOrg)
+	expectStatus(t, w, http.StatusOK)
+	w = h.do(http.MethodPost, "/api/v1/orders", nil, aliceOrg)
+	expectStatus(t, w, http.StatusCreated)
+	orderID := uint(decodeData(t, w)["id"].(float64))
+	w = h.do(http.MethodPost, fmt.Sprintf("/api/v1/orders/%d/pay", orderID), nil, aliceOrg)
+	expectStatus(t, w, http.StatusOK)
+
+	w = h.do(http.MethodPost, fmt.Sprintf("/api/v1/admin/orders/%d/ship", orderID), nil, admin.Token)
+	expectStatus(t, w, http.StatusOK)
+	if status := decodeData(t, w)["status"]; status != models.OrderStatusShipped {
+		t.Fatalf("expected order to be shipped, got %v", status)
+	}
+
+	w = h.do(http.MethodGet, fmt.Sprintf("/api/v1/orders/%d", orderID), nil, aliceOrg)
+	expectStatus(t, w, http.StatusOK)
+	if status := decodeData(t, w)["status"]; status != models.OrderStatusShipped {
+		t.Fatalf("expected the org member to see the shipped order, got %v", status)
+	}
+}
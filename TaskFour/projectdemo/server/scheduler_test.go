@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"projectdemo/models"
+	"projectdemo/scheduler"
+	"projectdemo/services"
+	"testing"
+	"time"
+)
+
+func TestAdminScheduledTasks(t *testing.T) {
+	h := newHarness(t)
+	admin := aUser("root").asAdmin().create(t, h)
+	alice := aUser("alice").create(t, h)
+	bob := aUser("bob").create(t, h)
+	carol := aUser("carol").create(t, h)
+	ctx := context.Background()
+
+	// alice 删除已超过保留期，bob 刚被删除
+	for _, u := range []*testUser{alice, bob} {
+		w := h.do(http.MethodDelete, fmt.Sprintf("/api/v1/admin/users/%d", u.ID), nil, admin.Token)
+		expectStatus(t, w, http.StatusOK)
+	}
+	deletedAt := time.Now().Add(-services.DeletedUserRetention - time.Hour)
+	if err := h.db.Unscoped().Model(&models.User{}).Where("id = ?", alice.ID).Update("deleted_at", deletedAt).Error; err != nil {
+		t.Fatalf("backdate deletion: %v", err)
+	}
+
+	s := scheduler.New(h.db, h.cfg.Scheduler)
+	if err := services.RegisterMaintenance(s, h.db); err != nil {
+		t.Fatalf("register maintenance: %v", err)
+	}
+	// 第一次运行只创建调度状态，任务都还没到期
+	if err := s.RunDue(ctx); err != nil {
+		t.Fatalf("run due: %v", err)
+	}
+
+	w := h.do(http.MethodGet, "/api/v1/admin/scheduler/tasks", nil, carol.Token)
+	expectStatus(t, w, http.StatusForbidden)
+	w = h.do(http.MethodGet, "/api/v1/admin/scheduler/tasks", nil, admin.Token)
+	expectStatus(t, w, http.StatusOK)
+	if list := decodeList(t, w); len(list) != 3 || list[0]["name"] != "purge-deleted-users" || list[0]["last_status"] != nil {
+		t.Fatalf("unexpected tasks %v", list)
+	}
+
+	w = h.do(http.MethodPost, "/api/v1/admin/scheduler/tasks/purge-deleted-users/run", nil, admin.Token)
+	expectStatus(t, w, http.StatusOK)
+	if err := s.RunDue(ctx); err != nil {
+		t.Fatalf("run due: %v", err)
+	}
+
+	var remaining []uint
+	if err := h.db.Unscoped().Model(&models.User{}).Order("id").Pluck("id", &remaining).Error; err != nil {
+		t.Fatalf("list users: %v", err)
+	}
+	if len(remaining) != 3 || remaining[0] != admin.ID || remaining[1] != bob.ID {
+		t.Fatalf("expected only alice to be purged, got %v", remaining)
+	}
+
+	w = h.do(http.MethodGet, "/api/v1/admin/scheduler/tasks", nil, admin.Token)
+	expectStatus(t, w, http.StatusOK)
+	list := decodeList(t, w)
+	if list[0]["last_status"] != models.TaskStatusSucceeded || list[0]["last_finished_at"] == nil {
+		t.Fatalf("expected the purge to be recorded, got %v", list[0])
+	}
+	if list[1]["last_status"] != nil {
+		t.Fatalf("expected other tasks not to run, got %v", list[1])
+	}
+
+	w = h.do(http.MethodPost, "/api/v1/admin/scheduler/tasks/missing/run", nil, admin.Token)
+	expectStatus(t, w, http.StatusNotFound)
+}
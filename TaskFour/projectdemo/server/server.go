@@ -0,0 +1,238 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"projectdemo/cache"
+	"projectdemo/config"
+	"projectdemo/events"
+	"projectdemo/handlers"
+	"projectdemo/middleware"
+	"projectdemo/models"
+	"projectdemo/openapi"
+	"projectdemo/repository"
+	"projectdemo/services"
+	"projectdemo/storage"
+	"projectdemo/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// Migrate 自动迁移所有模型
+func Migrate(db *gorm.DB) error {
+	err := db.AutoMigrate(
+		&models.User{},
+		&models.AuditEvent{},
+		&models.Organization{},
+		&models.Membership{},
+		&models.Invitation{},
+		&models.Product{},
+		&models.Inventory{},
+		&models.CartItem{},
+		&models.Order{},
+		&models.OrderItem{},
+		&models.OutboxEvent{},
+		&models.WebhookSubscription{},
+		&models.WebhookDelivery{},
+		&models.WebhookDeliveryLog{},
+		&models.Job{},
+		&models.ScheduledTask{},
+	)
+	if err != nil {
+		return err
+	}
+	// SKU 由全局唯一改为组织内唯一（idx_products_org_sku），AutoMigrate 不会删除旧索引
+	if db.Migrator().HasIndex(&models.Product{}, "idx_products_sku") {
+		return db.Migrator().DropIndex(&models.Product{}, "idx_products_sku")
+	}
+	return nil
+}
+
+// OpenDatabase 按配置打开数据库，busy_timeout 让并发写入等待锁释放而不是直接返回 SQLITE_BUSY
+func OpenDatabase(cfg config.DatabaseConfig, gormCfg *gorm.Config) (*gorm.DB, error) {
+	if cfg.Driver != "sqlite" {
+		return nil, fmt.Errorf("unsupported database driver: %s", cfg.Driver)
+	}
+	return gorm.Open(sqlite.Open(cfg.Path+"?_pragma=busy_timeout(5000)"), gormCfg)
+}
+
+// Option 注入由调用方管理生命周期的组件
+type Option func(*options)
+
+type options struct {
+	bus   *events.Bus
+	cache cache.Store
+}
+
+// WithEventBus 服务发布领域事件的总线，由调用方负责 Shutdown
+func WithEventBus(bus *events.Bus) Option {
+	return func(o *options) {
+		o.bus = bus
+	}
+}
+
+// WithCacheStore 使用外部缓存（如 Redis），多个副本共享缓存和失效。未设置时使用进程内的 LRU
+func WithCacheStore(store cache.Store) Option {
+	return func(o *options) {
+		o.cache = store
+	}
+}
+
+// NewServer 组装服务、处理器和路由，返回可直接用于 http.Server 或 httptest 的 Gin 引擎
+func NewServer(cfg *config.Config, db *gorm.DB, opts ...Option) (*gin.Engine, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if cfg.Server.Mode != "" {
+		gin.SetMode(cfg.Server.Mode)
+	}
+
+	// 按 context 中的组织自动限定租户模型，同一个 db 重复创建服务时插件已注册
+	if err := db.Use(repository.TenantPlugin{}); err != nil && !errors.Is(err, gorm.ErrRegistered) {
+		return nil, fmt.Errorf("register tenant plugin: %w", err)
+	}
+
+	// 初始化服务
+	auditService := services.NewAuditService(db)
+	if o.cache == nil {
+		o.cache = cache.NewLRU(cfg.Cache.Size)
+	}
+	userOpts := []services.UserServiceOption{services.WithEventBus(o.bus)}
+	var userCache *cache.Loader[models.User]
+	if cfg.Cache.UserTTL > 0 {
+		userCache = cache.NewLoader[models.User](o.cache, "user:", cfg.Cache.UserTTL)
+		userOpts = append(userOpts, services.WithUserCache(userCache))
+	}
+	userService := services.NewUserService(repository.NewGormStore(db), userOpts...)
+	if cfg.Storage.Driver != "local" {
+		return nil, fmt.Errorf("unsupported storage driver: %s", cfg.Storage.Driver)
+	}
+	blobStore, err := storage.NewLocalStore(cfg.Storage.LocalDir, cfg.Storage.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("init blob store: %w", err)
+	}
+	avatarService := services.NewAvatarService(blobStore, userService, cfg.Avatar)
+	orgService := services.NewOrgService(db)
+	userHandler := handlers.NewUserHandler(userService, avatarService, orgService, []byte(cfg.JWT.Secret))
+	orgHandler := handlers.NewOrgHandler(orgService)
+	auditHandler := handlers.NewAuditHandler(auditService)
+	webhookHandler := handlers.NewWebhookHandler(services.NewWebhookService(db))
+	jobHandler := handlers.NewJobHandler(services.NewJobService(db))
+	scheduleHandler := handlers.NewScheduleHandler(services.NewScheduleService(db))
+	cacheHandler := handlers.NewCacheHandler(userCache)
+	productRepo := repository.NewProductRepository(db)
+	productHandler := handlers.NewProductHandler(productRepo)
+	catalogHandler := handlers.NewCatalogHandler(productRepo)
+	cartHandler := handlers.NewCartHandler(services.NewCartService(db))
+	orderHandler := handlers.NewOrderHandler(services.NewOrderService(db), services.NewInventoryService(db))
+
+	// 创建 Gin 引擎
+	r := gin.New()
+
+	// 全局中间件
+	r.Use(gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
+		utils.Error(c, http.StatusInternalServerError, "Internal server error")
+	}))
+	r.Use(middleware.RequestID())
+	r.Use(middleware.Logger())
+	r.Use(middleware.CORS())
+
+	// 未匹配的路由同样返回统一的错误格式
+	r.HandleMethodNotAllowed = true
+	r.NoRoute(func(c *gin.Context) {
+		utils.Error(c, http.StatusNotFound, "Not found")
+	})
+	r.NoMethod(func(c *gin.Context) {
+		utils.Error(c, http.StatusMethodNotAllowed, "Method not allowed")
+	})
+
+	// 本地存储的公开文件（头像等），不开启目录浏览
+	r.StaticFS(cfg.Storage.BaseURL, gin.Dir(blobStore.Root(), false))
+
+	// 健康检查
+	r.GET("/health", func(c *gin.Context) {
+		utils.Success(c, gin.H{
+			"status": "ok",
+		})
+	})
+
+	// 公开路由
+	public := r.Group("/api/v1")
+	{
+		public.POST("/users/register", userHandler.Register)
+		public.POST("/users/login", userHandler.Login)
+		public.GET("/catalog/products", catalogHandler.ListProducts)
+	}
+
+	// 需要认证的路由
+	protected := r.Group("/api/v1")
+	protected.Use(middleware.Auth([]byte(cfg.JWT.Secret), userService), middleware.Tenant(orgService))
+	{
+		protected.POST("/users/refresh", userHandler.RefreshToken)
+		protected.PUT("/users/me/organization", userHandler.SwitchOrganization)
+		protected.GET("/users/me", userHandler.GetProfile)
+		protected.PUT("/users/me", userHandler.UpdateProfile)
+		protected.PUT("/users/me/avatar", userHandler.UploadAvatar)
+		protected.DELETE("/users/me", userHandler.DeleteProfile)
+
+		productHandler.Register(protected, "/products")
+		protected.GET("/products/:id/inventory", orderHandler.GetInventory)
+		protected.PUT("/products/:id/inventory", orderHandler.SetInventory)
+
+		protected.GET("/cart", cartHandler.GetCart)
+		protected.POST("/cart/items", cartHandler.AddItem)
+		protected.PUT("/cart/items/:product_id", cartHandler.UpdateItem)
+		protected.DELETE("/cart/items/:product_id", cartHandler.RemoveItem)
+
+		protected.POST("/orders", orderHandler.PlaceOrder)
+		protected.GET("/orders", orderHandler.ListOrders)
+		protected.GET("/orders/:id", orderHandler.GetOrder)
+		protected.POST("/orders/:id/pay", orderHandler.Pay)
+		protected.POST("/orders/:id/cancel", orderHandler.Cancel)
+
+		protected.POST("/orgs", orgHandler.Create)
+		protected.GET("/orgs", orgHandler.List)
+		protected.GET("/orgs/:id/members", orgHandler.ListMembers)
+		protected.DELETE("/orgs/:id/members/:user_id", orgHandler.RemoveMember)
+		protected.POST("/orgs/:id/invitations", orgHandler.CreateInvitation)
+		protected.GET("/orgs/:id/invitations", orgHandler.ListInvitations)
+		protected.DELETE("/orgs/:id/invitations/:invitation_id", orgHandler.RevokeInvitation)
+		protected.POST("/invitations/accept", orgHandler.AcceptInvitation)
+	}
+
+	// 管理员路由
+	admin := r.Group("/api/v1/admin")
+	admin.Use(middleware.Auth([]byte(cfg.JWT.Secret), userService), middleware.Tenant(orgService), middleware.RequireRole(models.RoleAdmin), middleware.Unscoped())
+	{
+		admin.DELETE("/users/:id", userHandler.DeleteUser)
+		admin.GET("/audit-events", auditHandler.ListEvents)
+		admin.POST("/orders/:id/ship", orderHandler.Ship)
+
+		admin.POST("/webhooks", webhookHandler.Create)
+		admin.GET("/webhooks", webhookHandler.List)
+		admin.DELETE("/webhooks/:id", webhookHandler.Delete)
+		admin.GET("/webhooks/:id/deliveries", webhookHandler.ListDeliveries)
+		admin.POST("/webhook-deliveries/:id/redeliver", webhookHandler.Redeliver)
+
+		admin.GET("/jobs", jobHandler.List)
+		admin.GET("/jobs/stats", jobHandler.Stats)
+		admin.GET("/jobs/:id", jobHandler.Get)
+		admin.POST("/jobs/:id/retry", jobHandler.Retry)
+
+		admin.GET("/scheduler/tasks", scheduleHandler.List)
+		admin.POST("/scheduler/tasks/:name/run", scheduleHandler.Trigger)
+
+		admin.GET("/cache/stats", cacheHandler.Stats)
+	}
+
+	// API 文档根据已注册的路由生成，必须放在所有路由之后
+	if _, err := openapi.Mount(r, apiInfo, apiOperations(cfg), specPath, docsPath); err != nil {
+		return nil, fmt.Errorf("build openapi document: %w", err)
+	}
+
+	return r, nil
+}
@@ -0,0 +1,232 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"projectdemo/models"
+	"projectdemo/utils"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestUserFlow(t *testing.T) {
+	h := newHarness(t)
+	fixture := aUser("alice")
+
+	// 注册
+	w := h.do(http.MethodPost, "/api/v1/users/register", fixture.registerRequest(), "")
+	expectStatus(t, w, http.StatusOK)
+	assertGolden(t, "register", w)
+
+	// 登录
+	w = h.do(http.MethodPost, "/api/v1/users/login", models.LoginRequest{Username: "alice", Password: "secret123"}, "")
+	expectStatus(t, w, http.StatusOK)
+	assertGolden(t, "login", w)
+	token := decodeData(t, w)["token"].(string)
+
+	// 获取当前用户
+	w = h.do(http.MethodGet, "/api/v1/users/me", nil, token)
+	expectStatus(t, w, http.StatusOK)
+	assertGolden(t, "me", w)
+
+	// 更新资料
+	displayName := "Alice"
+	w = h.do(http.MethodPut, "/api/v1/users/me", models.UpdateUserRequest{
+		Email:       "alice@new.example.com",
+		DisplayName: &displayName,
+	}, token)
+	expectStatus(t, w, http.StatusOK)
+	assertGolden(t, "update_me", w)
+
+	// 更新后再次读取应看到新值
+	w = h.do(http.MethodGet, "/api/v1/users/me", nil, token)
+	expectStatus(t, w, http.StatusOK)
+	if got := decodeData(t, w)["email"]; got != "alice@new.example.com" {
+		t.Fatalf("expected updated email, got %v", got)
+	}
+}
+
+func TestAuthFailures(t *testing.T) {
+	h := newHarness(t)
+	user := aUser("bob").create(t, h)
+
+	otherSecret, err := utils.GenerateToken([]byte("another-secret"), user.ID, user.Username, models.RoleUser, 0)
+	if err != nil {
+		t.Fatalf("generate token: %v", err)
+	}
+	expired := jwt.NewWithClaims(jwt.SigningMethodHS256, utils.Claims{
+		UserID:   user.ID,
+		Username: user.Username,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Hour)),
+		},
+	})
+	expiredToken, err := expired.SignedString([]byte(h.cfg.JWT.Secret))
+	if err != nil {
+		t.Fatalf("sign expired token: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		header string
+		golden string
+	}{
+		{"missing header", "", "auth_missing_header"},
+		{"wrong scheme", "Basic dXNlcjpwYXNz", "auth_bad_format"},
+		{"garbage token", "Bearer not-a-jwt", "auth_invalid_token"},
+		{"wrong secret", "Bearer " + otherSecret, "auth_invalid_token"},
+		{"expired token", "Bearer " + expiredToken, "auth_invalid_token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/users/me", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := h.serve(req)
+			expectStatus(t, w, http.StatusUnauthorized)
+			assertGolden(t, tt.golden, w)
+		})
+	}
+
+	t.Run("wrong password", func(t *testing.T) {
+		w := h.do(http.MethodPost, "/api/v1/users/login", models.LoginRequest{Username: "bob", Password: "nope"}, "")
+		expectStatus(t, w, http.StatusUnauthorized)
+		assertGolden(t, "login_invalid_credentials", w)
+	})
+
+	// 禁用或删除用户后，已签发的 token 立即失效
+	t.Run("disabled user", func(t *testing.T) {
+		carol := aUser("carol").create(t, h)
+		if err := h.db.Model(&models.User{}).Where("id = ?", carol.ID).Update("disabled_at", time.Now()).Error; err != nil {
+			t.Fatalf("disable user: %v", err)
+		}
+		w := h.do(http.MethodGet, "/api/v1/users/me", nil, carol.Token)
+		expectStatus(t, w, http.StatusForbidden)
+		assertGolden(t, "auth_account_disabled", w)
+	})
+
+	t.Run("deleted user", func(t *testing.T) {
+		dave := aUser("dave").create(t, h)
+		if err := h.db.Delete(&models.User{}, dave.ID).Error; err != nil {
+			t.Fatalf("delete user: %v", err)
+		}
+		w := h.do(http.MethodGet, "/api/v1/users/me", nil, dave.Token)
+		expectStatus(t, w, http.StatusUnauthorized)
+		assertGolden(t, "auth_invalid_token", w)
+	})
+
+	// 角色以数据库为准：降级后，签发时还是管理员的 token 不能再访问管理接口
+	t.Run("demoted admin", func(t *testing.T) {
+		erin := aUser("erin").asAdmin().create(t, h)
+		if err := h.db.Model(&models.User{}).Where("id = ?", erin.ID).Update("role", models.RoleUser).Error; err != nil {
+			t.Fatalf("demote user: %v", err)
+		}
+		w := h.do(http.MethodGet, "/api/v1/admin/audit-events", nil, erin.Token)
+		expectStatus(t, w, http.StatusForbidden)
+		assertGolden(t, "admin_forbidden", w)
+	})
+
+	t.Run("non-admin on admin route", func(t *testing.T) {
+		w := h.do(http.MethodGet, "/api/v1/admin/audit-events", nil, user.Token)
+		expectStatus(t, w, http.StatusForbidden)
+		assertGolden(t, "admin_forbidden", w)
+	})
+
+	t.Run("admin on admin route", func(t *testing.T) {
+		admin := aUser("root").asAdmin().create(t, h)
+		w := h.do(http.MethodGet, "/api/v1/admin/audit-events?action=user.register", nil, admin.Token)
+		expectStatus(t, w, http.StatusOK)
+	})
+}
+
+func TestCORSPreflight(t *testing.T) {
+	h := newHarness(t)
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/v1/users/me", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPut)
+	w := h.serve(req)
+
+	expectStatus(t, w, http.StatusNoContent)
+	wantHeaders := map[string]string{
+		"Access-Control-Allow-Origin":      "https://app.example.com",
+		"Access-Control-Allow-Methods":     "GET, POST, PUT, PATCH, DELETE, OPTIONS",
+		"Access-Control-Allow-Credentials": "true",
+	}
+	for k, want := range wantHeaders {
+		if got := w.Header().Get(k); got != want {
+			t.Errorf("%s: expected %q, got %q", k, want, got)
+		}
+	}
+	if w.Header().Get("X-Request-ID") == "" {
+		t.Error("expected X-Request-ID header")
+	}
+}
+
+func TestErrorEnvelopes(t *testing.T) {
+	h := newHarness(t)
+	existing := aUser("carol").create(t, h)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   interface{}
+		token  string
+		status int
+		golden string
+	}{
+		{
+			name:   "validation error",
+			method: http.MethodPost,
+			path:   "/api/v1/users/register",
+			body:   map[string]string{"username": "x"},
+			status: http.StatusUnprocessableEntity,
+			golden: "register_validation_error",
+		},
+		{
+			name:   "duplicate username",
+			method: http.MethodPost,
+			path:   "/api/v1/users/register",
+			body:   aUser("carol").withEmail("carol2@example.com").registerRequest(),
+			status: http.StatusConflict,
+			golden: "register_duplicate_username",
+		},
+		{
+			name:   "duplicate email on update",
+			method: http.MethodPut,
+			path:   "/api/v1/users/me",
+			body:   models.UpdateUserRequest{Email: "dave@example.com"},
+			token:  existing.Token,
+			status: http.StatusConflict,
+			golden: "update_duplicate_email",
+		},
+		{
+			name:   "unknown route",
+			method: http.MethodGet,
+			path:   "/api/v1/does-not-exist",
+			status: http.StatusNotFound,
+			golden: "not_found",
+		},
+		{
+			name:   "method not allowed",
+			method: http.MethodPatch,
+			path:   "/api/v1/users/login",
+			status: http.StatusMethodNotAllowed,
+			golden: "method_not_allowed",
+		},
+	}
+
+	aUser("dave").create(t, h)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := h.do(tt.method, tt.path, tt.body, tt.token)
+			expectStatus(t, w, tt.status)
+			assertGolden(t, tt.golden, w)
+		})
+	}
+}
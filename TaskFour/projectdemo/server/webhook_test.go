@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"projectdemo/models"
+	"projectdemo/services"
+	"testing"
+)
+
+func TestAdminWebhooks(t *testing.T) {
+	h := newHarness(t)
+	admin := aUser("root").asAdmin().create(t, h)
+	alice := aUser("alice").create(t, h)
+
+	received := make(chan string, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get("X-Webhook-Event")
+	}))
+	t.Cleanup(receiver.Close)
+
+	req := models.CreateWebhookRequest{URL: receiver.URL, Secret: "0123456789abcdef", Events: []string{models.EventUserRegistered}}
+	w := h.do(http.MethodPost, "/api/v1/admin/webhooks", req, alice.Token)
+	expectStatus(t, w, http.StatusForbidden)
+	w = h.do(http.MethodPost, "/api/v1/admin/webhooks", models.CreateWebhookRequest{URL: receiver.URL, Secret: req.Secret, Events: []string{"user.unknown"}}, admin.Token)
+	expectStatus(t, w, http.StatusUnprocessableEntity)
+	w = h.do(http.MethodPost, "/api/v1/admin/webhooks", req, admin.Token)
+	expectStatus(t, w, http.StatusCreated)
+	sub := decodeData(t, w)
+	if _, ok := sub["secret"]; ok {
+		t.Fatal("secret must not be returned")
+	}
+	subID := uint(sub["id"].(float64))
+
+	aUser("bob").create(t, h)
+	if _, err := services.NewWebhookDispatcher(h.db, h.cfg.Webhook).DispatchOnce(context.Background()); err != nil {
+		t.Fatalf("dispatch: %v", err)
+	}
+	// 订阅之前注册的用户同样在 outbox 中，也会被投递
+	for range 3 {
+		if got := <-received; got != models.EventUserRegistered {
+			t.Fatalf("expected %s, got %s", models.EventUserRegistered, got)
+		}
+	}
+
+	w = h.do(http.MethodGet, fmt.Sprintf("/api/v1/admin/webhooks/%d/deliveries?status=succeeded", subID), nil, admin.Token)
+	expectStatus(t, w, http.StatusOK)
+	deliveries := decodeList(t, w)
+	if len(deliveries) != 3 {
+		t.Fatalf("expected 3 succeeded deliveries, got %d", len(deliveries))
+	}
+	deliveryID := uint(deliveries[0]["id"].(float64))
+
+	w = h.do(http.MethodPost, fmt.Sprintf("/api/v1/admin/webhook-deliveries/%d/redeliver", deliveryID), nil, admin.Token)
+	expectStatus(t, w, http.StatusOK)
+	if status := decodeData(t, w)["status"]; status != models.DeliveryStatusPending {
+		t.Fatalf("expected pending after redeliver, got %v", status)
+	}
+
+	w = h.do(http.MethodDelete, fmt.Sprintf("/api/v1/admin/webhooks/%d", subID), nil, admin.Token)
+	expectStatus(t, w, http.StatusOK)
+	w = h.do(http.MethodGet, fmt.Sprintf("/api/v1/admin/webhooks/%d/deliveries", subID), nil, admin.Token)
+	expectStatus(t, w, http.StatusNotFound)
+}
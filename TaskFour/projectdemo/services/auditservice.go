@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"projectdemo/models"
+	"projectdemo/utils"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const defaultAuditLimit = 100
+
+type AuditService struct {
+	db *gorm.DB
+}
+
+func NewAuditService(db *gorm.DB) *AuditService {
+	return &AuditService{db: db}
+}
+
+// NewAuditEvent 根据 context 中的请求信息构造审计事件，由调用方在业务事务中写入
+func NewAuditEvent(ctx context.Context, action, targetType, targetID string, before, after interface{}) *models.AuditEvent {
+	meta := utils.RequestMetaFrom(ctx)
+	event := &models.AuditEvent{
+		ActorName:  meta.Username,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		IP:         meta.IP,
+		RequestID:  meta.RequestID,
+		Before:     marshalAuditState(before),
+		After:      marshalAuditState(after),
+	}
+	if meta.UserID != 0 {
+		actorID := meta.UserID
+		event.ActorID = &actorID
+	}
+	return event
+}
+
+func (s *AuditService) List(ctx context.Context, q models.AuditQuery) ([]models.AuditEvent, error) {
+	limit := q.Limit
+	if limit == 0 {
+		limit = defaultAuditLimit
+	}
+
+	var events []models.AuditEvent
+	if err := s.filter(ctx, q).Order("id DESC").Limit(limit).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ExportCSV 按批次读取并写出，避免一次性把全部事件加载到内存
+func (s *AuditService) ExportCSV(ctx context.Context, q models.AuditQuery, w io.Writer) error {
+	cw := csv.NewWriter(w)
+	header := []string{"id", "created_at", "actor_id", "actor_name", "action", "target_type", "target_id", "ip", "request_id", "before", "after"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	err := s.each(ctx, q, func(e *models.AuditEvent) error {
+		actorID := ""
+		if e.ActorID != nil {
+			actorID = strconv.FormatUint(uint64(*e.ActorID), 10)
+		}
+		return cw.Write([]string{
+			strconv.FormatUint(uint64(e.ID), 10),
+			e.CreatedAt.UTC().Format(time.RFC3339),
+			actorID,
+			e.ActorName,
+			e.Action,
+			e.TargetType,
+			e.TargetID,
+			e.IP,
+			e.RequestID,
+			e.Before,
+			e.After,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func (s *AuditService) ExportJSONL(ctx context.Context, q models.AuditQuery, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	return s.each(ctx, q, func(e *models.AuditEvent) error {
+		return enc.Encode(e)
+	})
+}
+
+func (s *AuditService) each(ctx context.Context, q models.AuditQuery, fn func(*models.AuditEvent) error) error {
+	var batch []models.AuditEvent
+	var fnErr error
+	result := s.filter(ctx, q).Order("id ASC").FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+		for i := range batch {
+			if fnErr = fn(&batch[i]); fnErr != nil {
+				return fnErr
+			}
+		}
+		return nil
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	return result.Error
+}
+
+func (s *AuditService) filter(ctx context.Context, q models.AuditQuery) *gorm.DB {
+	db := s.db.WithContext(ctx).Model(&models.AuditEvent{})
+	if !q.From.IsZero() {
+		db = db.Where("created_at >= ?", q.From)
+	}
+	if !q.To.IsZero() {
+		db = db.Where("created_at < ?", q.To)
+	}
+	if q.Action != "" {
+		db = db.Where("action = ?", q.Action)
+	}
+	if q.ActorID != 0 {
+		db = db.Where("actor_id = ?", q.ActorID)
+	}
+	if q.TargetID != "" {
+		db = db.Where("target_id = ?", q.TargetID)
+	}
+	return db
+}
+
+func marshalAuditState(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
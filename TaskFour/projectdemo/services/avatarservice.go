@@ -0,0 +1,134 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"path"
+	"projectdemo/config"
+	"projectdemo/imaging"
+	"projectdemo/models"
+	"projectdemo/storage"
+	"projectdemo/utils"
+	"strconv"
+	"strings"
+)
+
+var avatarFormats = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".png", // GIF 只取第一帧，转存为 PNG
+}
+
+type AvatarService struct {
+	store storage.BlobStore
+	users UserService
+	cfg   config.AvatarConfig
+}
+
+func NewAvatarService(store storage.BlobStore, users UserService, cfg config.AvatarConfig) *AvatarService {
+	return &AvatarService{store: store, users: users, cfg: cfg}
+}
+
+// Upload 校验并缩放头像，按配置的各个尺寸写入存储，然后更新用户的头像
+func (s *AvatarService) Upload(ctx context.Context, userID uint, r io.Reader) (*models.User, error) {
+	// 多读一个字节用来判断是否超过大小限制
+	data, err := io.ReadAll(io.LimitReader(r, s.cfg.MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.cfg.MaxSize {
+		return nil, utils.NewAppError(http.StatusRequestEntityTooLarge, "Avatar exceeds maximum size")
+	}
+
+	// 以文件内容判断类型，不信任客户端提供的 Content-Type
+	mimeType := http.DetectContentType(data)
+	ext, ok := avatarFormats[mimeType]
+	if !ok {
+		return nil, utils.NewAppError(http.StatusUnsupportedMediaType, "Avatar must be a JPEG, PNG or GIF image")
+	}
+
+	// 解码前先检查尺寸，防止超大分辨率的图片耗尽内存
+	imgCfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, utils.NewAppError(http.StatusUnprocessableEntity, "Invalid image")
+	}
+	if imgCfg.Width > s.cfg.MaxDimension || imgCfg.Height > s.cfg.MaxDimension {
+		return nil, utils.NewAppError(http.StatusUnprocessableEntity, "Image dimensions too large")
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, utils.NewAppError(http.StatusUnprocessableEntity, "Invalid image")
+	}
+
+	sum := sha256.Sum256(data)
+	key := fmt.Sprintf("avatars/%d/%s%s", userID, hex.EncodeToString(sum[:8]), ext)
+
+	for _, size := range s.cfg.Sizes {
+		var buf bytes.Buffer
+		if err := encodeImage(&buf, imaging.Fill(img, size, size), ext); err != nil {
+			return nil, err
+		}
+		if err := s.store.Put(ctx, sizedKey(key, size), &buf, mimeByExt(ext)); err != nil {
+			return nil, err
+		}
+	}
+
+	oldKey, err := s.users.SetAvatar(ctx, userID, key)
+	if err != nil {
+		s.deleteAll(ctx, key)
+		return nil, err
+	}
+	if oldKey != "" && oldKey != key {
+		s.deleteAll(ctx, oldKey)
+	}
+
+	return s.users.GetUserByID(ctx, userID)
+}
+
+// URLs 返回各尺寸头像的访问地址，key 为尺寸
+func (s *AvatarService) URLs(key string) map[string]string {
+	if key == "" {
+		return nil
+	}
+	urls := make(map[string]string, len(s.cfg.Sizes))
+	for _, size := range s.cfg.Sizes {
+		urls[strconv.Itoa(size)] = s.store.URL(sizedKey(key, size))
+	}
+	return urls
+}
+
+func (s *AvatarService) deleteAll(ctx context.Context, key string) {
+	for _, size := range s.cfg.Sizes {
+		_ = s.store.Delete(ctx, sizedKey(key, size))
+	}
+}
+
+// sizedKey avatars/1/abc.jpg -> avatars/1/abc_128.jpg
+func sizedKey(key string, size int) string {
+	ext := path.Ext(key)
+	return strings.TrimSuffix(key, ext) + "_" + strconv.Itoa(size) + ext
+}
+
+func encodeImage(w io.Writer, img image.Image, ext string) error {
+	if ext == ".jpg" {
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+	}
+	return png.Encode(w, img)
+}
+
+func mimeByExt(ext string) string {
+	if ext == ".jpg" {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"projectdemo/models"
+	"projectdemo/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CartService struct {
+	db *gorm.DB
+}
+
+func NewCartService(db *gorm.DB) *CartService {
+	return &CartService{db: db}
+}
+
+func (s *CartService) List(ctx context.Context, userID uint) ([]models.CartItem, error) {
+	items := make([]models.CartItem, 0)
+	err := s.db.WithContext(ctx).Preload("Product").
+		Where("user_id = ?", userID).
+		Order("id ASC").
+		Find(&items).Error
+	return items, err
+}
+
+// AddItem 添加商品到购物车，已存在时累加数量
+func (s *CartService) AddItem(ctx context.Context, userID uint, req models.AddCartItemRequest) ([]models.CartItem, error) {
+	var product models.Product
+	if err := s.db.WithContext(ctx).First(&product, req.ProductID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(http.StatusNotFound, "Product not found")
+		}
+		return nil, err
+	}
+	if product.Status != models.ProductStatusActive {
+		return nil, utils.NewAppError(http.StatusConflict, "Product is not available")
+	}
+
+	item := models.CartItem{UserID: userID, ProductID: req.ProductID, Quantity: req.Quantity}
+	err := s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "product_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"quantity":   gorm.Expr("cart_items.quantity + ?", req.Quantity),
+			"updated_at": gorm.Expr("CURRENT_TIMESTAMP"),
+		}),
+	}).Create(&item).Error
+	if err != nil {
+		return nil, err
+	}
+	return s.List(ctx, userID)
+}
+
+func (s *CartService) UpdateItem(ctx context.Context, userID, productID uint, quantity int) ([]models.CartItem, error) {
+	result := s.db.WithContext(ctx).Model(&models.CartItem{}).
+		Where("user_id = ? AND product_id = ?", userID, productID).
+		Update("quantity", quantity)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, utils.NewAppError(http.StatusNotFound, "Cart item not found")
+	}
+	return s.List(ctx, userID)
+}
+
+func (s *CartService) RemoveItem(ctx context.Context, userID, productID uint) ([]models.CartItem, error) {
+	err := s.db.WithContext(ctx).
+		Where("user_id = ? AND product_id = ?", userID, productID).
+		Delete(&models.CartItem{}).Error
+	if err != nil {
+		return nil, err
+	}
+	return s.List(ctx, userID)
+}
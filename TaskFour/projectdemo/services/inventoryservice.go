@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"projectdemo/models"
+	"projectdemo/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errStockConflict 乐观锁版本冲突，调用方应重试整个事务
+var errStockConflict = errors.New("inventory version conflict")
+
+type InventoryService struct {
+	db *gorm.DB
+}
+
+func NewInventoryService(db *gorm.DB) *InventoryService {
+	return &InventoryService{db: db}
+}
+
+func (s *InventoryService) Get(ctx context.Context, productID uint) (*models.Inventory, error) {
+	// 库存表没有 org_id，先在租户范围内确认商品存在，避免读取其他组织商品的库存
+	if err := s.db.WithContext(ctx).Select("id").First(&models.Product{}, productID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(http.StatusNotFound, "Product not found")
+		}
+		return nil, err
+	}
+
+	var inv models.Inventory
+	err := s.db.WithContext(ctx).First(&inv, "product_id = ?", productID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.Inventory{ProductID: productID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+// SetStock 设置商品库存。ownerID 不为 0 时要求商品属于该用户，管理员调用时传 0
+func (s *InventoryService) SetStock(ctx context.Context, productID uint, quantity int, ownerID uint) (*models.Inventory, error) {
+	var product models.Product
+	if err := s.db.WithContext(ctx).First(&product, productID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(http.StatusNotFound, "Product not found")
+		}
+		return nil, err
+	}
+	if ownerID != 0 && product.OwnerID != ownerID {
+		return nil, utils.NewAppError(http.StatusNotFound, "Product not found")
+	}
+
+	inv := models.Inventory{ProductID: productID, Quantity: quantity}
+	err := s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "product_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"quantity":   quantity,
+			"version":    gorm.Expr("inventories.version + 1"),
+			"updated_at": gorm.Expr("CURRENT_TIMESTAMP"),
+		}),
+	}).Create(&inv).Error
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, productID)
+}
+
+// decrementStock 在事务 tx 中扣减库存。先读取当前版本，再以版本号为条件更新，
+// 更新行数为 0 说明期间有其他事务修改了库存
+func decrementStock(tx *gorm.DB, productID uint, quantity int) error {
+	var inv models.Inventory
+	if err := tx.First(&inv, "product_id = ?", productID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.NewAppError(http.StatusConflict, fmt.Sprintf("Insufficient stock for product %d", productID))
+		}
+		return err
+	}
+	if inv.Quantity < quantity {
+		return utils.NewAppError(http.StatusConflict, fmt.Sprintf("Insufficient stock for product %d", productID))
+	}
+
+	result := tx.Model(&models.Inventory{}).
+		Where("product_id = ? AND version = ?", productID, inv.Version).
+		Updates(map[string]interface{}{
+			"quantity": gorm.Expr("quantity - ?", quantity),
+			"version":  gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errStockConflict
+	}
+	return nil
+}
+
+// restoreStock 取消订单时归还库存，同样递增版本号让并发的扣减重新读取
+func restoreStock(tx *gorm.DB, productID uint, quantity int) error {
+	return tx.Model(&models.Inventory{}).
+		Where("product_id = ?", productID).
+		Updates(map[string]interface{}{
+			"quantity": gorm.Expr("quantity + ?", quantity),
+			"version":  gorm.Expr("version + 1"),
+		}).Error
+}
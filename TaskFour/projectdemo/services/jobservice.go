@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"projectdemo/jobs"
+	"projectdemo/models"
+	"projectdemo/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultJobLimit = 100
+
+	// JobPurgeInvitations 删除过期超过 InvitationRetention 的未接受邀请
+	JobPurgeInvitations = "orgs.purge_invitations"
+)
+
+// RegisterJobs 注册所有后台任务的处理函数
+func RegisterJobs(q *jobs.Queue, orgs *OrgService) {
+	jobs.Register(q, JobPurgeInvitations, func(ctx context.Context, _ struct{}) error {
+		_, err := orgs.PurgeExpiredInvitations(ctx)
+		return err
+	})
+}
+
+// JobService 供管理接口查询和重试后台任务，任务的执行由 jobs.Queue 负责
+type JobService struct {
+	db *gorm.DB
+}
+
+func NewJobService(db *gorm.DB) *JobService {
+	return &JobService{db: db}
+}
+
+func (s *JobService) List(ctx context.Context, q models.JobQuery) ([]models.Job, error) {
+	limit := q.Limit
+	if limit == 0 {
+		limit = defaultJobLimit
+	}
+	db := s.db.WithContext(ctx)
+	if q.Status != "" {
+		db = db.Where("status = ?", q.Status)
+	}
+	if q.Type != "" {
+		db = db.Where("type = ?", q.Type)
+	}
+
+	list := make([]models.Job, 0)
+	err := db.Order("id DESC").Limit(limit).Find(&list).Error
+	return list, err
+}
+
+func (s *JobService) Get(ctx context.Context, id uint) (*models.Job, error) {
+	var job models.Job
+	if err := s.db.WithContext(ctx).First(&job, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(http.StatusNotFound, "Job not found")
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (s *JobService) Stats(ctx context.Context) (*models.JobStats, error) {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	err := s.db.WithContext(ctx).Model(&models.Job{}).
+		Select("status, COUNT(*) AS count").Group("status").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var stats models.JobStats
+	for _, r := range rows {
+		switch r.Status {
+		case models.JobStatusQueued:
+			stats.Queued = r.Count
+		case models.JobStatusRunning:
+			stats.Running = r.Count
+		case models.JobStatusSucceeded:
+			stats.Succeeded = r.Count
+		case models.JobStatusDead:
+			stats.Dead = r.Count
+		}
+	}
+	return &stats, nil
+}
+
+// Retry 把 dead 任务放回队列立即执行，尝试次数从 0 计算
+func (s *JobService) Retry(ctx context.Context, id uint) (*models.Job, error) {
+	result := s.db.WithContext(ctx).Model(&models.Job{}).
+		Where("id = ? AND status = ?", id, models.JobStatusDead).
+		Updates(map[string]interface{}{
+			"status":      models.JobStatusQueued,
+			"attempts":    0,
+			"run_at":      time.Now(),
+			"finished_at": nil,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	job, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if result.RowsAffected == 0 {
+		return nil, utils.NewAppError(http.StatusConflict, "Only dead jobs can be retried")
+	}
+	return job, nil
+}
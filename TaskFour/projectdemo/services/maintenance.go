@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"log"
+	"projectdemo/models"
+	"projectdemo/scheduler"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DeletedUserRetention 软删除的用户保留这么久后被彻底删除
+	DeletedUserRetention = 30 * 24 * time.Hour
+	// FinishedJobRetention 已结束（成功或 dead）的后台任务保留这么久
+	FinishedJobRetention = 7 * 24 * time.Hour
+)
+
+// Maintenance 定期清理过期数据，由 scheduler 调度执行
+type Maintenance struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+func NewMaintenance(db *gorm.DB) *Maintenance {
+	return &Maintenance{db: db, now: time.Now}
+}
+
+// RegisterMaintenance 注册所有维护任务
+func RegisterMaintenance(s *scheduler.Scheduler, db *gorm.DB) error {
+	m := NewMaintenance(db)
+	orgs := NewOrgService(db)
+	tasks := []scheduler.Task{
+		{Name: "purge-deleted-users", Schedule: "30 3 * * *", Run: logPurged("deleted users", m.PurgeDeletedUsers)},
+		{Name: "purge-expired-invitations", Schedule: "@hourly", Run: logPurged("expired invitations", orgs.PurgeExpiredInvitations)},
+		{Name: "purge-finished-jobs", Schedule: "45 3 * * *", Run: logPurged("finished jobs", m.PurgeFinishedJobs)},
+	}
+	for _, t := range tasks {
+		if err := s.Register(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func logPurged(what string, purge func(context.Context) (int64, error)) func(context.Context) error {
+	return func(ctx context.Context) error {
+		n, err := purge(ctx)
+		if n > 0 {
+			log.Printf("maintenance: purged %d %s", n, what)
+		}
+		return err
+	}
+}
+
+// PurgeDeletedUsers 彻底删除软删除超过 DeletedUserRetention 的用户及其组织成员关系和购物车。
+// 订单保留，仍然引用原来的用户 ID
+func (m *Maintenance) PurgeDeletedUsers(ctx context.Context) (int64, error) {
+	var purged int64
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		err := tx.Unscoped().Model(&models.User{}).
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", m.now().Add(-DeletedUserRetention)).
+			Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		if err := tx.Where("user_id IN ?", ids).Delete(&models.Membership{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id IN ?", ids).Delete(&models.CartItem{}).Error; err != nil {
+			return err
+		}
+		result := tx.Unscoped().Where("id IN ?", ids).Delete(&models.User{})
+		purged = result.RowsAffected
+		return result.Error
+	})
+	return purged, err
+}
+
+// PurgeFinishedJobs 删除结束超过 FinishedJobRetention 的后台任务
+func (m *Maintenance) PurgeFinishedJobs(ctx context.Context) (int64, error) {
+	result := m.db.WithContext(ctx).
+		Where("status IN ? AND finished_at < ?",
+			[]string{models.JobStatusSucceeded, models.JobStatusDead}, m.now().Add(-FinishedJobRetention)).
+		Delete(&models.Job{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"projectdemo/models"
+	"projectdemo/repository"
+	"projectdemo/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 库存版本冲突或事务冲突时整个下单事务的最大重试次数
+const placeOrderMaxAttempts = 3
+
+type OrderService struct {
+	db *gorm.DB
+}
+
+func NewOrderService(db *gorm.DB) *OrderService {
+	return &OrderService{db: db}
+}
+
+// PlaceOrder 将用户购物车中的商品下单。扣减库存、创建订单和清空购物车在同一个事务中完成
+func (s *OrderService) PlaceOrder(ctx context.Context, userID uint) (*models.Order, error) {
+	var order *models.Order
+	var err error
+	for attempt := 0; attempt < placeOrderMaxAttempts; attempt++ {
+		order, err = s.placeOrder(ctx, userID)
+		if !retryOrder(err) {
+			break
+		}
+	}
+	if retryOrder(err) {
+		return nil, utils.NewAppError(http.StatusConflict, "Stock changed concurrently, please retry")
+	}
+	return order, err
+}
+
+func retryOrder(err error) bool {
+	return errors.Is(err, errStockConflict) || repository.IsTxConflict(err)
+}
+
+func (s *OrderService) placeOrder(ctx context.Context, userID uint) (*models.Order, error) {
+	var order models.Order
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var cart []models.CartItem
+		if err := tx.Preload("Product").Where("user_id = ?", userID).Order("id ASC").Find(&cart).Error; err != nil {
+			return err
+		}
+		if len(cart) == 0 {
+			return utils.NewAppError(http.StatusBadRequest, "Cart is empty")
+		}
+
+		order = models.Order{UserID: userID, Status: models.OrderStatusPending}
+		for _, item := range cart {
+			// Preload 不会加载已软删除的商品
+			if item.Product.ID == 0 || item.Product.Status != models.ProductStatusActive {
+				return utils.NewAppError(http.StatusConflict, fmt.Sprintf("Product %d is not available", item.ProductID))
+			}
+			if err := decrementStock(tx, item.ProductID, item.Quantity); err != nil {
+				return err
+			}
+			order.Items = append(order.Items, models.OrderItem{
+				ProductID: item.ProductID,
+				SKU:       item.Product.SKU,
+				Name:      item.Product.Name,
+				UnitPrice: item.Product.Price,
+				Quantity:  item.Quantity,
+			})
+			order.Total += item.Product.Price * int64(item.Quantity)
+		}
+
+		if err := tx.Create(&order).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.CartItem{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// GetOrder 查询订单。userID 不为 0 时只允许查看自己的订单
+func (s *OrderService) GetOrder(ctx context.Context, id, userID uint) (*models.Order, error) {
+	var order models.Order
+	db := s.db.WithContext(ctx).Preload("Items")
+	if userID != 0 {
+		db = db.Where("user_id = ?", userID)
+	}
+	if err := db.First(&order, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(http.StatusNotFound, "Order not found")
+		}
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (s *OrderService) ListOrders(ctx context.Context, userID uint) ([]models.Order, error) {
+	orders := make([]models.Order, 0)
+	err := s.db.WithContext(ctx).Preload("Items").
+		Where("user_id = ?", userID).
+		Order("id DESC").
+		Find(&orders).Error
+	return orders, err
+}
+
+func (s *OrderService) Pay(ctx context.Context, id, userID uint) (*models.Order, error) {
+	return s.transit(ctx, id, userID, models.OrderStatusPaid, nil)
+}
+
+func (s *OrderService) Ship(ctx context.Context, id uint) (*models.Order, error) {
+	return s.transit(ctx, id, 0, models.OrderStatusShipped, nil)
+}
+
+// Cancel 取消订单并归还库存
+func (s *OrderService) Cancel(ctx context.Context, id, userID uint) (*models.Order, error) {
+	return s.transit(ctx, id, userID, models.OrderStatusCancelled, func(tx *gorm.DB, order *models.Order) error {
+		for _, item := range order.Items {
+			if err := restoreStock(tx, item.ProductID, item.Quantity); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// transit 以乐观锁更新订单状态：只有状态和版本号都未被其他请求修改时才会成功
+func (s *OrderService) transit(ctx context.Context, id, userID uint, to string, fn func(tx *gorm.DB, order *models.Order) error) (*models.Order, error) {
+	order, err := s.GetOrder(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !models.CanTransitOrder(order.Status, to) {
+		return nil, utils.NewAppError(http.StatusConflict, fmt.Sprintf("Cannot change order from %s to %s", order.Status, to))
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":  to,
+		"version": gorm.Expr("version + 1"),
+	}
+	switch to {
+	case models.OrderStatusPaid:
+		updates["paid_at"] = now
+	case models.OrderStatusShipped:
+		updates["shipped_at"] = now
+	case models.OrderStatusCancelled:
+		updates["cancelled_at"] = now
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Order{}).
+			Where("id = ? AND status = ? AND version = ?", order.ID, order.Status, order.Version).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return utils.NewAppError(http.StatusConflict, "Order was modified concurrently")
+		}
+		if fn != nil {
+			return fn(tx, order)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetOrder(ctx, id, userID)
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"projectdemo/models"
+	"projectdemo/utils"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// newOrderTestDB 在 newSQLiteStore 的 WAL 数据库上迁移商品、库存、购物车和订单
+func newOrderTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	_, db := newSQLiteStore(t)
+	if err := db.AutoMigrate(&models.Product{}, &models.Inventory{}, &models.CartItem{}, &models.Order{}, &models.OrderItem{}); err != nil {
+		t.Fatalf("auto migrate: %v", err)
+	}
+	return db
+}
+
+// seedProduct 创建上架的商品并设置库存
+func seedProduct(t *testing.T, db *gorm.DB, sku string, price int64, stock int) *models.Product {
+	t.Helper()
+	product := models.Product{OwnerID: 1, SKU: sku, Name: sku, Price: price, Status: models.ProductStatusActive}
+	if err := db.Create(&product).Error; err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	if _, err := NewInventoryService(db).SetStock(context.Background(), product.ID, stock, 0); err != nil {
+		t.Fatalf("set stock: %v", err)
+	}
+	return &product
+}
+
+func addToCart(t *testing.T, db *gorm.DB, userID, productID uint, quantity int) {
+	t.Helper()
+	_, err := NewCartService(db).AddItem(context.Background(), userID, models.AddCartItemRequest{ProductID: productID, Quantity: quantity})
+	if err != nil {
+		t.Fatalf("add to cart: %v", err)
+	}
+}
+
+func stockOf(t *testing.T, db *gorm.DB, productID uint) int {
+	t.Helper()
+	inv, err := NewInventoryService(db).Get(context.Background(), productID)
+	if err != nil {
+		t.Fatalf("get stock: %v", err)
+	}
+	return inv.Quantity
+}
+
+func cartSize(t *testing.T, db *gorm.DB, userID uint) int64 {
+	t.Helper()
+	var n int64
+	if err := db.Model(&models.CartItem{}).Where("user_id = ?", userID).Count(&n).Error; err != nil {
+		t.Fatalf("count cart: %v", err)
+	}
+	return n
+}
+
+func TestPlaceOrder(t *testing.T) {
+	db := newOrderTestDB(t)
+	svc := NewOrderService(db)
+	ctx := context.Background()
+	pen := seedProduct(t, db, "PEN", 150, 5)
+	book := seedProduct(t, db, "BOOK", 2000, 1)
+
+	addToCart(t, db, 1, pen.ID, 2)
+	addToCart(t, db, 1, book.ID, 1)
+	order, err := svc.PlaceOrder(ctx, 1)
+	if err != nil {
+		t.Fatalf("place order: %v", err)
+	}
+	if order.Total != 2*150+2000 || len(order.Items) != 2 || order.Status != models.OrderStatusPending {
+		t.Fatalf("unexpected order %+v", order)
+	}
+	if stockOf(t, db, pen.ID) != 3 || stockOf(t, db, book.ID) != 0 || cartSize(t, db, 1) != 0 {
+		t.Fatal("expected stock to be decremented and the cart to be cleared")
+	}
+
+	// 库存不足时整个订单回滚，已经扣减的商品也恢复
+	addToCart(t, db, 2, pen.ID, 1)
+	addToCart(t, db, 2, book.ID, 1)
+	_, err = svc.PlaceOrder(ctx, 2)
+	assertAppError(t, err, http.StatusConflict)
+	if stockOf(t, db, pen.ID) != 3 || cartSize(t, db, 2) != 2 {
+		t.Fatal("expected a failed order to leave stock and cart unchanged")
+	}
+
+	_, err = svc.PlaceOrder(ctx, 3)
+	assertAppError(t, err, http.StatusBadRequest)
+}
+
+func TestPlaceOrderLastUnitConcurrently(t *testing.T) {
+	const buyers = 8
+	db := newOrderTestDB(t)
+	svc := NewOrderService(db)
+	product := seedProduct(t, db, "LAST", 100, 1)
+	for i := 1; i <= buyers; i++ {
+		addToCart(t, db, uint(i), product.ID, 1)
+	}
+
+	start := make(chan struct{})
+	errs := make([]error, buyers)
+	var wg sync.WaitGroup
+	for i := 0; i < buyers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			_, errs[i] = svc.PlaceOrder(context.Background(), uint(i+1))
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	succeeded := 0
+	for i, err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		var appErr *utils.AppError
+		if !errors.As(err, &appErr) || appErr.Code != http.StatusConflict {
+			t.Errorf("buyer %d: expected 409, got %v", i+1, err)
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("expected exactly one order, got %d", succeeded)
+	}
+	var orders int64
+	db.Model(&models.Order{}).Count(&orders)
+	if stock := stockOf(t, db, product.ID); stock != 0 || orders != 1 {
+		t.Fatalf("expected stock 0 and 1 order, got stock %d and %d orders", stock, orders)
+	}
+}
+
+// bumpInventoryVersion 在接下来 n 次扣减库存的 UPDATE 之前递增版本号，
+// 相当于在读取库存和更新之间有其他事务修改了库存
+func bumpInventoryVersion(t *testing.T, db *gorm.DB, n int) {
+	t.Helper()
+	err := db.Callback().Update().Before("gorm:update").Register("test:bump_inventory_version", func(tx *gorm.DB) {
+		if n == 0 || tx.Statement.Table != "inventories" {
+			return
+		}
+		n--
+		_, err := tx.Statement.ConnPool.ExecContext(tx.Statement.Context, "UPDATE inventories SET version = version + 1")
+		if err != nil {
+			_ = tx.AddError(err)
+		}
+	})
+	if err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+}
+
+func TestPlaceOrderRetriesStockConflict(t *testing.T) {
+	tests := []struct {
+		name      string
+		conflicts int
+		wantCode  int
+		wantStock int
+	}{
+		{name: "retried", conflicts: placeOrderMaxAttempts - 1, wantStock: 2},
+		{name: "gives up", conflicts: placeOrderMaxAttempts, wantCode: http.StatusConflict, wantStock: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newOrderTestDB(t)
+			product := seedProduct(t, db, "HOT", 100, 3)
+			addToCart(t, db, 1, product.ID, 1)
+			bumpInventoryVersion(t, db, tt.conflicts)
+
+			_, err := NewOrderService(db).PlaceOrder(context.Background(), 1)
+			assertAppError(t, err, tt.wantCode)
+			if stock := stockOf(t, db, product.ID); stock != tt.wantStock {
+				t.Fatalf("expected stock %d, got %d", tt.wantStock, stock)
+			}
+		})
+	}
+}
+
+func TestDecrementStockChecksVersion(t *testing.T) {
+	db := newOrderTestDB(t)
+	product := seedProduct(t, db, "V", 100, 2)
+	bumpInventoryVersion(t, db, 1)
+
+	if err := decrementStock(db, product.ID, 1); !errors.Is(err, errStockConflict) {
+		t.Fatalf("expected errStockConflict, got %v", err)
+	}
+	if err := decrementStock(db, product.ID, 1); err != nil {
+		t.Fatalf("decrement: %v", err)
+	}
+	var appErr *utils.AppError
+	if err := decrementStock(db, product.ID, 2); !errors.As(err, &appErr) || appErr.Code != http.StatusConflict {
+		t.Fatalf("expected insufficient stock, got %v", err)
+	}
+	if stock := stockOf(t, db, product.ID); stock != 1 {
+		t.Fatalf("expected stock 1, got %d", stock)
+	}
+}
+
+func TestCancelRestoresStock(t *testing.T) {
+	db := newOrderTestDB(t)
+	svc := NewOrderService(db)
+	ctx := context.Background()
+	product := seedProduct(t, db, "C", 100, 5)
+	addToCart(t, db, 1, product.ID, 3)
+	order, err := svc.PlaceOrder(ctx, 1)
+	if err != nil {
+		t.Fatalf("place order: %v", err)
+	}
+
+	// 只有下单的用户可以取消
+	_, err = svc.Cancel(ctx, order.ID, 2)
+	assertAppError(t, err, http.StatusNotFound)
+
+	cancelled, err := svc.Cancel(ctx, order.ID, 1)
+	if err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if cancelled.Status != models.OrderStatusCancelled || cancelled.CancelledAt == nil {
+		t.Fatalf("unexpected order %+v", cancelled)
+	}
+	if stock := stockOf(t, db, product.ID); stock != 5 {
+		t.Fatalf("expected stock to be restored to 5, got %d", stock)
+	}
+
+	// 重复取消不会再次归还库存
+	_, err = svc.Cancel(ctx, order.ID, 1)
+	assertAppError(t, err, http.StatusConflict)
+	if stock := stockOf(t, db, product.ID); stock != 5 {
+		t.Fatalf("expected stock to stay at 5, got %d", stock)
+	}
+}
+
+func TestCanTransitOrder(t *testing.T) {
+	statuses := []string{models.OrderStatusPending, models.OrderStatusPaid, models.OrderStatusShipped, models.OrderStatusCancelled}
+	allowed := map[[2]string]bool{
+		{models.OrderStatusPending, models.OrderStatusPaid}:      true,
+		{models.OrderStatusPending, models.OrderStatusCancelled}: true,
+		{models.OrderStatusPaid, models.OrderStatusShipped}:      true,
+		{models.OrderStatusPaid, models.OrderStatusCancelled}:    true,
+	}
+	for _, from := range statuses {
+		for _, to := range statuses {
+			if got := models.CanTransitOrder(from, to); got != allowed[[2]string{from, to}] {
+				t.Errorf("%s -> %s: expected %v, got %v", from, to, !got, got)
+			}
+		}
+	}
+	if models.CanTransitOrder("unknown", models.OrderStatusPaid) {
+		t.Error("expected unknown status to have no transitions")
+	}
+}
@@ -0,0 +1,313 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"projectdemo/models"
+	"projectdemo/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// InvitationTTL 邀请令牌的有效期
+	InvitationTTL = 7 * 24 * time.Hour
+	// InvitationRetention 过期的邀请保留一段时间，期间使用会得到 410 而不是 404
+	InvitationRetention = 30 * 24 * time.Hour
+)
+
+// OrgService 管理组织、成员和邀请。组织接口以路径中的组织 ID 为准，
+// 与 token 中的活动组织无关，权限按成员在该组织内的角色判断
+type OrgService struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+func NewOrgService(db *gorm.DB) *OrgService {
+	return &OrgService{db: db, now: time.Now}
+}
+
+// Create 创建组织，创建者成为 owner
+func (s *OrgService) Create(ctx context.Context, userID uint, req models.CreateOrganizationRequest) (*models.Organization, error) {
+	org := models.Organization{Name: req.Name}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&org).Error; err != nil {
+			return err
+		}
+		membership := models.Membership{OrgID: org.ID, UserID: userID, Role: models.OrgRoleOwner}
+		if err := tx.Create(&membership).Error; err != nil {
+			return err
+		}
+		return tx.Create(NewAuditEvent(ctx, models.AuditOrgCreate, "org", orgTargetID(org.ID), nil, map[string]string{"name": org.Name})).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// ListForUser 返回用户所在的所有组织
+func (s *OrgService) ListForUser(ctx context.Context, userID uint) ([]models.OrganizationResponse, error) {
+	orgs := make([]models.OrganizationResponse, 0)
+	err := s.db.WithContext(ctx).Model(&models.Membership{}).
+		Select("organizations.id, organizations.name, memberships.role, organizations.created_at").
+		Joins("JOIN organizations ON organizations.id = memberships.org_id").
+		Where("memberships.user_id = ?", userID).
+		Order("organizations.id ASC").
+		Scan(&orgs).Error
+	return orgs, err
+}
+
+// MemberRole 返回用户在组织内的角色，不是成员时返回 403
+func (s *OrgService) MemberRole(ctx context.Context, orgID, userID uint) (string, error) {
+	var membership models.Membership
+	err := s.db.WithContext(ctx).Where("org_id = ? AND user_id = ?", orgID, userID).First(&membership).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", utils.NewAppError(http.StatusForbidden, "Not a member of this organization")
+	}
+	if err != nil {
+		return "", err
+	}
+	return membership.Role, nil
+}
+
+// requireRole 要求用户是组织成员且角色在 roles 中（roles 为空时只要求是成员）。
+// 不是成员时返回 404，避免泄露组织是否存在
+func (s *OrgService) requireRole(ctx context.Context, orgID, userID uint, roles ...string) (string, error) {
+	role, err := s.MemberRole(ctx, orgID, userID)
+	if err != nil {
+		var appErr *utils.AppError
+		if errors.As(err, &appErr) {
+			return "", utils.NewAppError(http.StatusNotFound, "Organization not found")
+		}
+		return "", err
+	}
+	if len(roles) == 0 {
+		return role, nil
+	}
+	for _, r := range roles {
+		if role == r {
+			return role, nil
+		}
+	}
+	return "", utils.NewAppError(http.StatusForbidden, "Insufficient organization role")
+}
+
+func (s *OrgService) ListMembers(ctx context.Context, orgID, userID uint) ([]models.MemberResponse, error) {
+	if _, err := s.requireRole(ctx, orgID, userID); err != nil {
+		return nil, err
+	}
+
+	members := make([]models.MemberResponse, 0)
+	err := s.db.WithContext(ctx).Model(&models.Membership{}).
+		Select("users.id AS user_id, users.username, users.email, memberships.role, memberships.created_at AS joined_at").
+		Joins("JOIN users ON users.id = memberships.user_id AND users.deleted_at IS NULL").
+		Where("memberships.org_id = ?", orgID).
+		Order("memberships.id ASC").
+		Scan(&members).Error
+	return members, err
+}
+
+// RemoveMember 移除成员。owner 和 admin 可以移除他人，任何成员都可以移除自己（退出组织）；
+// 只有 owner 可以移除 owner，组织至少保留一个 owner
+func (s *OrgService) RemoveMember(ctx context.Context, orgID, actorID, memberID uint) error {
+	actorRole, err := s.requireRole(ctx, orgID, actorID)
+	if err != nil {
+		return err
+	}
+	if actorID != memberID && actorRole != models.OrgRoleOwner && actorRole != models.OrgRoleAdmin {
+		return utils.NewAppError(http.StatusForbidden, "Insufficient organization role")
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var membership models.Membership
+		err := tx.Where("org_id = ? AND user_id = ?", orgID, memberID).First(&membership).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.NewAppError(http.StatusNotFound, "Member not found")
+		}
+		if err != nil {
+			return err
+		}
+
+		if membership.Role == models.OrgRoleOwner {
+			if actorRole != models.OrgRoleOwner {
+				return utils.NewAppError(http.StatusForbidden, "Only owners can remove an owner")
+			}
+			var owners int64
+			if err := tx.Model(&models.Membership{}).Where("org_id = ? AND role = ?", orgID, models.OrgRoleOwner).Count(&owners).Error; err != nil {
+				return err
+			}
+			if owners <= 1 {
+				return utils.NewAppError(http.StatusConflict, "Cannot remove the last owner")
+			}
+		}
+
+		if err := tx.Delete(&membership).Error; err != nil {
+			return err
+		}
+		return tx.Create(NewAuditEvent(ctx, models.AuditOrgMemberRemove, "org", orgTargetID(orgID),
+			map[string]string{"user_id": strconv.FormatUint(uint64(memberID), 10), "role": membership.Role}, nil)).Error
+	})
+}
+
+// CreateInvitation 由 owner 或 admin 邀请邮箱加入组织，返回的 Token 只在此时可见
+func (s *OrgService) CreateInvitation(ctx context.Context, orgID, actorID uint, req models.CreateInvitationRequest) (*models.Invitation, error) {
+	if _, err := s.requireRole(ctx, orgID, actorID, models.OrgRoleOwner, models.OrgRoleAdmin); err != nil {
+		return nil, err
+	}
+
+	token, err := newInvitationToken()
+	if err != nil {
+		return nil, err
+	}
+	inv := models.Invitation{
+		OrgID:     orgID,
+		Email:     strings.ToLower(req.Email),
+		Role:      req.Role,
+		TokenHash: hashInvitationToken(token),
+		InvitedBy: actorID,
+		ExpiresAt: s.now().Add(InvitationTTL),
+	}
+	if inv.Role == "" {
+		inv.Role = models.OrgRoleMember
+	}
+
+	// 审计中不记录令牌
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&inv).Error; err != nil {
+			return err
+		}
+		return tx.Create(NewAuditEvent(ctx, models.AuditOrgInviteCreate, "org", orgTargetID(orgID), nil,
+			map[string]string{"email": inv.Email, "role": inv.Role})).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	inv.Token = token
+	return &inv, nil
+}
+
+// ListInvitations 返回尚未接受且未过期的邀请
+func (s *OrgService) ListInvitations(ctx context.Context, orgID, actorID uint) ([]models.Invitation, error) {
+	if _, err := s.requireRole(ctx, orgID, actorID, models.OrgRoleOwner, models.OrgRoleAdmin); err != nil {
+		return nil, err
+	}
+
+	invitations := make([]models.Invitation, 0)
+	err := s.db.WithContext(ctx).
+		Where("org_id = ? AND accepted_at IS NULL AND expires_at > ?", orgID, s.now()).
+		Order("id ASC").
+		Find(&invitations).Error
+	return invitations, err
+}
+
+func (s *OrgService) RevokeInvitation(ctx context.Context, orgID, actorID, invitationID uint) error {
+	if _, err := s.requireRole(ctx, orgID, actorID, models.OrgRoleOwner, models.OrgRoleAdmin); err != nil {
+		return err
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND org_id = ? AND accepted_at IS NULL", invitationID, orgID).Delete(&models.Invitation{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return utils.NewAppError(http.StatusNotFound, "Invitation not found")
+		}
+		return tx.Create(NewAuditEvent(ctx, models.AuditOrgInviteRevoke, "org", orgTargetID(orgID),
+			map[string]string{"invitation_id": strconv.FormatUint(uint64(invitationID), 10)}, nil)).Error
+	})
+}
+
+// PurgeExpiredInvitations 删除过期超过 InvitationRetention 且未被接受的邀请，返回删除的数量
+func (s *OrgService) PurgeExpiredInvitations(ctx context.Context) (int64, error) {
+	result := s.db.WithContext(ctx).
+		Where("accepted_at IS NULL AND expires_at < ?", s.now().Add(-InvitationRetention)).
+		Delete(&models.Invitation{})
+	return result.RowsAffected, result.Error
+}
+
+// AcceptInvitation 当前用户接受邀请。邀请只能由受邀邮箱对应的用户使用一次
+func (s *OrgService) AcceptInvitation(ctx context.Context, userID uint, token string) (*models.Membership, error) {
+	var inv models.Invitation
+	err := s.db.WithContext(ctx).Where("token_hash = ?", hashInvitationToken(token)).First(&inv).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, utils.NewAppError(http.StatusNotFound, "Invitation not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if inv.AcceptedAt != nil {
+		return nil, utils.NewAppError(http.StatusConflict, "Invitation already accepted")
+	}
+	if inv.Expired(s.now()) {
+		return nil, utils.NewAppError(http.StatusGone, "Invitation expired")
+	}
+
+	var user models.User
+	if err := s.db.WithContext(ctx).First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(http.StatusNotFound, "User not found")
+		}
+		return nil, err
+	}
+	if !strings.EqualFold(user.Email, inv.Email) {
+		return nil, utils.NewAppError(http.StatusForbidden, "Invitation was sent to a different email")
+	}
+
+	membership := models.Membership{OrgID: inv.OrgID, UserID: userID, Role: inv.Role}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 以 accepted_at IS NULL 为条件更新，并发接受同一邀请时只有一个成功
+		result := tx.Model(&models.Invitation{}).
+			Where("id = ? AND accepted_at IS NULL", inv.ID).
+			Update("accepted_at", s.now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return utils.NewAppError(http.StatusConflict, "Invitation already accepted")
+		}
+
+		var existing int64
+		if err := tx.Model(&models.Membership{}).Where("org_id = ? AND user_id = ?", inv.OrgID, userID).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return utils.NewAppError(http.StatusConflict, "Already a member of this organization")
+		}
+		if err := tx.Create(&membership).Error; err != nil {
+			return err
+		}
+		return tx.Create(NewAuditEvent(ctx, models.AuditOrgInviteAccept, "org", orgTargetID(inv.OrgID), nil,
+			map[string]string{"email": inv.Email, "role": inv.Role})).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &membership, nil
+}
+
+func newInvitationToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func orgTargetID(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"projectdemo/models"
+	"projectdemo/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ScheduleService 供管理接口查看定时任务的执行状态，任务的执行由 scheduler.Scheduler 负责
+type ScheduleService struct {
+	db *gorm.DB
+}
+
+func NewScheduleService(db *gorm.DB) *ScheduleService {
+	return &ScheduleService{db: db}
+}
+
+func (s *ScheduleService) List(ctx context.Context) ([]models.ScheduledTask, error) {
+	list := make([]models.ScheduledTask, 0)
+	err := s.db.WithContext(ctx).Order("name").Find(&list).Error
+	return list, err
+}
+
+// Trigger 把任务的下次执行时间提前到现在，由下一次轮询执行；正在执行的任务不受影响
+func (s *ScheduleService) Trigger(ctx context.Context, name string) (*models.ScheduledTask, error) {
+	result := s.db.WithContext(ctx).Model(&models.ScheduledTask{}).
+		Where("name = ?", name).Update("next_run_at", time.Now())
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, utils.NewAppError(http.StatusNotFound, "Scheduled task not found")
+	}
+
+	var task models.ScheduledTask
+	if err := s.db.WithContext(ctx).First(&task, "name = ?", name).Error; err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
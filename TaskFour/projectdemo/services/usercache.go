@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+	"log"
+	"projectdemo/cache"
+	"projectdemo/models"
+	"strconv"
+)
+
+// WithUserCache 按 ID 和用户名缓存 GetUserByID/GetUserByUsername 的结果，未设置时直接查询数据库。
+// 本服务修改用户后删除对应缓存；绕过服务直接修改数据库（如 CLI）时要等缓存过期。
+// 缓存中不保存密码哈希（Store 可能通过 WithCacheStore 与其他服务共用），需要密码的地方直接查询数据库
+func WithUserCache(users *cache.Loader[models.User]) UserServiceOption {
+	return func(s *userService) {
+		s.users = users
+	}
+}
+
+func userIDKey(id uint) string {
+	return "id:" + strconv.FormatUint(uint64(id), 10)
+}
+
+func usernameKey(username string) string {
+	return "name:" + username
+}
+
+// cachedUser 通过缓存读取用户，未设置缓存时直接调用 load
+func (s *userService) cachedUser(ctx context.Context, key string, load func(context.Context) (*models.User, error)) (*models.User, error) {
+	if s.users == nil {
+		return load(ctx)
+	}
+	user, err := s.users.Get(ctx, key, func(ctx context.Context) (models.User, error) {
+		u, err := load(ctx)
+		if err != nil {
+			return models.User{}, err
+		}
+		cached := *u
+		cached.Password = ""
+		return cached, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// invalidate 在事务提交之后调用，删除用户按 ID 和用户名的缓存
+func (s *userService) invalidate(ctx context.Context, user *models.User) {
+	if s.users == nil {
+		return
+	}
+	if err := s.users.Delete(ctx, userIDKey(user.ID), usernameKey(user.Username)); err != nil {
+		log.Printf("invalidate user %d: %v", user.ID, err)
+	}
+}
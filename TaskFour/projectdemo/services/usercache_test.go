@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"projectdemo/cache"
+	"projectdemo/models"
+	"projectdemo/repository"
+	"testing"
+	"time"
+)
+
+func TestUserServiceCache(t *testing.T) {
+	store := repository.NewMemoryStore()
+	users := cache.NewLoader[models.User](cache.NewLRU(100), "user:", time.Minute)
+	svc := NewUserService(store, WithUserCache(users))
+	ctx := context.Background()
+	alice, err := svc.CreateUser(ctx, models.CreateUserRequest{Username: "alice", Email: "alice@example.com", Password: "secret123"})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := svc.GetUserByID(ctx, alice.ID); err != nil {
+			t.Fatalf("get user: %v", err)
+		}
+	}
+	if stats := users.Stats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Fatalf("expected one hit and one miss, got %+v", stats)
+	}
+
+	// 绕过服务修改的数据在缓存过期前不可见
+	raw, _ := store.Users().FindByID(ctx, alice.ID)
+	raw.DisplayName = "Changed Elsewhere"
+	if err := store.Users().Save(ctx, raw); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if u, _ := svc.GetUserByID(ctx, alice.ID); u.DisplayName != "" {
+		t.Fatalf("expected cached user, got display name %q", u.DisplayName)
+	}
+
+	// 通过服务修改后缓存失效，修改基于数据库中的最新数据
+	bio := "hello"
+	if _, err := svc.UpdateUser(ctx, alice.ID, models.UpdateUserRequest{Bio: &bio}); err != nil {
+		t.Fatalf("update user: %v", err)
+	}
+	u, err := svc.GetUserByID(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	if u.Bio != "hello" || u.DisplayName != "Changed Elsewhere" {
+		t.Fatalf("expected fresh user after update, got %+v", u)
+	}
+
+	byName, err := svc.GetUserByUsername(ctx, "alice")
+	if err != nil {
+		t.Fatalf("get by username: %v", err)
+	}
+	if byName.Password != "" {
+		t.Fatal("expected the password hash to be stripped before caching")
+	}
+	if _, err := svc.Authenticate(ctx, "alice", "secret123"); err != nil {
+		t.Fatalf("authenticate after cached read: %v", err)
+	}
+	if err := svc.DeleteUser(ctx, alice.ID); err != nil {
+		t.Fatalf("delete user: %v", err)
+	}
+	_, err = svc.GetUserByID(ctx, alice.ID)
+	assertAppError(t, err, 404)
+	_, err = svc.GetUserByUsername(ctx, "alice")
+	assertAppError(t, err, 404)
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"log"
+	"projectdemo/events"
+	"projectdemo/models"
+)
+
+// 用户领域事件，在事务提交后发布到 events.Bus。User 为发布时的副本（不含密码哈希），订阅者可以安全地持有
+type (
+	UserRegistered struct {
+		User models.User
+	}
+	UserLoggedIn struct {
+		User models.User
+	}
+	UserEmailChanged struct {
+		User          models.User
+		PreviousEmail string
+	}
+	UserEmailVerified struct {
+		User models.User
+	}
+	UserDeleted struct {
+		User models.User
+	}
+	UserDisabled struct {
+		User models.User
+	}
+	UserPasswordReset struct {
+		User models.User
+	}
+	UserRoleChanged struct {
+		User         models.User
+		PreviousRole string
+	}
+)
+
+// eventUser 返回事件中携带的用户副本，清除密码哈希，订阅者（webhook、审计等）不会拿到它
+func eventUser(user *models.User) models.User {
+	u := *user
+	u.Password = ""
+	return u
+}
+
+type UserServiceOption func(*userService)
+
+// WithEventBus 设置发布用户领域事件的总线，未设置时不发布
+func WithEventBus(bus *events.Bus) UserServiceOption {
+	return func(s *userService) {
+		s.bus = bus
+	}
+}
+
+// publish 在业务已提交之后调用，同步订阅者的错误只记录日志，不改变返回给调用方的结果
+func publish[T any](ctx context.Context, bus *events.Bus, event T) {
+	if err := events.Publish(ctx, bus, event); err != nil {
+		log.Printf("publish %T: %v", event, err)
+	}
+}
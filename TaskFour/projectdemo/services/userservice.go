@@ -1,34 +1,55 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"projectdemo/cache"
+	"projectdemo/events"
 	"projectdemo/models"
+	"projectdemo/repository"
 	"projectdemo/utils"
+	"strconv"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
-	"gorm.io/gorm"
 )
 
-type UserService struct {
-	db *gorm.DB
+type UserService interface {
+	CreateUser(ctx context.Context, req models.CreateUserRequest) (*models.User, error)
+	GetUserByID(ctx context.Context, id uint) (*models.User, error)
+	// LoadUser 不经过缓存读取用户，签发 token 时角色和禁用状态以数据库为准
+	LoadUser(ctx context.Context, id uint) (*models.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
+	ListUsers(ctx context.Context, offset, limit int) ([]models.User, int64, error)
+	Authenticate(ctx context.Context, username, password string) (*models.User, error)
+	UpdateUser(ctx context.Context, id uint, req models.UpdateUserRequest) (*models.User, error)
+	// SetAvatar 更新头像并返回旧的头像 key，由调用方负责清理旧文件
+	SetAvatar(ctx context.Context, id uint, key string) (string, error)
+	DeleteUser(ctx context.Context, id uint) error
+	// VerifyEmail 标记当前邮箱已验证，已验证时不做任何操作
+	VerifyEmail(ctx context.Context, id uint) error
+	// DisableUser 禁止用户登录，已签发的 token 由 Auth 中间件拒绝
+	DisableUser(ctx context.Context, id uint) error
+	ResetPassword(ctx context.Context, id uint, password string) error
+	SetRole(ctx context.Context, id uint, role string) error
 }
 
-func NewUserService(db *gorm.DB) *UserService {
-	return &UserService{db: db}
+type userService struct {
+	store repository.Store
+	bus   *events.Bus
+	users *cache.Loader[models.User]
 }
 
-func (s *UserService) CreateUser(req models.CreateUserRequest) (*models.User, error) {
-	// 检查用户名是否已存在
-	var existingUser models.User
-	if err := s.db.Where("username = ?", req.Username).First(&existingUser).Error; err == nil {
-		return nil, utils.NewAppError(409, "Username already exists")
-	}
-
-	// 检查邮箱是否已存在
-	if err := s.db.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
-		return nil, utils.NewAppError(409, "Email already exists")
+func NewUserService(store repository.Store, opts ...UserServiceOption) UserService {
+	s := &userService{store: store}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
+}
 
+func (s *userService) CreateUser(ctx context.Context, req models.CreateUserRequest) (*models.User, error) {
 	// 加密密码
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -40,60 +61,395 @@ func (s *UserService) CreateUser(req models.CreateUserRequest) (*models.User, er
 		Username: req.Username,
 		Email:    req.Email,
 		Password: string(hashedPassword),
+		Role:     models.RoleUser,
 	}
 
-	if err := s.db.Create(&user).Error; err != nil {
-		return nil, err
+	// 不预先查询用户名/邮箱是否存在：并发注册时两次查询都可能通过检查，
+	// 这里直接依赖唯一索引，冲突时由仓储返回 DuplicateKeyError
+	err = s.store.Transaction(ctx, func(tx repository.Store) error {
+		if err := tx.Users().Create(ctx, &user); err != nil {
+			return err
+		}
+		if err := enqueueUserEvent(ctx, tx, models.EventUserRegistered, &user, ""); err != nil {
+			return err
+		}
+		return tx.Audit().Create(ctx, NewAuditEvent(asActor(ctx, &user), models.AuditUserRegister, "user", userTargetID(&user), nil, auditUserState(&user)))
+	})
+	if err != nil {
+		return nil, conflictError(err)
 	}
 
+	publish(ctx, s.bus, UserRegistered{User: eventUser(&user)})
 	return &user, nil
 }
 
-func (s *UserService) GetUserByID(id uint) (*models.User, error) {
-	var user models.User
-	if err := s.db.First(&user, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+func (s *userService) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+	return s.cachedUser(ctx, userIDKey(id), func(ctx context.Context) (*models.User, error) {
+		return s.findByID(ctx, id)
+	})
+}
+
+func (s *userService) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	return s.cachedUser(ctx, usernameKey(username), func(ctx context.Context) (*models.User, error) {
+		user, err := s.store.Users().FindByUsername(ctx, username)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil, utils.NewAppError(404, "User not found")
+			}
+			return nil, err
+		}
+		return user, nil
+	})
+}
+
+// LoadUser 直接查询数据库，签发 token 时角色和禁用状态不受缓存延迟影响
+func (s *userService) LoadUser(ctx context.Context, id uint) (*models.User, error) {
+	return s.findByID(ctx, id)
+}
+
+// findByID 绕过缓存读取用户，修改用户之前必须基于数据库中的最新数据
+func (s *userService) findByID(ctx context.Context, id uint) (*models.User, error) {
+	user, err := s.store.Users().FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			return nil, utils.NewAppError(404, "User not found")
 		}
 		return nil, err
 	}
-	return &user, nil
+	return user, nil
+}
+
+func (s *userService) ListUsers(ctx context.Context, offset, limit int) ([]models.User, int64, error) {
+	return s.store.Users().List(ctx, offset, limit)
 }
 
-func (s *UserService) Authenticate(username, password string) (*models.User, error) {
-	var user models.User
-	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+func (s *userService) Authenticate(ctx context.Context, username, password string) (*models.User, error) {
+	user, err := s.store.Users().FindByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			s.recordLoginFailure(ctx, username, "unknown_user")
 			return nil, utils.NewAppError(401, "Invalid credentials")
 		}
 		return nil, err
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		s.recordLoginFailure(ctx, username, "bad_password")
 		return nil, utils.NewAppError(401, "Invalid credentials")
 	}
 
-	return &user, nil
+	// 只有密码正确时才提示账号已禁用，避免泄露用户是否存在
+	if user.IsDisabled() {
+		s.recordLoginFailure(ctx, username, "disabled")
+		return nil, utils.NewAppError(403, "Account disabled")
+	}
+
+	event := NewAuditEvent(asActor(ctx, user), models.AuditUserLoginSuccess, "user", userTargetID(user), nil, nil)
+	if err := s.store.Audit().Create(ctx, event); err != nil {
+		return nil, err
+	}
+
+	publish(ctx, s.bus, UserLoggedIn{User: eventUser(user)})
+	return user, nil
 }
 
-func (s *UserService) UpdateUser(id uint, req models.UpdateUserRequest) (*models.User, error) {
-	user, err := s.GetUserByID(id)
+func (s *userService) UpdateUser(ctx context.Context, id uint, req models.UpdateUserRequest) (*models.User, error) {
+	user, err := s.findByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	// 如果更新邮箱，检查是否已存在
-	if req.Email != "" && req.Email != user.Email {
-		var existingUser models.User
-		if err := s.db.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
-			return nil, utils.NewAppError(409, "Email already exists")
-		}
+	// 只更新请求修改的列，不会覆盖同时进行的禁用、改角色等操作
+	before, after := applyProfile(user, req)
+	fields := make(map[string]interface{}, len(after)+2)
+	for column, value := range after {
+		fields[column] = value
+	}
+	// 邮箱是否已被占用同样交给唯一索引判断
+	oldEmail := user.Email
+	if req.Email != "" && req.Email != oldEmail {
 		user.Email = req.Email
+		// 新邮箱需要重新验证
+		user.EmailVerifiedAt = nil
+		fields["email"] = req.Email
+		fields["email_verified_at"] = nil
+	}
+	if len(fields) == 0 {
+		return user, nil
 	}
 
-	if err := s.db.Save(user).Error; err != nil {
-		return nil, err
+	err = s.store.Transaction(ctx, func(tx repository.Store) error {
+		if err := tx.Users().Update(ctx, id, fields); err != nil {
+			return err
+		}
+		if user.Email != oldEmail {
+			event := NewAuditEvent(ctx, models.AuditUserEmailChange, "user", userTargetID(user),
+				map[string]string{"email": oldEmail}, map[string]string{"email": user.Email})
+			if err := tx.Audit().Create(ctx, event); err != nil {
+				return err
+			}
+			if err := enqueueUserEvent(ctx, tx, models.EventUserEmailChanged, user, oldEmail); err != nil {
+				return err
+			}
+		}
+		if len(after) > 0 {
+			if err := tx.Audit().Create(ctx, NewAuditEvent(ctx, models.AuditUserProfileUpdate, "user", userTargetID(user), before, after)); err != nil {
+				return err
+			}
+		}
+		// 返回包含其他字段最新值的用户
+		updated, err := tx.Users().FindByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		user = updated
+		return nil
+	})
+	if err != nil {
+		return nil, conflictError(err)
 	}
+	s.invalidate(ctx, user)
 
+	if user.Email != oldEmail {
+		publish(ctx, s.bus, UserEmailChanged{User: eventUser(user), PreviousEmail: oldEmail})
+	}
 	return user, nil
 }
+
+func (s *userService) SetAvatar(ctx context.Context, id uint, key string) (string, error) {
+	user, err := s.findByID(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	oldKey := user.AvatarKey
+	user.AvatarKey = key
+	err = s.store.Transaction(ctx, func(tx repository.Store) error {
+		if err := tx.Users().Update(ctx, id, map[string]interface{}{"avatar_key": key}); err != nil {
+			return err
+		}
+		return tx.Audit().Create(ctx, NewAuditEvent(ctx, models.AuditUserAvatarChange, "user", userTargetID(user),
+			map[string]string{"avatar_key": oldKey}, map[string]string{"avatar_key": key}))
+	})
+	if err != nil {
+		return "", err
+	}
+	s.invalidate(ctx, user)
+	return oldKey, nil
+}
+
+func (s *userService) DeleteUser(ctx context.Context, id uint) error {
+	user, err := s.findByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = s.store.Transaction(ctx, func(tx repository.Store) error {
+		if err := tx.Users().Delete(ctx, user); err != nil {
+			return err
+		}
+		if err := enqueueUserEvent(ctx, tx, models.EventUserDeleted, user, ""); err != nil {
+			return err
+		}
+		return tx.Audit().Create(ctx, NewAuditEvent(ctx, models.AuditUserDelete, "user", userTargetID(user), auditUserState(user), nil))
+	})
+	if err != nil {
+		return err
+	}
+	s.invalidate(ctx, user)
+	publish(ctx, s.bus, UserDeleted{User: eventUser(user)})
+	return nil
+}
+
+func (s *userService) VerifyEmail(ctx context.Context, id uint) error {
+	user, err := s.findByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	err = s.store.Transaction(ctx, func(tx repository.Store) error {
+		if err := tx.Users().Update(ctx, id, map[string]interface{}{"email_verified_at": now}); err != nil {
+			return err
+		}
+		if err := enqueueUserEvent(ctx, tx, models.EventUserVerified, user, ""); err != nil {
+			return err
+		}
+		return tx.Audit().Create(ctx, NewAuditEvent(ctx, models.AuditUserEmailVerify, "user", userTargetID(user), nil, map[string]string{"email": user.Email}))
+	})
+	if err != nil {
+		return err
+	}
+	s.invalidate(ctx, user)
+	publish(ctx, s.bus, UserEmailVerified{User: eventUser(user)})
+	return nil
+}
+
+func (s *userService) DisableUser(ctx context.Context, id uint) error {
+	user, err := s.findByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if user.IsDisabled() {
+		return nil
+	}
+
+	now := time.Now()
+	user.DisabledAt = &now
+	err = s.store.Transaction(ctx, func(tx repository.Store) error {
+		if err := tx.Users().Update(ctx, id, map[string]interface{}{"disabled_at": now}); err != nil {
+			return err
+		}
+		return tx.Audit().Create(ctx, NewAuditEvent(ctx, models.AuditUserDisable, "user", userTargetID(user), nil, nil))
+	})
+	if err != nil {
+		return err
+	}
+	s.invalidate(ctx, user)
+	publish(ctx, s.bus, UserDisabled{User: eventUser(user)})
+	return nil
+}
+
+func (s *userService) ResetPassword(ctx context.Context, id uint, password string) error {
+	user, err := s.findByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	user.Password = string(hashedPassword)
+
+	// 审计中不记录密码
+	err = s.store.Transaction(ctx, func(tx repository.Store) error {
+		if err := tx.Users().Update(ctx, id, map[string]interface{}{"password": user.Password}); err != nil {
+			return err
+		}
+		return tx.Audit().Create(ctx, NewAuditEvent(ctx, models.AuditUserPasswordReset, "user", userTargetID(user), nil, nil))
+	})
+	if err != nil {
+		return err
+	}
+	s.invalidate(ctx, user)
+	publish(ctx, s.bus, UserPasswordReset{User: eventUser(user)})
+	return nil
+}
+
+func (s *userService) SetRole(ctx context.Context, id uint, role string) error {
+	if role != models.RoleUser && role != models.RoleAdmin {
+		return utils.NewAppError(400, "Unknown role "+role)
+	}
+
+	user, err := s.findByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if user.Role == role {
+		return nil
+	}
+
+	oldRole := user.Role
+	user.Role = role
+	err = s.store.Transaction(ctx, func(tx repository.Store) error {
+		if err := tx.Users().Update(ctx, id, map[string]interface{}{"role": role}); err != nil {
+			return err
+		}
+		return tx.Audit().Create(ctx, NewAuditEvent(ctx, models.AuditUserRoleChange, "user", userTargetID(user),
+			map[string]string{"role": oldRole}, map[string]string{"role": role}))
+	})
+	if err != nil {
+		return err
+	}
+	s.invalidate(ctx, user)
+	publish(ctx, s.bus, UserRoleChanged{User: eventUser(user), PreviousRole: oldRole})
+	return nil
+}
+
+func (s *userService) recordLoginFailure(ctx context.Context, username, reason string) {
+	// 登录失败的审计写入失败不应改变返回给客户端的结果
+	_ = s.store.Audit().Create(ctx, NewAuditEvent(ctx, models.AuditUserLoginFailure, "user", username, nil, map[string]string{
+		"username": username,
+		"reason":   reason,
+	}))
+}
+
+// conflictError 将唯一约束冲突转换为 409，并指明冲突的字段
+func conflictError(err error) error {
+	var dup *repository.DuplicateKeyError
+	if !errors.As(err, &dup) {
+		return err
+	}
+	switch dup.Field {
+	case "username":
+		return utils.NewConflictError(dup.Field, "Username already exists")
+	case "email":
+		return utils.NewConflictError(dup.Field, "Email already exists")
+	default:
+		return utils.NewConflictError(dup.Field, "Resource already exists")
+	}
+}
+
+// asActor 注册和登录发生在认证之前，此时操作者就是目标用户本身
+func asActor(ctx context.Context, user *models.User) context.Context {
+	meta := utils.RequestMetaFrom(ctx)
+	meta.UserID = user.ID
+	meta.Username = user.Username
+	return utils.WithRequestMeta(ctx, meta)
+}
+
+// applyProfile 将请求中的资料字段写入 user，返回变更前后的差异
+func applyProfile(user *models.User, req models.UpdateUserRequest) (before, after map[string]string) {
+	before, after = map[string]string{}, map[string]string{}
+	fields := []struct {
+		name  string
+		value *string
+		dst   *string
+	}{
+		{"display_name", req.DisplayName, &user.DisplayName},
+		{"bio", req.Bio, &user.Bio},
+		{"locale", req.Locale, &user.Locale},
+		{"timezone", req.Timezone, &user.Timezone},
+	}
+	for _, f := range fields {
+		if f.value == nil || *f.value == *f.dst {
+			continue
+		}
+		before[f.name] = *f.dst
+		after[f.name] = *f.value
+		*f.dst = *f.value
+	}
+	return before, after
+}
+
+// enqueueUserEvent 在事务 tx 中写入 webhook 事件，业务写入回滚时事件一起回滚
+func enqueueUserEvent(ctx context.Context, tx repository.Store, eventType string, user *models.User, previousEmail string) error {
+	payload, err := json.Marshal(models.UserEventData{
+		ID:            user.ID,
+		Username:      user.Username,
+		Email:         user.Email,
+		PreviousEmail: previousEmail,
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Outbox().Create(ctx, &models.OutboxEvent{Type: eventType, Payload: string(payload)})
+}
+
+func userTargetID(user *models.User) string {
+	return strconv.FormatUint(uint64(user.ID), 10)
+}
+
+func auditUserState(user *models.User) map[string]interface{} {
+	return map[string]interface{}{
+		"id":       user.ID,
+		"username": user.Username,
+		"email":    user.Email,
+		"role":     user.Role,
+	}
+}
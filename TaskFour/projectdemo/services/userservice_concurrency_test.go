@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"projectdemo/models"
+	"projectdemo/repository"
+	"projectdemo/utils"
+	"sync"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newSQLiteStore 使用真实的 SQLite 数据库，唯一索引的行为才与线上一致
+func newSQLiteStore(t *testing.T) (repository.Store, *gorm.DB) {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "users.db") + "?_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("get generic db: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = sqlDB.Close()
+	})
+
+	if err := db.AutoMigrate(&models.User{}, &models.AuditEvent{}, &models.OutboxEvent{}); err != nil {
+		t.Fatalf("auto migrate: %v", err)
+	}
+	return repository.NewGormStore(db), db
+}
+
+func TestCreateUserConcurrentRegistrations(t *testing.T) {
+	const workers = 16
+
+	tests := []struct {
+		name      string
+		req       func(i int) models.CreateUserRequest
+		wantField string
+	}{
+		{
+			name: "same username",
+			req: func(i int) models.CreateUserRequest {
+				return models.CreateUserRequest{Username: "racer", Email: fmt.Sprintf("racer%d@example.com", i), Password: "secret123"}
+			},
+			wantField: "username",
+		},
+		{
+			name: "same email",
+			req: func(i int) models.CreateUserRequest {
+				return models.CreateUserRequest{Username: fmt.Sprintf("racer%d", i), Email: "racer@example.com", Password: "secret123"}
+			},
+			wantField: "email",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, db := newSQLiteStore(t)
+			svc := NewUserService(store)
+
+			// 所有 goroutine 就绪后同时开始，尽量制造竞争
+			start := make(chan struct{})
+			errs := make([]error, workers)
+			var wg sync.WaitGroup
+			for i := 0; i < workers; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					<-start
+					_, errs[i] = svc.CreateUser(context.Background(), tt.req(i))
+				}(i)
+			}
+			close(start)
+			wg.Wait()
+
+			succeeded := 0
+			for i, err := range errs {
+				if err == nil {
+					succeeded++
+					continue
+				}
+				var appErr *utils.AppError
+				if !errors.As(err, &appErr) || appErr.Code != 409 || appErr.Field != tt.wantField {
+					t.Errorf("worker %d: expected 409 on %s, got %v", i, tt.wantField, err)
+				}
+			}
+			if succeeded != 1 {
+				t.Fatalf("expected exactly one successful registration, got %d", succeeded)
+			}
+
+			var users, events, outbox int64
+			db.Model(&models.User{}).Count(&users)
+			db.Model(&models.AuditEvent{}).Where("action = ?", models.AuditUserRegister).Count(&events)
+			db.Model(&models.OutboxEvent{}).Count(&outbox)
+			if users != 1 || events != 1 {
+				t.Fatalf("expected 1 user and 1 register event, got %d users and %d events", users, events)
+			}
+			// 失败的注册随事务回滚，不会留下 webhook 事件
+			if outbox != 1 {
+				t.Fatalf("expected 1 outbox event, got %d", outbox)
+			}
+		})
+	}
+}
+
+func TestConcurrentUserUpdatesKeepEachOther(t *testing.T) {
+	const rounds = 10
+	store, _ := newSQLiteStore(t)
+	svc := NewUserService(store)
+	ctx := context.Background()
+	user, err := svc.CreateUser(ctx, models.CreateUserRequest{Username: "target", Email: "target@example.com", Password: "secret123"})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	// 管理操作和资料修改同时进行，每个操作只写自己的列，不会互相覆盖
+	start := make(chan struct{})
+	errs := make(chan error, 3*rounds)
+	var wg sync.WaitGroup
+	for i := 0; i < rounds; i++ {
+		bio := fmt.Sprintf("bio %d", i)
+		ops := []func() error{
+			func() error { return svc.DisableUser(ctx, user.ID) },
+			func() error { return svc.SetRole(ctx, user.ID, models.RoleAdmin) },
+			func() error {
+				_, err := svc.UpdateUser(ctx, user.ID, models.UpdateUserRequest{Bio: &bio})
+				return err
+			},
+		}
+		for _, op := range ops {
+			wg.Add(1)
+			go func(op func() error) {
+				defer wg.Done()
+				<-start
+				errs <- op()
+			}(op)
+		}
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("update: %v", err)
+		}
+	}
+
+	got, err := store.Users().FindByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("find user: %v", err)
+	}
+	if !got.IsDisabled() || got.Role != models.RoleAdmin || got.Bio == "" {
+		t.Fatalf("expected user to be disabled, admin and have a bio, got %+v", got)
+	}
+}
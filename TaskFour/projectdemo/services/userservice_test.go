@@ -0,0 +1,421 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"projectdemo/events"
+	"projectdemo/models"
+	"projectdemo/repository"
+	"projectdemo/utils"
+	"strings"
+	"testing"
+)
+
+func newTestUserService(t *testing.T) (UserService, *repository.MemoryStore) {
+	t.Helper()
+	store := repository.NewMemoryStore()
+	svc := NewUserService(store)
+
+	// 预置用户 alice，供冲突和登录用例使用
+	_, err := svc.CreateUser(context.Background(), models.CreateUserRequest{
+		Username: "alice",
+		Email:    "alice@example.com",
+		Password: "secret123",
+	})
+	if err != nil {
+		t.Fatalf("seed user: %v", err)
+	}
+	return svc, store
+}
+
+func assertAppError(t *testing.T, err error, wantCode int) {
+	t.Helper()
+	if wantCode == 0 {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return
+	}
+	var appErr *utils.AppError
+	if !errors.As(err, &appErr) {
+		t.Fatalf("expected AppError with code %d, got %v", wantCode, err)
+	}
+	if appErr.Code != wantCode {
+		t.Fatalf("expected code %d, got %d (%s)", wantCode, appErr.Code, appErr.Message)
+	}
+}
+
+func lastAuditAction(store *repository.MemoryStore) string {
+	events := store.AuditEvents()
+	if len(events) == 0 {
+		return ""
+	}
+	return events[len(events)-1].Action
+}
+
+func TestCreateUser(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      models.CreateUserRequest
+		wantCode int
+	}{
+		{
+			name: "success",
+			req:  models.CreateUserRequest{Username: "bob", Email: "bob@example.com", Password: "secret123"},
+		},
+		{
+			name:     "duplicate username",
+			req:      models.CreateUserRequest{Username: "alice", Email: "other@example.com", Password: "secret123"},
+			wantCode: 409,
+		},
+		{
+			name:     "duplicate email",
+			req:      models.CreateUserRequest{Username: "carol", Email: "alice@example.com", Password: "secret123"},
+			wantCode: 409,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, store := newTestUserService(t)
+			before := len(store.AuditEvents())
+
+			user, err := svc.CreateUser(context.Background(), tt.req)
+			assertAppError(t, err, tt.wantCode)
+			if tt.wantCode != 0 {
+				if got := len(store.AuditEvents()); got != before {
+					t.Fatalf("failed registration should not be audited, got %d new events", got-before)
+				}
+				return
+			}
+
+			if user.ID == 0 || user.Role != models.RoleUser {
+				t.Fatalf("unexpected user: %+v", user)
+			}
+			if user.Password == tt.req.Password {
+				t.Fatal("password should be hashed")
+			}
+			if got := lastAuditAction(store); got != models.AuditUserRegister {
+				t.Fatalf("expected %s audit event, got %q", models.AuditUserRegister, got)
+			}
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	tests := []struct {
+		name      string
+		username  string
+		password  string
+		wantCode  int
+		wantAudit string
+	}{
+		{"success", "alice", "secret123", 0, models.AuditUserLoginSuccess},
+		{"wrong password", "alice", "wrong", 401, models.AuditUserLoginFailure},
+		{"unknown user", "nobody", "secret123", 401, models.AuditUserLoginFailure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, store := newTestUserService(t)
+
+			user, err := svc.Authenticate(context.Background(), tt.username, tt.password)
+			assertAppError(t, err, tt.wantCode)
+			if tt.wantCode == 0 && user.Username != tt.username {
+				t.Fatalf("expected user %s, got %s", tt.username, user.Username)
+			}
+			if got := lastAuditAction(store); got != tt.wantAudit {
+				t.Fatalf("expected %s audit event, got %q", tt.wantAudit, got)
+			}
+		})
+	}
+}
+
+func TestUpdateUser(t *testing.T) {
+	displayName := "Alice A."
+
+	tests := []struct {
+		name      string
+		id        uint
+		req       models.UpdateUserRequest
+		wantCode  int
+		wantEmail string
+	}{
+		{
+			name:      "change email",
+			id:        1,
+			req:       models.UpdateUserRequest{Email: "new@example.com"},
+			wantEmail: "new@example.com",
+		},
+		{
+			name:      "update profile keeps email",
+			id:        1,
+			req:       models.UpdateUserRequest{DisplayName: &displayName},
+			wantEmail: "alice@example.com",
+		},
+		{
+			name:     "email taken",
+			id:       1,
+			req:      models.UpdateUserRequest{Email: "bob@example.com"},
+			wantCode: 409,
+		},
+		{
+			name:     "user not found",
+			id:       99,
+			req:      models.UpdateUserRequest{Email: "x@example.com"},
+			wantCode: 404,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _ := newTestUserService(t)
+			ctx := context.Background()
+			if _, err := svc.CreateUser(ctx, models.CreateUserRequest{Username: "bob", Email: "bob@example.com", Password: "secret123"}); err != nil {
+				t.Fatalf("seed bob: %v", err)
+			}
+
+			user, err := svc.UpdateUser(ctx, tt.id, tt.req)
+			assertAppError(t, err, tt.wantCode)
+			if tt.wantCode != 0 {
+				return
+			}
+
+			stored, err := svc.GetUserByID(ctx, tt.id)
+			if err != nil {
+				t.Fatalf("reload user: %v", err)
+			}
+			if stored.Email != tt.wantEmail || user.Email != tt.wantEmail {
+				t.Fatalf("expected email %s, got %s", tt.wantEmail, stored.Email)
+			}
+			if tt.req.DisplayName != nil && stored.DisplayName != *tt.req.DisplayName {
+				t.Fatalf("expected display name %s, got %s", *tt.req.DisplayName, stored.DisplayName)
+			}
+		})
+	}
+}
+
+func TestDeleteUserHidesUser(t *testing.T) {
+	svc, store := newTestUserService(t)
+	ctx := context.Background()
+
+	if err := svc.DeleteUser(ctx, 1); err != nil {
+		t.Fatalf("delete user: %v", err)
+	}
+	_, err := svc.GetUserByID(ctx, 1)
+	assertAppError(t, err, 404)
+	assertAppError(t, svc.DeleteUser(ctx, 1), 404)
+
+	if got := lastAuditAction(store); got != models.AuditUserDelete {
+		t.Fatalf("expected %s audit event, got %q", models.AuditUserDelete, got)
+	}
+}
+
+func TestAdminUserOperations(t *testing.T) {
+	tests := []struct {
+		name      string
+		run       func(svc UserService) error
+		wantCode  int
+		wantAudit string
+	}{
+		{
+			name:      "disable",
+			run:       func(svc UserService) error { return svc.DisableUser(context.Background(), 1) },
+			wantAudit: models.AuditUserDisable,
+		},
+		{
+			name:     "disable unknown user",
+			run:      func(svc UserService) error { return svc.DisableUser(context.Background(), 99) },
+			wantCode: 404,
+		},
+		{
+			name:      "reset password",
+			run:       func(svc UserService) error { return svc.ResetPassword(context.Background(), 1, "newsecret") },
+			wantAudit: models.AuditUserPasswordReset,
+		},
+		{
+			name:      "promote to admin",
+			run:       func(svc UserService) error { return svc.SetRole(context.Background(), 1, models.RoleAdmin) },
+			wantAudit: models.AuditUserRoleChange,
+		},
+		{
+			name:     "unknown role",
+			run:      func(svc UserService) error { return svc.SetRole(context.Background(), 1, "root") },
+			wantCode: 400,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, store := newTestUserService(t)
+			assertAppError(t, tt.run(svc), tt.wantCode)
+			if tt.wantCode != 0 {
+				return
+			}
+			if got := lastAuditAction(store); got != tt.wantAudit {
+				t.Fatalf("expected %s audit event, got %q", tt.wantAudit, got)
+			}
+		})
+	}
+}
+
+func TestDisabledUserCannotLogin(t *testing.T) {
+	svc, store := newTestUserService(t)
+	ctx := context.Background()
+
+	if err := svc.DisableUser(ctx, 1); err != nil {
+		t.Fatalf("disable user: %v", err)
+	}
+	// 错误密码仍返回 401，不泄露账号状态
+	_, err := svc.Authenticate(ctx, "alice", "wrong")
+	assertAppError(t, err, 401)
+	_, err = svc.Authenticate(ctx, "alice", "secret123")
+	assertAppError(t, err, 403)
+	if got := lastAuditAction(store); got != models.AuditUserLoginFailure {
+		t.Fatalf("expected %s audit event, got %q", models.AuditUserLoginFailure, got)
+	}
+}
+
+func TestResetPasswordReplacesOldPassword(t *testing.T) {
+	svc, _ := newTestUserService(t)
+	ctx := context.Background()
+
+	if err := svc.ResetPassword(ctx, 1, "newsecret"); err != nil {
+		t.Fatalf("reset password: %v", err)
+	}
+	_, err := svc.Authenticate(ctx, "alice", "secret123")
+	assertAppError(t, err, 401)
+	_, err = svc.Authenticate(ctx, "alice", "newsecret")
+	assertAppError(t, err, 0)
+}
+
+func TestUserEventsWrittenToOutbox(t *testing.T) {
+	svc, store := newTestUserService(t)
+	ctx := context.Background()
+
+	if _, err := svc.UpdateUser(ctx, 1, models.UpdateUserRequest{Email: "alice@example.com"}); err != nil {
+		t.Fatalf("update without email change: %v", err)
+	}
+	if err := svc.VerifyEmail(ctx, 1); err != nil {
+		t.Fatalf("verify email: %v", err)
+	}
+	// 重复验证不产生新事件
+	if err := svc.VerifyEmail(ctx, 1); err != nil {
+		t.Fatalf("verify email again: %v", err)
+	}
+	user, err := svc.UpdateUser(ctx, 1, models.UpdateUserRequest{Email: "alice@new.example.com"})
+	if err != nil {
+		t.Fatalf("change email: %v", err)
+	}
+	if user.EmailVerifiedAt != nil {
+		t.Fatal("expected email change to clear verification")
+	}
+	if err := svc.DeleteUser(ctx, 1); err != nil {
+		t.Fatalf("delete user: %v", err)
+	}
+
+	events := store.OutboxEvents()
+	want := []string{models.EventUserRegistered, models.EventUserVerified, models.EventUserEmailChanged, models.EventUserDeleted}
+	if len(events) != len(want) {
+		t.Fatalf("expected %d outbox events, got %d", len(want), len(events))
+	}
+	for i, e := range events {
+		if e.Type != want[i] {
+			t.Fatalf("event %d: expected %s, got %s", i, want[i], e.Type)
+		}
+	}
+
+	var data models.UserEventData
+	if err := json.Unmarshal([]byte(events[2].Payload), &data); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	if data.Email != "alice@new.example.com" || data.PreviousEmail != "alice@example.com" {
+		t.Fatalf("unexpected email_changed payload: %+v", data)
+	}
+}
+
+// recordEvents 同步订阅类型 T，把 describe 的结果追加到 got
+func recordEvents[T any](t *testing.T, bus *events.Bus, got *[]string, describe func(T) string) {
+	t.Helper()
+	_, err := events.Subscribe(bus, func(_ context.Context, e T) error {
+		*got = append(*got, describe(e))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+}
+
+func TestUserServicePublishesDomainEvents(t *testing.T) {
+	bus := events.NewBus()
+	var got []string
+	recordEvents(t, bus, &got, func(e UserRegistered) string { return "registered:" + e.User.Username })
+	recordEvents(t, bus, &got, func(e UserEmailChanged) string { return "email_changed:" + e.PreviousEmail })
+	recordEvents(t, bus, &got, func(e UserRoleChanged) string { return "role:" + e.PreviousRole + "->" + e.User.Role })
+	recordEvents(t, bus, &got, func(UserDeleted) string { return "deleted" })
+	// 订阅者失败不影响已经提交的修改
+	if _, err := events.Subscribe(bus, func(context.Context, UserRoleChanged) error { return errors.New("metrics unavailable") }); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	svc := NewUserService(repository.NewMemoryStore(), WithEventBus(bus))
+	ctx := context.Background()
+	user, err := svc.CreateUser(ctx, models.CreateUserRequest{Username: "alice", Email: "alice@example.com", Password: "secret123"})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	// 冲突失败时不发布事件
+	_, err = svc.CreateUser(ctx, models.CreateUserRequest{Username: "alice", Email: "other@example.com", Password: "secret123"})
+	assertAppError(t, err, 409)
+	if _, err := svc.UpdateUser(ctx, user.ID, models.UpdateUserRequest{Email: "alice@new.example.com"}); err != nil {
+		t.Fatalf("update user: %v", err)
+	}
+	if err := svc.SetRole(ctx, user.ID, models.RoleAdmin); err != nil {
+		t.Fatalf("set role: %v", err)
+	}
+	if err := svc.DeleteUser(ctx, user.ID); err != nil {
+		t.Fatalf("delete user: %v", err)
+	}
+
+	want := []string{"registered:alice", "email_changed:alice@example.com", "role:user->admin", "deleted"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected events %v, got %v", want, got)
+	}
+}
+
+// 事件会传给 webhook、审计等订阅者，不能带上密码哈希
+func TestUserEventsOmitPasswordHash(t *testing.T) {
+	bus := events.NewBus()
+	var got []string
+	hash := func(u models.User) string { return u.Password }
+	recordEvents(t, bus, &got, func(e UserRegistered) string { return hash(e.User) })
+	recordEvents(t, bus, &got, func(e UserLoggedIn) string { return hash(e.User) })
+	recordEvents(t, bus, &got, func(e UserPasswordReset) string { return hash(e.User) })
+	recordEvents(t, bus, &got, func(e UserDisabled) string { return hash(e.User) })
+
+	svc := NewUserService(repository.NewMemoryStore(), WithEventBus(bus))
+	ctx := context.Background()
+	user, err := svc.CreateUser(ctx, models.CreateUserRequest{Username: "alice", Email: "alice@example.com", Password: "secret123"})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	if _, err := svc.Authenticate(ctx, "alice", "secret123"); err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	if err := svc.ResetPassword(ctx, user.ID, "newsecret123"); err != nil {
+		t.Fatalf("reset password: %v", err)
+	}
+	if err := svc.DisableUser(ctx, user.ID); err != nil {
+		t.Fatalf("disable user: %v", err)
+	}
+
+	if len(got) != 4 {
+		t.Fatalf("expected 4 events, got %d", len(got))
+	}
+	for i, h := range got {
+		if h != "" {
+			t.Fatalf("event %d: expected no password hash, got %q", i, h)
+		}
+	}
+}
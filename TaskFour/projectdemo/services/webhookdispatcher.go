@@ -0,0 +1,311 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"projectdemo/config"
+	"projectdemo/internal/background"
+	"projectdemo/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	dispatchBatchSize = 50
+	maxLoggedBody     = 1024
+	maxLoggedError    = 500
+)
+
+// WebhookDispatcher 后台轮询 outbox：先为新事件生成投递记录，再投递到期的记录。
+// 多个实例同时运行时通过条件更新认领投递，同一次尝试只会发送一次
+type WebhookDispatcher struct {
+	db     *gorm.DB
+	cfg    config.WebhookConfig
+	client *http.Client
+	owner  string
+	now    func() time.Time
+}
+
+// errLeaseLost 租约过期后投递被其他实例重新认领，本次结果不再写回
+var errLeaseLost = errors.New("lease lost before the result was recorded")
+
+func NewWebhookDispatcher(db *gorm.DB, cfg config.WebhookConfig) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		db:  db,
+		cfg: cfg,
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			// 不跟随重定向，3xx 按失败处理
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		owner: background.NewOwnerID(),
+		now:   time.Now,
+	}
+}
+
+// Run 按 PollInterval 轮询，直到 ctx 取消
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := d.DispatchOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("webhook dispatch: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchOnce 处理一批 outbox 事件和到期的投递，返回本轮尝试投递的次数
+func (d *WebhookDispatcher) DispatchOnce(ctx context.Context) (int, error) {
+	if err := d.fanOut(ctx); err != nil {
+		return 0, err
+	}
+	return d.deliverDue(ctx)
+}
+
+// fanOut 为每个未分发的事件生成匹配订阅的投递记录，并在同一事务中标记事件已分发
+func (d *WebhookDispatcher) fanOut(ctx context.Context) error {
+	var events []models.OutboxEvent
+	err := d.db.WithContext(ctx).Where("dispatched_at IS NULL").
+		Order("id ASC").Limit(dispatchBatchSize).Find(&events).Error
+	if err != nil || len(events) == 0 {
+		return err
+	}
+
+	var subs []models.WebhookSubscription
+	if err := d.db.WithContext(ctx).Where("active = ?", true).Find(&subs).Error; err != nil {
+		return err
+	}
+
+	for i := range events {
+		event := &events[i]
+		now := d.now()
+		err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var deliveries []models.WebhookDelivery
+			for _, sub := range subs {
+				if !sub.Accepts(event.Type) {
+					continue
+				}
+				deliveries = append(deliveries, models.WebhookDelivery{
+					SubscriptionID: sub.ID,
+					EventID:        event.ID,
+					EventType:      event.Type,
+					Status:         models.DeliveryStatusPending,
+					NextAttemptAt:  now,
+				})
+			}
+			if len(deliveries) > 0 {
+				// 另一个实例可能已经为该事件生成过投递记录
+				if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&deliveries).Error; err != nil {
+					return err
+				}
+			}
+			return tx.Model(event).Update("dispatched_at", now).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *WebhookDispatcher) deliverDue(ctx context.Context) (int, error) {
+	var due []models.WebhookDelivery
+	err := d.db.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ?", models.DeliveryStatusPending, d.now()).
+		Order("next_attempt_at ASC, id ASC").Limit(dispatchBatchSize).Find(&due).Error
+	if err != nil {
+		return 0, err
+	}
+
+	attempted := 0
+	for i := range due {
+		if ctx.Err() != nil {
+			return attempted, ctx.Err()
+		}
+		delivery := &due[i]
+		claimed, err := d.claim(ctx, delivery)
+		if err != nil {
+			return attempted, err
+		}
+		if !claimed {
+			continue
+		}
+		attempted++
+		if err := d.deliver(ctx, delivery); err != nil {
+			return attempted, err
+		}
+	}
+	return attempted, nil
+}
+
+// claim 增加尝试次数并把下次尝试时间推迟到请求超时之后，
+// 进程在投递途中退出时，该投递会在租约过期后被重新认领
+func (d *WebhookDispatcher) claim(ctx context.Context, delivery *models.WebhookDelivery) (bool, error) {
+	result := d.db.WithContext(ctx).Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND attempts = ?", delivery.ID, models.DeliveryStatusPending, delivery.Attempts).
+		Updates(map[string]interface{}{
+			"attempts":        delivery.Attempts + 1,
+			"next_attempt_at": d.now().Add(d.cfg.Timeout + time.Minute),
+			"locked_by":       d.owner,
+		})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	delivery.Attempts++
+	return true, nil
+}
+
+func (d *WebhookDispatcher) deliver(ctx context.Context, delivery *models.WebhookDelivery) error {
+	attempt := models.WebhookDeliveryLog{DeliveryID: delivery.ID, Attempt: delivery.Attempts}
+
+	var sub models.WebhookSubscription
+	err := d.db.WithContext(ctx).First(&sub, delivery.SubscriptionID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !sub.Active) {
+		attempt.Error = "subscription is no longer active"
+		return d.finish(ctx, delivery, &attempt, models.DeliveryStatusFailed)
+	}
+	if err != nil {
+		return err
+	}
+
+	var event models.OutboxEvent
+	if err := d.db.WithContext(ctx).First(&event, delivery.EventID).Error; err != nil {
+		return err
+	}
+	body, err := json.Marshal(models.WebhookPayload{
+		ID:        event.ID,
+		Type:      event.Type,
+		CreatedAt: event.CreatedAt,
+		Data:      json.RawMessage(event.Payload),
+	})
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	statusCode, respBody, sendErr := d.send(ctx, &sub, delivery, body)
+	if ctx.Err() != nil {
+		// 关闭过程中被中断的请求不计入结果，租约过期后重新投递
+		return ctx.Err()
+	}
+	attempt.DurationMS = time.Since(start).Milliseconds()
+	attempt.StatusCode = statusCode
+	attempt.ResponseBody = respBody
+	switch {
+	case sendErr != nil:
+		attempt.Error = background.Truncate(sendErr.Error(), maxLoggedError)
+	case statusCode < 200 || statusCode > 299:
+		attempt.Error = "unexpected status " + strconv.Itoa(statusCode)
+	default:
+		return d.finish(ctx, delivery, &attempt, models.DeliveryStatusSucceeded)
+	}
+
+	if delivery.Attempts >= d.cfg.MaxAttempts {
+		return d.finish(ctx, delivery, &attempt, models.DeliveryStatusFailed)
+	}
+	return d.finish(ctx, delivery, &attempt, models.DeliveryStatusPending)
+}
+
+func (d *WebhookDispatcher) send(ctx context.Context, sub *models.WebhookSubscription, delivery *models.WebhookDelivery, body []byte) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	timestamp := strconv.FormatInt(d.now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "projectdemo-webhooks/1.0")
+	req.Header.Set("X-Webhook-ID", strconv.FormatUint(uint64(delivery.EventID), 10))
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+SignWebhook(sub.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxLoggedBody))
+	// 读完剩余内容以便复用连接
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, string(respBody), nil
+}
+
+// finish 记录本次尝试并更新投递状态，pending 表示按退避时间重试。
+// 条件中带上 attempts 和 locked_by，租约过期后被其他实例接手的投递不会被旧结果覆盖
+func (d *WebhookDispatcher) finish(ctx context.Context, delivery *models.WebhookDelivery, attempt *models.WebhookDeliveryLog, status string) error {
+	now := d.now()
+	updates := map[string]interface{}{
+		"status":           status,
+		"last_status_code": attempt.StatusCode,
+		"last_error":       attempt.Error,
+		"locked_by":        "",
+	}
+	switch status {
+	case models.DeliveryStatusSucceeded:
+		updates["delivered_at"] = now
+	case models.DeliveryStatusPending:
+		updates["next_attempt_at"] = now.Add(background.Backoff(d.cfg.BaseDelay, d.cfg.MaxDelay, delivery.Attempts))
+	}
+
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.WebhookDelivery{}).
+			Where("id = ? AND attempts = ? AND locked_by = ?", delivery.ID, delivery.Attempts, d.owner).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errLeaseLost
+		}
+		return tx.Create(attempt).Error
+	})
+	if errors.Is(err, errLeaseLost) {
+		log.Printf("webhooks: delivery %d attempt %d: %v", delivery.ID, delivery.Attempts, err)
+		return nil
+	}
+	return err
+}
+
+// SignWebhook 计算 HMAC-SHA256(secret, timestamp + "." + body) 的十六进制值，
+// 接收方用同样的方式计算后与 X-Webhook-Signature 中 "sha256=" 之后的部分比较
+func SignWebhook(secret, timestamp string, body []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(timestamp))
+	h.Write([]byte("."))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// VerifyWebhookSignature 校验 X-Webhook-Signature 请求头，使用常量时间比较
+func VerifyWebhookSignature(secret, timestamp string, body []byte, header string) bool {
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	expected, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	actual, _ := hex.DecodeString(SignWebhook(secret, timestamp, body))
+	return hmac.Equal(expected, actual)
+}
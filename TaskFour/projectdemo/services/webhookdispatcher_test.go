@@ -0,0 +1,262 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"projectdemo/config"
+	"projectdemo/models"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookReceiver 记录收到的请求并校验签名，按 statuses 依次返回状态码，用完后返回 200
+type webhookReceiver struct {
+	t        *testing.T
+	secret   string
+	mu       sync.Mutex
+	statuses []int
+	payloads []models.WebhookPayload
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	if !VerifyWebhookSignature(r.secret, req.Header.Get("X-Webhook-Timestamp"), body, req.Header.Get("X-Webhook-Signature")) {
+		r.t.Errorf("invalid signature %q", req.Header.Get("X-Webhook-Signature"))
+	}
+	var payload models.WebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		r.t.Errorf("decode payload: %v", err)
+	}
+	if got := req.Header.Get("X-Webhook-Event"); got != payload.Type {
+		r.t.Errorf("expected event header %s, got %s", payload.Type, got)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.payloads = append(r.payloads, payload)
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte("ack"))
+}
+
+func (r *webhookReceiver) received() []models.WebhookPayload {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]models.WebhookPayload(nil), r.payloads...)
+}
+
+func TestWebhookDispatcher(t *testing.T) {
+	store, db := newSQLiteStore(t)
+	if err := db.AutoMigrate(&models.WebhookSubscription{}, &models.WebhookDelivery{}, &models.WebhookDeliveryLog{}); err != nil {
+		t.Fatalf("auto migrate: %v", err)
+	}
+	ctx := context.Background()
+
+	flaky := &webhookReceiver{t: t, secret: "flaky-secret-0123456789", statuses: []int{http.StatusInternalServerError}}
+	broken := &webhookReceiver{t: t, secret: "broken-secret-0123456789", statuses: []int{500, 502, 503}}
+	other := &webhookReceiver{t: t, secret: "other-secret-0123456789"}
+	webhooks := NewWebhookService(db)
+	var subs []*models.WebhookSubscription
+	for _, tc := range []struct {
+		receiver *webhookReceiver
+		events   []string
+	}{
+		{flaky, []string{models.EventUserRegistered}},
+		{broken, nil},
+		// 事件过滤：只订阅删除事件，不应收到注册事件
+		{other, []string{models.EventUserDeleted}},
+	} {
+		srv := httptest.NewServer(tc.receiver)
+		t.Cleanup(srv.Close)
+		sub, err := webhooks.Create(ctx, models.CreateWebhookRequest{URL: srv.URL, Secret: tc.receiver.secret, Events: tc.events})
+		if err != nil {
+			t.Fatalf("create subscription: %v", err)
+		}
+		subs = append(subs, sub)
+	}
+
+	user, err := NewUserService(store).CreateUser(ctx, models.CreateUserRequest{Username: "alice", Email: "alice@example.com", Password: "secret123"})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	d := NewWebhookDispatcher(db, config.WebhookConfig{Timeout: 5 * time.Second, MaxAttempts: 2, BaseDelay: time.Minute, MaxDelay: time.Hour})
+	clock := time.Now()
+	d.now = func() time.Time { return clock }
+	dispatch := func(want int) {
+		t.Helper()
+		n, err := d.DispatchOnce(ctx)
+		if err != nil {
+			t.Fatalf("dispatch: %v", err)
+		}
+		if n != want {
+			t.Fatalf("expected %d attempts, got %d", want, n)
+		}
+	}
+
+	dispatch(2)
+	// 退避时间未到，不会重试
+	dispatch(0)
+	clock = clock.Add(time.Minute)
+	dispatch(2)
+	dispatch(0)
+
+	got := flaky.received()
+	if len(got) != 2 || got[1].Type != models.EventUserRegistered {
+		t.Fatalf("expected two registered deliveries to flaky receiver, got %+v", got)
+	}
+	var data models.UserEventData
+	if err := json.Unmarshal(got[1].Data, &data); err != nil || data.ID != user.ID || data.Username != "alice" {
+		t.Fatalf("unexpected event data %s (%v)", got[1].Data, err)
+	}
+	if n := len(other.received()); n != 0 {
+		t.Fatalf("expected filtered subscription to receive nothing, got %d", n)
+	}
+
+	deliveries, err := webhooks.ListDeliveries(ctx, subs[0].ID, models.WebhookDeliveryQuery{})
+	if err != nil {
+		t.Fatalf("list deliveries: %v", err)
+	}
+	if len(deliveries) != 1 || deliveries[0].Status != models.DeliveryStatusSucceeded || deliveries[0].DeliveredAt == nil {
+		t.Fatalf("expected one succeeded delivery, got %+v", deliveries)
+	}
+	logs := deliveries[0].Logs
+	if len(logs) != 2 || logs[0].StatusCode != 500 || logs[0].Error == "" || logs[1].StatusCode != 200 || logs[1].ResponseBody != "ack" {
+		t.Fatalf("unexpected delivery logs %+v", logs)
+	}
+
+	// 达到最大尝试次数后标记为失败，重新投递后从头计数
+	failed, err := webhooks.ListDeliveries(ctx, subs[1].ID, models.WebhookDeliveryQuery{Status: models.DeliveryStatusFailed})
+	if err != nil {
+		t.Fatalf("list failed deliveries: %v", err)
+	}
+	if len(failed) != 1 || failed[0].Attempts != 2 || failed[0].LastStatusCode != 502 {
+		t.Fatalf("expected one failed delivery after 2 attempts, got %+v", failed)
+	}
+	if _, err := webhooks.Redeliver(ctx, failed[0].ID); err != nil {
+		t.Fatalf("redeliver: %v", err)
+	}
+	_, err = webhooks.Redeliver(ctx, failed[0].ID)
+	assertAppError(t, err, http.StatusConflict)
+	dispatch(1)
+	if n := len(broken.received()); n != 3 {
+		t.Fatalf("expected 3 requests to broken receiver, got %d", n)
+	}
+}
+
+func TestWebhookDispatcherSkipsDeletedSubscription(t *testing.T) {
+	store, db := newSQLiteStore(t)
+	if err := db.AutoMigrate(&models.WebhookSubscription{}, &models.WebhookDelivery{}, &models.WebhookDeliveryLog{}); err != nil {
+		t.Fatalf("auto migrate: %v", err)
+	}
+	ctx := context.Background()
+	receiver := &webhookReceiver{t: t, secret: "receiver-secret-0123456789"}
+	srv := httptest.NewServer(receiver)
+	t.Cleanup(srv.Close)
+
+	webhooks := NewWebhookService(db)
+	sub, err := webhooks.Create(ctx, models.CreateWebhookRequest{URL: srv.URL, Secret: receiver.secret})
+	if err != nil {
+		t.Fatalf("create subscription: %v", err)
+	}
+	if _, err := NewUserService(store).CreateUser(ctx, models.CreateUserRequest{Username: "bob", Email: "bob@example.com", Password: "secret123"}); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	d := NewWebhookDispatcher(db, config.WebhookConfig{Timeout: 5 * time.Second, MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Hour})
+	if err := d.fanOut(ctx); err != nil {
+		t.Fatalf("fan out: %v", err)
+	}
+	if err := webhooks.Delete(ctx, sub.ID); err != nil {
+		t.Fatalf("delete subscription: %v", err)
+	}
+	if _, err := d.DispatchOnce(ctx); err != nil {
+		t.Fatalf("dispatch: %v", err)
+	}
+
+	var delivery models.WebhookDelivery
+	if err := db.First(&delivery).Error; err != nil {
+		t.Fatalf("load delivery: %v", err)
+	}
+	if delivery.Status != models.DeliveryStatusFailed || len(receiver.received()) != 0 {
+		t.Fatalf("expected delivery to fail without sending, got %s and %d requests", delivery.Status, len(receiver.received()))
+	}
+}
+
+func TestWebhookDispatcherDropsResultAfterLeaseLost(t *testing.T) {
+	store, db := newSQLiteStore(t)
+	if err := db.AutoMigrate(&models.WebhookSubscription{}, &models.WebhookDelivery{}, &models.WebhookDeliveryLog{}); err != nil {
+		t.Fatalf("auto migrate: %v", err)
+	}
+	ctx := context.Background()
+	if _, err := NewWebhookService(db).Create(ctx, models.CreateWebhookRequest{URL: "http://127.0.0.1:1", Secret: "receiver-secret-0123456789"}); err != nil {
+		t.Fatalf("create subscription: %v", err)
+	}
+	if _, err := NewUserService(store).CreateUser(ctx, models.CreateUserRequest{Username: "carol", Email: "carol@example.com", Password: "secret123"}); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	cfg := config.WebhookConfig{Timeout: 5 * time.Second, MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Hour}
+	slow, fast := NewWebhookDispatcher(db, cfg), NewWebhookDispatcher(db, cfg)
+	if err := slow.fanOut(ctx); err != nil {
+		t.Fatalf("fan out: %v", err)
+	}
+	claim := func(d *WebhookDispatcher) *models.WebhookDelivery {
+		t.Helper()
+		var delivery models.WebhookDelivery
+		if err := db.First(&delivery).Error; err != nil {
+			t.Fatalf("load delivery: %v", err)
+		}
+		if claimed, err := d.claim(ctx, &delivery); err != nil || !claimed {
+			t.Fatalf("expected claim to succeed, got %v (%v)", claimed, err)
+		}
+		return &delivery
+	}
+
+	// slow 的租约过期后 fast 重新认领并投递成功，slow 之后写回的失败结果被丢弃
+	stale := claim(slow)
+	current := claim(fast)
+	if err := fast.finish(ctx, current, &models.WebhookDeliveryLog{DeliveryID: current.ID, Attempt: current.Attempts, StatusCode: 200}, models.DeliveryStatusSucceeded); err != nil {
+		t.Fatalf("finish: %v", err)
+	}
+	if err := slow.finish(ctx, stale, &models.WebhookDeliveryLog{DeliveryID: stale.ID, Attempt: stale.Attempts, Error: "timeout"}, models.DeliveryStatusPending); err != nil {
+		t.Fatalf("stale finish: %v", err)
+	}
+
+	var delivery models.WebhookDelivery
+	if err := db.Preload("Logs").First(&delivery).Error; err != nil {
+		t.Fatalf("load delivery: %v", err)
+	}
+	if delivery.Status != models.DeliveryStatusSucceeded || delivery.Attempts != 2 || delivery.LockedBy != "" {
+		t.Fatalf("expected the newer attempt to win, got %+v", delivery)
+	}
+	if len(delivery.Logs) != 1 || delivery.Logs[0].Attempt != 2 {
+		t.Fatalf("expected only the newer attempt to be logged, got %+v", delivery.Logs)
+	}
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	body := []byte(`{"id":1}`)
+	sig := "sha256=" + SignWebhook("secret", "1700000000", body)
+
+	if !VerifyWebhookSignature("secret", "1700000000", body, sig) {
+		t.Fatal("expected signature to verify")
+	}
+	for name, tc := range map[string][3]string{
+		"wrong secret":    {"other", "1700000000", sig},
+		"wrong timestamp": {"secret", "1700000001", sig},
+		"missing prefix":  {"secret", "1700000000", sig[len("sha256="):]},
+		"not hex":         {"secret", "1700000000", "sha256=zz"},
+	} {
+		if VerifyWebhookSignature(tc[0], tc[1], body, tc[2]) {
+			t.Fatalf("%s: expected signature to be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"projectdemo/models"
+	"projectdemo/utils"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const defaultDeliveryLimit = 50
+
+// WebhookService 管理 webhook 订阅和投递记录，实际投递由 WebhookDispatcher 完成
+type WebhookService struct {
+	db *gorm.DB
+}
+
+func NewWebhookService(db *gorm.DB) *WebhookService {
+	return &WebhookService{db: db}
+}
+
+func (s *WebhookService) Create(ctx context.Context, req models.CreateWebhookRequest) (*models.WebhookSubscription, error) {
+	sub := models.WebhookSubscription{
+		URL:    req.URL,
+		Secret: req.Secret,
+		Events: models.StringList(req.Events),
+		Active: true,
+	}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&sub).Error; err != nil {
+			return err
+		}
+		// 审计中不记录密钥
+		return tx.Create(NewAuditEvent(ctx, models.AuditWebhookCreate, "webhook", webhookTargetID(sub.ID), nil,
+			map[string]interface{}{"url": sub.URL, "events": sub.Events})).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+func (s *WebhookService) List(ctx context.Context) ([]models.WebhookSubscription, error) {
+	subs := make([]models.WebhookSubscription, 0)
+	err := s.db.WithContext(ctx).Order("id ASC").Find(&subs).Error
+	return subs, err
+}
+
+// Delete 删除订阅，尚未完成的投递会在下次尝试时标记为失败
+func (s *WebhookService) Delete(ctx context.Context, id uint) error {
+	var sub models.WebhookSubscription
+	if err := s.db.WithContext(ctx).First(&sub, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.NewAppError(http.StatusNotFound, "Webhook not found")
+		}
+		return err
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&sub).Error; err != nil {
+			return err
+		}
+		return tx.Create(NewAuditEvent(ctx, models.AuditWebhookDelete, "webhook", webhookTargetID(sub.ID),
+			map[string]interface{}{"url": sub.URL, "events": sub.Events}, nil)).Error
+	})
+}
+
+// ListDeliveries 返回订阅最近的投递记录及每次尝试的日志
+func (s *WebhookService) ListDeliveries(ctx context.Context, subscriptionID uint, q models.WebhookDeliveryQuery) ([]models.WebhookDelivery, error) {
+	if err := s.db.WithContext(ctx).Select("id").First(&models.WebhookSubscription{}, subscriptionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(http.StatusNotFound, "Webhook not found")
+		}
+		return nil, err
+	}
+
+	limit := q.Limit
+	if limit == 0 {
+		limit = defaultDeliveryLimit
+	}
+	db := s.db.WithContext(ctx).
+		Preload("Logs", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		Where("subscription_id = ?", subscriptionID)
+	if q.Status != "" {
+		db = db.Where("status = ?", q.Status)
+	}
+
+	deliveries := make([]models.WebhookDelivery, 0)
+	err := db.Order("id DESC").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+// Redeliver 让已结束的投递重新开始，尝试次数从 0 计算
+func (s *WebhookService) Redeliver(ctx context.Context, id uint) (*models.WebhookDelivery, error) {
+	result := s.db.WithContext(ctx).Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status <> ?", id, models.DeliveryStatusPending).
+		Updates(map[string]interface{}{
+			"status":          models.DeliveryStatusPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+			"last_error":      "",
+			"delivered_at":    nil,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	var delivery models.WebhookDelivery
+	if err := s.db.WithContext(ctx).First(&delivery, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewAppError(http.StatusNotFound, "Delivery not found")
+		}
+		return nil, err
+	}
+	if result.RowsAffected == 0 {
+		return nil, utils.NewAppError(http.StatusConflict, "Delivery is already pending")
+	}
+	return &delivery, nil
+}
+
+func webhookTargetID(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
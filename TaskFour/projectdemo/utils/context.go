@@ -0,0 +1,47 @@
+package utils
+
+import "context"
+
+type requestMetaKey struct{}
+
+// RequestMeta 描述一次请求的调用方信息，由中间件写入 context，供 service 层记录审计等使用
+type RequestMeta struct {
+	RequestID string
+	IP        string
+	UserID    uint
+	Username  string
+}
+
+func WithRequestMeta(ctx context.Context, meta RequestMeta) context.Context {
+	return context.WithValue(ctx, requestMetaKey{}, meta)
+}
+
+func RequestMetaFrom(ctx context.Context) RequestMeta {
+	if ctx == nil {
+		return RequestMeta{}
+	}
+	meta, _ := ctx.Value(requestMetaKey{}).(RequestMeta)
+	return meta
+}
+
+type tenantKey struct{}
+
+// WithTenant 标记 context 所属的租户（组织），orgID 为 0 表示个人空间。
+// 带有租户的 context 执行的 GORM 操作由 repository.TenantPlugin 自动限定在该租户内
+func WithTenant(ctx context.Context, orgID uint) context.Context {
+	return context.WithValue(ctx, tenantKey{}, orgID)
+}
+
+// WithoutTenant 取消 context 的租户限定，用于管理员等需要跨组织操作的场景
+func WithoutTenant(ctx context.Context) context.Context {
+	return context.WithValue(ctx, tenantKey{}, nil)
+}
+
+// TenantFrom 返回 context 中的租户，ok 为 false 表示不限定租户（命令行、公开接口等）
+func TenantFrom(ctx context.Context) (orgID uint, ok bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	orgID, ok = ctx.Value(tenantKey{}).(uint)
+	return orgID, ok
+}
@@ -10,7 +10,9 @@ import (
 type AppError struct {
 	Code    int
 	Message string
-	Err     error
+	// Field 出错的请求字段，例如唯一约束冲突的 username
+	Field string
+	Err   error
 }
 
 func (e *AppError) Error() string {
@@ -27,14 +29,32 @@ func NewAppError(code int, message string) *AppError {
 	}
 }
 
+// NewConflictError 409 冲突，指明冲突的字段
+func NewConflictError(field, message string) *AppError {
+	return &AppError{
+		Code:    409,
+		Message: message,
+		Field:   field,
+	}
+}
+
 func HandleError(c *gin.Context, err error) {
 	var appErr *AppError
 	if errors.As(err, &appErr) {
-		c.JSON(appErr.Code, gin.H{
+		// Err 可能为空（NewAppError 构造的业务错误），此时直接返回 Message
+		detail := appErr.Message
+		if appErr.Err != nil {
+			detail = appErr.Err.Error()
+		}
+		body := gin.H{
 			"code":    appErr.Code,
 			"message": appErr.Message,
-			"error":   appErr.Err.Error(),
-		})
+			"error":   detail,
+		}
+		if appErr.Field != "" {
+			body["field"] = appErr.Field
+		}
+		c.JSON(appErr.Code, body)
 		return
 	}
 	c.JSON(500, gin.H{
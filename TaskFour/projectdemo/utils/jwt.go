@@ -10,13 +10,18 @@ import (
 type Claims struct {
 	UserID   uint   `json:"user_id"`
 	Username string `json:"username"`
+	Role     string `json:"role"`
+	// OrgID 当前活动的组织，0 表示个人空间
+	OrgID uint `json:"org_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(secret []byte, userID uint, username string) (string, error) {
+func GenerateToken(secret []byte, userID uint, username, role string, orgID uint) (string, error) {
 	claims := Claims{
 		UserID:   userID,
 		Username: username,
+		Role:     role,
+		OrgID:    orgID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),